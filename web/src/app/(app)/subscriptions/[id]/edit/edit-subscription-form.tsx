@@ -31,6 +31,8 @@ const EVENT_TYPES = [
   { value: "entity_appeared", label: "Entity Appeared" },
   { value: "entity_changed", label: "Entity Changed" },
   { value: "entity_disappeared", label: "Entity Disappeared" },
+  { value: "watch_suspect", label: "Suspicious Run" },
+  { value: "watch_error", label: "Watch Failing" },
 ];
 
 interface EditSubscriptionFormProps {
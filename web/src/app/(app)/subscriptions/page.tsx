@@ -36,6 +36,10 @@ function getEventTypeLabel(eventType: string): string {
       return "Changed";
     case "entity_disappeared":
       return "Disappeared";
+    case "watch_suspect":
+      return "Suspect";
+    case "watch_error":
+      return "Watch failing";
     default:
       return eventType;
   }
@@ -2,7 +2,16 @@
 
 import { useState } from "react";
 import { useRouter } from "next/navigation";
-import { Play, Pause, Trash2, RotateCw, Eye, AlertTriangle, Pencil } from "lucide-react";
+import {
+  Play,
+  Pause,
+  Trash2,
+  RotateCw,
+  Eye,
+  AlertTriangle,
+  Pencil,
+  CheckCheck,
+} from "lucide-react";
 import {
   DropdownMenu,
   DropdownMenuContent,
@@ -17,12 +26,25 @@ import {
   DialogHeader,
   DialogTitle,
 } from "@/components/ui/dialog";
-import { pauseWatch, unpauseWatch, deleteWatch, triggerWatchRun } from "@/server/watches";
-
-export function WatchRunActions({ errorMessage }: { errorMessage: string | null }) {
+import {
+  pauseWatch,
+  unpauseWatch,
+  deleteWatch,
+  triggerWatchRun,
+  acceptWatchRun,
+} from "@/server/watches";
+import type { PageError } from "@/lib/types";
+
+export function WatchRunActions({
+  errorMessage,
+  pageErrors,
+}: {
+  errorMessage: string | null;
+  pageErrors: PageError[];
+}) {
   const [errorOpen, setErrorOpen] = useState(false);
 
-  if (!errorMessage) return null;
+  if (!errorMessage && pageErrors.length === 0) return null;
 
   return (
     <>
@@ -31,7 +53,7 @@ export function WatchRunActions({ errorMessage }: { errorMessage: string | null
         <DropdownMenuContent align="end">
           <DropdownMenuItem onClick={() => setErrorOpen(true)}>
             <AlertTriangle className="mr-2 h-4 w-4" />
-            View Error
+            {errorMessage ? "View Error" : `View Failed Pages (${pageErrors.length})`}
           </DropdownMenuItem>
         </DropdownMenuContent>
       </DropdownMenu>
@@ -40,10 +62,14 @@ export function WatchRunActions({ errorMessage }: { errorMessage: string | null
         <DialogContent className="sm:max-w-lg">
           <DialogHeader>
             <DialogTitle>Run Error</DialogTitle>
-            <DialogDescription>Full error message from this run.</DialogDescription>
+            <DialogDescription>
+              Full error message from this run, and the pages it went on without.
+            </DialogDescription>
           </DialogHeader>
           <pre className="max-h-[400px] overflow-auto rounded-md bg-muted p-4 text-sm whitespace-pre-wrap break-words">
-            {errorMessage}
+            {[errorMessage, ...pageErrors.map((p) => `${p.url} (${p.phase}): ${p.error}`)]
+              .filter(Boolean)
+              .join("\n")}
           </pre>
         </DialogContent>
       </Dialog>
@@ -113,7 +139,15 @@ export function EventActions({ payload }: { payload: unknown }) {
   );
 }
 
-export function WatchActions({ watchId, status }: { watchId: string; status: string }) {
+export function WatchActions({
+  watchId,
+  status,
+  suspect,
+}: {
+  watchId: string;
+  status: string;
+  suspect: boolean;
+}) {
   const router = useRouter();
   const [loading, setLoading] = useState<string | null>(null);
 
@@ -148,6 +182,20 @@ export function WatchActions({ watchId, status }: { watchId: string; status: str
           {loading === "trigger" ? "Triggering..." : "Trigger Run"}
         </DropdownMenuItem>
 
+        {suspect && (
+          <DropdownMenuItem
+            disabled={loading !== null}
+            onClick={() =>
+              handleAction(async () => {
+                await acceptWatchRun(watchId);
+              }, "accept")
+            }
+          >
+            <CheckCheck className="mr-2 h-4 w-4" />
+            {loading === "accept" ? "Accepting..." : "Accept Changes & Run"}
+          </DropdownMenuItem>
+        )}
+
         {status === "active" ? (
           <DropdownMenuItem
             disabled={loading !== null}
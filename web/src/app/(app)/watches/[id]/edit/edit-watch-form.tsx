@@ -14,15 +14,29 @@ import {
   SelectValue,
 } from "@/components/ui/select";
 import { updateWatch } from "@/server/watches";
-import { type BlueprintOption, SCHEDULE_PRESETS } from "@/lib/watch-constants";
+import {
+  type BlueprintOption,
+  MIN_ADAPTIVE_SECONDS,
+  SCHEDULE_PRESETS,
+  URL_PARAM_PLACEHOLDER,
+  checkAdaptiveBounds,
+  splitParams,
+  splitUrls,
+} from "@/lib/watch-constants";
 
 interface EditWatchFormProps {
   watch: {
     id: string;
     name: string;
     url: string;
+    urls: string[];
+    urlParams: string[];
     blueprintId: string;
     schedule: string;
+    timezone: string;
+    adaptiveSchedule: boolean;
+    adaptiveMinSeconds: number;
+    adaptiveMaxSeconds: number;
   };
   blueprints: BlueprintOption[];
 }
@@ -35,12 +49,18 @@ export function EditWatchForm({ watch, blueprints }: EditWatchFormProps) {
   const router = useRouter();
   const [name, setName] = useState(watch.name);
   const [url, setUrl] = useState(watch.url);
+  const [urls, setUrls] = useState(watch.urls.join(" "));
+  const [urlParams, setUrlParams] = useState(watch.urlParams.join(", "));
   const [blueprintId, setBlueprintId] = useState(watch.blueprintId);
   const [schedule, setSchedule] = useState(
     isPresetSchedule(watch.schedule) ? watch.schedule : "0 * * * *",
   );
   const [customSchedule, setCustomSchedule] = useState(watch.schedule);
   const [useCustomSchedule, setUseCustomSchedule] = useState(!isPresetSchedule(watch.schedule));
+  const [timezone, setTimezone] = useState(watch.timezone);
+  const [adaptiveSchedule, setAdaptiveSchedule] = useState(watch.adaptiveSchedule);
+  const [adaptiveMinMinutes, setAdaptiveMinMinutes] = useState(String(watch.adaptiveMinSeconds / 60));
+  const [adaptiveMaxMinutes, setAdaptiveMaxMinutes] = useState(String(watch.adaptiveMaxSeconds / 60));
   const [saving, setSaving] = useState(false);
   const [error, setError] = useState("");
 
@@ -53,8 +73,13 @@ export function EditWatchForm({ watch, blueprints }: EditWatchFormProps) {
       await updateWatch(watch.id, {
         name,
         url,
+        urls: splitUrls(urls),
+        urlParams: splitParams(urlParams),
         blueprintId,
         schedule: useCustomSchedule ? customSchedule : schedule,
+        timezone: timezone.trim() || "UTC",
+        adaptiveSchedule,
+        ...(adaptiveSchedule && { adaptiveMinSeconds, adaptiveMaxSeconds }),
       });
       router.push(`/watches/${watch.id}`);
     } catch (err) {
@@ -63,11 +88,18 @@ export function EditWatchForm({ watch, blueprints }: EditWatchFormProps) {
     }
   }
 
+  const adaptiveMinSeconds = Number(adaptiveMinMinutes) * 60;
+  const adaptiveMaxSeconds = Number(adaptiveMaxMinutes) * 60;
+  const boundsError = adaptiveSchedule
+    ? checkAdaptiveBounds(adaptiveMinSeconds, adaptiveMaxSeconds)
+    : null;
+
   const isValid =
     name.trim() &&
     url.trim() &&
     blueprintId &&
-    (useCustomSchedule ? customSchedule.trim() : schedule);
+    (useCustomSchedule ? customSchedule.trim() : schedule) &&
+    !boundsError;
 
   return (
     <form onSubmit={handleSubmit}>
@@ -126,6 +158,34 @@ export function EditWatchForm({ watch, blueprints }: EditWatchFormProps) {
             />
           </div>
 
+          <div className="space-y-2">
+            <Label htmlFor="urls">Additional URLs</Label>
+            <Input
+              id="urls"
+              value={urls}
+              onChange={(e) => setUrls(e.target.value)}
+              placeholder="https://example.com/products?page=2"
+            />
+            <p className="text-xs text-muted-foreground">
+              Space-separated pages fetched in the same run. Entities moving between pages are not
+              reported as disappeared.
+            </p>
+          </div>
+
+          <div className="space-y-2">
+            <Label htmlFor="urlParams">URL Parameters</Label>
+            <Input
+              id="urlParams"
+              value={urlParams}
+              onChange={(e) => setUrlParams(e.target.value)}
+              placeholder="laptops, tablets"
+            />
+            <p className="text-xs text-muted-foreground">
+              Comma-separated values; each URL containing {URL_PARAM_PLACEHOLDER} is fetched once per
+              value.
+            </p>
+          </div>
+
           <div className="space-y-2">
             <Label>Schedule</Label>
             {!useCustomSchedule ? (
@@ -155,7 +215,7 @@ export function EditWatchForm({ watch, blueprints }: EditWatchFormProps) {
                 <Input
                   value={customSchedule}
                   onChange={(e) => setCustomSchedule(e.target.value)}
-                  placeholder="*/15 * * * *"
+                  placeholder="*/15 * * * * or @every 90m"
                 />
                 <button
                   type="button"
@@ -168,6 +228,70 @@ export function EditWatchForm({ watch, blueprints }: EditWatchFormProps) {
             )}
           </div>
 
+          <div className="space-y-2">
+            <Label htmlFor="timezone">Timezone</Label>
+            <Input
+              id="timezone"
+              value={timezone}
+              onChange={(e) => setTimezone(e.target.value)}
+              placeholder="UTC"
+            />
+            <p className="text-xs text-muted-foreground">
+              IANA timezone the schedule is evaluated in (e.g. &quot;Europe/Amsterdam&quot;).
+            </p>
+          </div>
+
+          <div className="space-y-2">
+            <Label>Run Interval</Label>
+            <Select
+              value={adaptiveSchedule ? "adaptive" : "fixed"}
+              onValueChange={(v) => setAdaptiveSchedule(v === "adaptive")}
+            >
+              <SelectTrigger className="w-full">
+                <SelectValue />
+              </SelectTrigger>
+              <SelectContent>
+                <SelectItem value="fixed">Follow the schedule</SelectItem>
+                <SelectItem value="adaptive">Adapt to how often the page changes</SelectItem>
+              </SelectContent>
+            </Select>
+          </div>
+
+          {adaptiveSchedule && (
+            <div className="space-y-2">
+              <div className="grid grid-cols-2 gap-3">
+                <div className="space-y-2">
+                  <Label htmlFor="adaptiveMin">Minimum Interval (minutes)</Label>
+                  <Input
+                    id="adaptiveMin"
+                    type="number"
+                    min={MIN_ADAPTIVE_SECONDS / 60}
+                    value={adaptiveMinMinutes}
+                    onChange={(e) => setAdaptiveMinMinutes(e.target.value)}
+                  />
+                </div>
+                <div className="space-y-2">
+                  <Label htmlFor="adaptiveMax">Maximum Interval (minutes)</Label>
+                  <Input
+                    id="adaptiveMax"
+                    type="number"
+                    min={MIN_ADAPTIVE_SECONDS / 60}
+                    value={adaptiveMaxMinutes}
+                    onChange={(e) => setAdaptiveMaxMinutes(e.target.value)}
+                  />
+                </div>
+              </div>
+              {boundsError ? (
+                <p className="text-xs text-destructive">{boundsError}</p>
+              ) : (
+                <p className="text-xs text-muted-foreground">
+                  The watch runs more often while the page keeps changing and less often while it
+                  doesn&apos;t, within these bounds.
+                </p>
+              )}
+            </div>
+          )}
+
           <div className="flex gap-3 pt-2">
             <Button type="button" variant="outline" onClick={() => router.push(`/watches/${watch.id}`)}>
               Cancel
@@ -22,8 +22,14 @@ export default async function EditWatchPage({ params }: { params: Promise<{ id:
           id: watch.id,
           name: watch.name,
           url: watch.url,
+          urls: watch.urls,
+          urlParams: watch.urlParams,
           blueprintId: watch.blueprintId,
           schedule: watch.schedule,
+          timezone: watch.timezone,
+          adaptiveSchedule: watch.adaptiveSchedule,
+          adaptiveMinSeconds: watch.adaptiveMinSeconds,
+          adaptiveMaxSeconds: watch.adaptiveMaxSeconds,
         }}
         blueprints={blueprints.map((bp) => ({
           id: bp.id,
@@ -1,6 +1,5 @@
 import { notFound } from "next/navigation";
 import Link from "next/link";
-import cronstrue from "cronstrue";
 import { ExternalLink } from "lucide-react";
 import { getWatch, listWatchRuns, listWatchEntities, listWatchEvents } from "@/server/watches";
 import { Card, CardContent } from "@/components/ui/card";
@@ -22,8 +21,10 @@ function getRunStatusType(status: string): StatusType {
     case "completed":
       return "online";
     case "failed":
+    case "timed_out":
       return "offline";
     case "running":
+    case "suspect":
       return "degraded";
     default:
       return "maintenance";
@@ -38,6 +39,10 @@ function getEventTypeLabel(eventType: string): string {
       return "Changed";
     case "entity_disappeared":
       return "Disappeared";
+    case "watch_suspect":
+      return "Suspect";
+    case "watch_error":
+      return "Watch failing";
     default:
       return eventType;
   }
@@ -50,14 +55,17 @@ function getEventTypeStatusType(eventType: string): StatusType {
     case "entity_changed":
       return "degraded";
     case "entity_disappeared":
+    case "watch_error":
       return "offline";
+    case "watch_suspect":
+      return "degraded";
     default:
       return "maintenance";
   }
 }
 
 function getStatusLabel(status: string): string {
-  return status.charAt(0).toUpperCase() + status.slice(1);
+  return (status.charAt(0).toUpperCase() + status.slice(1)).replace(/_/g, " ");
 }
 
 export default async function WatchDetailPage({ params }: { params: Promise<{ id: string }> }) {
@@ -85,8 +93,19 @@ export default async function WatchDetailPage({ params }: { params: Promise<{ id
             {watch.url}
             <ExternalLink className="h-3 w-3" />
           </a>
+          {(watch.urls.length > 0 || watch.urlParams.length > 0) && (
+            <p className="text-xs text-muted-foreground">
+              {watch.urls.length > 0 && `+${watch.urls.length} more URLs`}
+              {watch.urls.length > 0 && watch.urlParams.length > 0 && " · "}
+              {watch.urlParams.length > 0 && `Parameters: ${watch.urlParams.join(", ")}`}
+            </p>
+          )}
         </div>
-        <WatchActions watchId={watch.id} status={watch.status} />
+        <WatchActions
+          watchId={watch.id}
+          status={watch.status}
+          suspect={runs[0]?.status === "suspect"}
+        />
       </div>
 
       <Card>
@@ -112,8 +131,16 @@ export default async function WatchDetailPage({ params }: { params: Promise<{ id
           </div>
           <div>
             <p className="text-xs text-muted-foreground">Schedule</p>
-            <p className="mt-1 text-sm font-medium">{cronstrue.toString(watch.schedule)}</p>
+            <p className="mt-1 text-sm font-medium">{describeSchedule(watch.schedule, watch.timezone)}</p>
             <p className="text-xs font-mono text-muted-foreground">{watch.schedule}</p>
+            {watch.adaptiveSchedule && (
+              <p className="text-xs text-muted-foreground">
+                Adaptive
+                {watch.adaptiveIntervalSeconds
+                  ? `: every ${Math.round(watch.adaptiveIntervalSeconds / 60)} min`
+                  : ""}
+              </p>
+            )}
           </div>
           <div>
             <p className="text-xs text-muted-foreground">Last Run</p>
@@ -158,19 +185,36 @@ export default async function WatchDetailPage({ params }: { params: Promise<{ id
                       <TableCell>
                         <Status status={getRunStatusType(run.status)}>
                           <StatusIndicator />
-                          <StatusLabel>{getStatusLabel(run.status)}</StatusLabel>
+                          <StatusLabel>
+                            {run.status === "running" && run.phase
+                              ? `Running (${run.phase})`
+                              : getStatusLabel(run.status)}
+                          </StatusLabel>
                         </Status>
                       </TableCell>
                       <TableCell className="text-sm">{run.startedAt.toLocaleString()}</TableCell>
                       <TableCell className="text-sm">
                         {run.completedAt?.toLocaleString() ?? "—"}
                       </TableCell>
-                      <TableCell className="text-sm">{run.entitiesFound ?? "—"}</TableCell>
+                      <TableCell className="text-sm">
+                        {run.entitiesFound ?? "—"}
+                        {(run.identityCollisions ?? 0) + (run.identityMissing ?? 0) > 0 && (
+                          <span
+                            className="ml-1 text-xs text-muted-foreground"
+                            title={`${run.identityCollisions ?? 0} with a duplicate identity, ${run.identityMissing ?? 0} without identity`}
+                          >
+                            ({(run.identityCollisions ?? 0) + (run.identityMissing ?? 0)} dropped)
+                          </span>
+                        )}
+                      </TableCell>
                       <TableCell className="text-sm">{run.entitiesNew ?? "—"}</TableCell>
                       <TableCell className="text-sm">{run.entitiesChanged ?? "—"}</TableCell>
                       <TableCell className="text-sm">{run.entitiesRemoved ?? "—"}</TableCell>
                       <TableCell>
-                        <WatchRunActions errorMessage={run.errorMessage} />
+                        <WatchRunActions
+                          errorMessage={run.errorMessage}
+                          pageErrors={run.pageErrors}
+                        />
                       </TableCell>
                     </TableRow>
                   ))}
@@ -190,8 +234,10 @@ export default async function WatchDetailPage({ params }: { params: Promise<{ id
               <Table>
                 <TableHeader>
                   <TableRow>
+                    {watch.trackPositions && <TableHead>Position</TableHead>}
                     <TableHead>External ID</TableHead>
                     <TableHead>Schema</TableHead>
+                    <TableHead>Source</TableHead>
                     <TableHead>Status</TableHead>
                     <TableHead>First Seen</TableHead>
                     <TableHead>Last Seen</TableHead>
@@ -201,10 +247,21 @@ export default async function WatchDetailPage({ params }: { params: Promise<{ id
                 <TableBody>
                   {entities.map((entity) => (
                     <TableRow key={entity.id}>
+                      {watch.trackPositions && (
+                        <TableCell className="text-sm">
+                          {String((entity.content as Record<string, unknown>)._position ?? "—")}
+                        </TableCell>
+                      )}
                       <TableCell className="font-mono text-sm">
                         {entity.externalId.slice(0, 12)}...
                       </TableCell>
                       <TableCell className="text-sm">{entity.schemaType}</TableCell>
+                      <TableCell
+                        className="max-w-[200px] truncate text-sm"
+                        title={entity.sourceUrl ?? ""}
+                      >
+                        {entity.sourceParam ?? entity.sourceUrl ?? "—"}
+                      </TableCell>
                       <TableCell>
                         <Status status={entity.status === "active" ? "online" : "offline"}>
                           <StatusIndicator />
@@ -43,6 +43,15 @@ export function HealthBadge({
     );
   }
 
+  if (status === "error") {
+    return (
+      <Status status="offline">
+        <StatusIndicator />
+        <StatusLabel>Failing</StatusLabel>
+      </Status>
+    );
+  }
+
   return (
     <TooltipProvider>
       <Tooltip>
@@ -14,17 +14,36 @@ import {
   SelectValue,
 } from "@/components/ui/select";
 import { createWatch } from "@/server/watches";
-import { type BlueprintOption, SCHEDULE_PRESETS } from "@/lib/watch-constants";
+import {
+  type BlueprintOption,
+  DEFAULT_ADAPTIVE_MAX_SECONDS,
+  DEFAULT_ADAPTIVE_MIN_SECONDS,
+  MIN_ADAPTIVE_SECONDS,
+  SCHEDULE_PRESETS,
+  URL_PARAM_PLACEHOLDER,
+  checkAdaptiveBounds,
+  splitParams,
+  splitUrls,
+} from "@/lib/watch-constants";
 
 export function NewWatchForm({ blueprints }: { blueprints: BlueprintOption[] }) {
   const router = useRouter();
   const [name, setName] = useState("");
   const [url, setUrl] = useState("");
+  const [urls, setUrls] = useState("");
+  const [urlParams, setUrlParams] = useState("");
   const [blueprintId, setBlueprintId] = useState("");
   const [schedule, setSchedule] = useState("0 * * * *");
   const [customSchedule, setCustomSchedule] = useState("");
   const [useCustomSchedule, setUseCustomSchedule] = useState(false);
+  const [timezone, setTimezone] = useState("UTC");
   const [identityFields, setIdentityFields] = useState("name");
+  const [identityFallbacks, setIdentityFallbacks] = useState("");
+  const [ignoredFields, setIgnoredFields] = useState("");
+  const [significantFields, setSignificantFields] = useState("");
+  const [adaptiveSchedule, setAdaptiveSchedule] = useState(false);
+  const [adaptiveMinMinutes, setAdaptiveMinMinutes] = useState(String(DEFAULT_ADAPTIVE_MIN_SECONDS / 60));
+  const [adaptiveMaxMinutes, setAdaptiveMaxMinutes] = useState(String(DEFAULT_ADAPTIVE_MAX_SECONDS / 60));
   const [saving, setSaving] = useState(false);
   const [error, setError] = useState("");
 
@@ -46,11 +65,29 @@ export function NewWatchForm({ blueprints }: { blueprints: BlueprintOption[] })
         blueprintId,
         name,
         url,
+        urls: splitUrls(urls),
+        urlParams: splitParams(urlParams),
         schedule: useCustomSchedule ? customSchedule : schedule,
+        timezone: timezone.trim() || "UTC",
+        adaptiveSchedule,
+        ...(adaptiveSchedule && { adaptiveMinSeconds, adaptiveMaxSeconds }),
         identityFields: identityFields
           .split(",")
           .map((f) => f.trim())
           .filter(Boolean),
+        identityFallbacks: identityFallbacks
+          .split(";")
+          .map((set) =>
+            set
+              .split(",")
+              .map((f) => f.trim())
+              .filter(Boolean),
+          )
+          .filter((set) => set.length > 0),
+        diffPolicy: {
+          ignore: splitParams(ignoredFields),
+          significant: splitParams(significantFields),
+        },
       });
       router.push(`/watches/${watch.id}`);
     } catch (err) {
@@ -59,11 +96,22 @@ export function NewWatchForm({ blueprints }: { blueprints: BlueprintOption[] })
     }
   }
 
+  const ignoredList = splitParams(ignoredFields);
+  const overlappingFields = splitParams(significantFields).filter((f) => ignoredList.includes(f));
+
+  const adaptiveMinSeconds = Number(adaptiveMinMinutes) * 60;
+  const adaptiveMaxSeconds = Number(adaptiveMaxMinutes) * 60;
+  const boundsError = adaptiveSchedule
+    ? checkAdaptiveBounds(adaptiveMinSeconds, adaptiveMaxSeconds)
+    : null;
+
   const isValid =
     name.trim() &&
     url.trim() &&
     blueprintId &&
-    (useCustomSchedule ? customSchedule.trim() : schedule);
+    (useCustomSchedule ? customSchedule.trim() : schedule) &&
+    !boundsError &&
+    overlappingFields.length === 0;
 
   return (
     <form onSubmit={handleSubmit}>
@@ -122,6 +170,34 @@ export function NewWatchForm({ blueprints }: { blueprints: BlueprintOption[] })
             />
           </div>
 
+          <div className="space-y-2">
+            <Label htmlFor="urls">Additional URLs</Label>
+            <Input
+              id="urls"
+              value={urls}
+              onChange={(e) => setUrls(e.target.value)}
+              placeholder="https://example.com/products?page=2"
+            />
+            <p className="text-xs text-muted-foreground">
+              Space-separated pages fetched in the same run. Entities moving between pages are not
+              reported as disappeared.
+            </p>
+          </div>
+
+          <div className="space-y-2">
+            <Label htmlFor="urlParams">URL Parameters</Label>
+            <Input
+              id="urlParams"
+              value={urlParams}
+              onChange={(e) => setUrlParams(e.target.value)}
+              placeholder="laptops, tablets"
+            />
+            <p className="text-xs text-muted-foreground">
+              Comma-separated values; each URL containing {URL_PARAM_PLACEHOLDER} is fetched once per
+              value.
+            </p>
+          </div>
+
           <div className="space-y-2">
             <Label>Schedule</Label>
             {!useCustomSchedule ? (
@@ -151,7 +227,7 @@ export function NewWatchForm({ blueprints }: { blueprints: BlueprintOption[] })
                 <Input
                   value={customSchedule}
                   onChange={(e) => setCustomSchedule(e.target.value)}
-                  placeholder="*/15 * * * *"
+                  placeholder="*/15 * * * * or @every 90m"
                 />
                 <button
                   type="button"
@@ -164,6 +240,70 @@ export function NewWatchForm({ blueprints }: { blueprints: BlueprintOption[] })
             )}
           </div>
 
+          <div className="space-y-2">
+            <Label htmlFor="timezone">Timezone</Label>
+            <Input
+              id="timezone"
+              value={timezone}
+              onChange={(e) => setTimezone(e.target.value)}
+              placeholder="UTC"
+            />
+            <p className="text-xs text-muted-foreground">
+              IANA timezone the schedule is evaluated in (e.g. &quot;Europe/Amsterdam&quot;).
+            </p>
+          </div>
+
+          <div className="space-y-2">
+            <Label>Run Interval</Label>
+            <Select
+              value={adaptiveSchedule ? "adaptive" : "fixed"}
+              onValueChange={(v) => setAdaptiveSchedule(v === "adaptive")}
+            >
+              <SelectTrigger className="w-full">
+                <SelectValue />
+              </SelectTrigger>
+              <SelectContent>
+                <SelectItem value="fixed">Follow the schedule</SelectItem>
+                <SelectItem value="adaptive">Adapt to how often the page changes</SelectItem>
+              </SelectContent>
+            </Select>
+          </div>
+
+          {adaptiveSchedule && (
+            <div className="space-y-2">
+              <div className="grid grid-cols-2 gap-3">
+                <div className="space-y-2">
+                  <Label htmlFor="adaptiveMin">Minimum Interval (minutes)</Label>
+                  <Input
+                    id="adaptiveMin"
+                    type="number"
+                    min={MIN_ADAPTIVE_SECONDS / 60}
+                    value={adaptiveMinMinutes}
+                    onChange={(e) => setAdaptiveMinMinutes(e.target.value)}
+                  />
+                </div>
+                <div className="space-y-2">
+                  <Label htmlFor="adaptiveMax">Maximum Interval (minutes)</Label>
+                  <Input
+                    id="adaptiveMax"
+                    type="number"
+                    min={MIN_ADAPTIVE_SECONDS / 60}
+                    value={adaptiveMaxMinutes}
+                    onChange={(e) => setAdaptiveMaxMinutes(e.target.value)}
+                  />
+                </div>
+              </div>
+              {boundsError ? (
+                <p className="text-xs text-destructive">{boundsError}</p>
+              ) : (
+                <p className="text-xs text-muted-foreground">
+                  The watch runs more often while the page keeps changing and less often while it
+                  doesn&apos;t, within these bounds.
+                </p>
+              )}
+            </div>
+          )}
+
           <div className="space-y-2">
             <Label htmlFor="identityFields">Identity Fields</Label>
             <Input
@@ -178,6 +318,52 @@ export function NewWatchForm({ blueprints }: { blueprints: BlueprintOption[] })
             </p>
           </div>
 
+          <div className="space-y-2">
+            <Label htmlFor="identityFallbacks">Identity Fallbacks</Label>
+            <Input
+              id="identityFallbacks"
+              value={identityFallbacks}
+              onChange={(e) => setIdentityFallbacks(e.target.value)}
+              placeholder="url:path; name,seller"
+            />
+            <p className="text-xs text-muted-foreground">
+              Field sets tried in order, separated by semicolons, for entities missing an identity
+              field. Append &quot;:path&quot; to a URL field to use only its path.
+            </p>
+          </div>
+
+          <div className="space-y-2">
+            <Label htmlFor="ignoredFields">Ignored Fields</Label>
+            <Input
+              id="ignoredFields"
+              value={ignoredFields}
+              onChange={(e) => setIgnoredFields(e.target.value)}
+              placeholder="review_count"
+            />
+            <p className="text-xs text-muted-foreground">
+              Comma-separated fields whose changes are never reported.
+            </p>
+          </div>
+
+          <div className="space-y-2">
+            <Label htmlFor="significantFields">Significant Fields</Label>
+            <Input
+              id="significantFields"
+              value={significantFields}
+              onChange={(e) => setSignificantFields(e.target.value)}
+              placeholder="price, availability"
+            />
+            <p className="text-xs text-muted-foreground">
+              Comma-separated fields that alone decide whether an entity changed. Leave empty to
+              count a change to any field.
+            </p>
+            {overlappingFields.length > 0 && (
+              <p className="text-xs text-destructive">
+                {overlappingFields.join(", ")} cannot be both ignored and significant.
+              </p>
+            )}
+          </div>
+
           <div className="flex gap-3 pt-2">
             <Button type="button" variant="outline" onClick={() => router.back()}>
               Cancel
@@ -1,5 +1,4 @@
 import Link from "next/link";
-import cronstrue from "cronstrue";
 import { Calendar } from "lucide-react";
 import { listWatches } from "@/server/watches";
 import { Button } from "@/components/ui/button";
@@ -75,7 +74,7 @@ export default async function WatchesPage() {
                   </TableCell>
                   <TableCell className="text-sm">{w.blueprintName ?? "—"}</TableCell>
                   <TableCell>
-                    <p className="text-sm">{cronstrue.toString(w.schedule)}</p>
+                    <p className="text-sm">{describeSchedule(w.schedule, w.timezone)}</p>
                     <p className="text-xs font-mono text-muted-foreground">{w.schedule}</p>
                   </TableCell>
                   <TableCell className="text-sm text-muted-foreground">
@@ -0,0 +1,21 @@
+import { pgTable, text, uuid, timestamp, jsonb, integer, uniqueIndex } from "drizzle-orm/pg-core";
+import { sql } from "drizzle-orm";
+import { blueprints } from "./blueprints";
+
+export const blueprintRevisions = pgTable(
+  "blueprint_revisions",
+  {
+    id: uuid("id")
+      .primaryKey()
+      .default(sql`gen_random_uuid()`),
+    orgId: text("org_id").notNull(),
+    blueprintId: uuid("blueprint_id")
+      .notNull()
+      .references(() => blueprints.id),
+    version: integer("version").notNull(),
+    schemaType: text("schema_type").notNull(),
+    extractionRules: jsonb("extraction_rules").notNull(),
+    createdAt: timestamp("created_at", { withTimezone: true }).notNull().defaultNow(),
+  },
+  (table) => [uniqueIndex("uq_blueprint_revisions_version").on(table.blueprintId, table.version)],
+);
@@ -20,6 +20,7 @@ export const deliveries = pgTable(
     attempts: integer("attempts").notNull().default(0),
     maxAttempts: integer("max_attempts").notNull().default(5),
     nextRetryAt: timestamp("next_retry_at", { withTimezone: true }).notNull().defaultNow(),
+    claimedBy: text("claimed_by"),
     lastError: text("last_error"),
     deliveredAt: timestamp("delivered_at", { withTimezone: true }),
     createdAt: timestamp("created_at", { withTimezone: true }).notNull().defaultNow(),
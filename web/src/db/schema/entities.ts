@@ -1,4 +1,13 @@
-import { pgTable, text, uuid, timestamp, jsonb, uniqueIndex, index } from "drizzle-orm/pg-core";
+import {
+  pgTable,
+  text,
+  uuid,
+  timestamp,
+  jsonb,
+  integer,
+  uniqueIndex,
+  index,
+} from "drizzle-orm/pg-core";
 import { sql } from "drizzle-orm";
 import { watches } from "./watches";
 
@@ -16,9 +25,14 @@ export const entities = pgTable(
     externalId: text("external_id").notNull(),
     content: jsonb("content").notNull(),
     url: text("url"),
+    sourceUrl: text("source_url"),
+    sourceParam: text("source_param"),
     status: text("status").notNull().default("active"),
+    schemaVersion: integer("schema_version").notNull().default(1),
+    blueprintVersion: integer("blueprint_version").notNull().default(1),
     firstSeenAt: timestamp("first_seen_at", { withTimezone: true }).notNull().defaultNow(),
     lastSeenAt: timestamp("last_seen_at", { withTimezone: true }).notNull().defaultNow(),
+    missedRuns: integer("missed_runs").notNull().default(0),
     createdAt: timestamp("created_at", { withTimezone: true }).notNull().defaultNow(),
     updatedAt: timestamp("updated_at", { withTimezone: true }).notNull().defaultNow(),
   },
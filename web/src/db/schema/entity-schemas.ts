@@ -0,0 +1,37 @@
+import {
+  pgTable,
+  text,
+  uuid,
+  timestamp,
+  jsonb,
+  integer,
+  uniqueIndex,
+  index,
+} from "drizzle-orm/pg-core";
+import { sql } from "drizzle-orm";
+
+export const entitySchemas = pgTable(
+  "entity_schemas",
+  {
+    id: uuid("id")
+      .primaryKey()
+      .default(sql`gen_random_uuid()`),
+    orgId: text("org_id").notNull(),
+    schemaType: text("schema_type").notNull(),
+    version: integer("version").notNull(),
+    description: text("description").notNull().default(""),
+    fields: jsonb("fields").notNull(),
+    identityFields: text("identity_fields")
+      .array()
+      .notNull()
+      .default(sql`ARRAY[]::text[]`),
+    createdAt: timestamp("created_at", { withTimezone: true }).notNull().defaultNow(),
+    deletedAt: timestamp("deleted_at", { withTimezone: true }),
+  },
+  (table) => [
+    uniqueIndex("uq_entity_schemas_version").on(table.orgId, table.schemaType, table.version),
+    index("idx_entity_schemas_org_id")
+      .on(table.orgId)
+      .where(sql`${table.deletedAt} IS NULL`),
+  ],
+);
@@ -0,0 +1,32 @@
+import { pgTable, text, uuid, timestamp, jsonb, index } from "drizzle-orm/pg-core";
+import { sql } from "drizzle-orm";
+import { watches } from "./watches";
+import { entities } from "./entities";
+import { watchRuns } from "./watch-runs";
+
+export const entityVersions = pgTable(
+  "entity_versions",
+  {
+    id: uuid("id")
+      .primaryKey()
+      .default(sql`gen_random_uuid()`),
+    orgId: text("org_id").notNull(),
+    watchId: uuid("watch_id")
+      .notNull()
+      .references(() => watches.id),
+    entityId: uuid("entity_id")
+      .notNull()
+      .references(() => entities.id),
+    watchRunId: uuid("watch_run_id")
+      .notNull()
+      .references(() => watchRuns.id),
+    externalId: text("external_id").notNull(),
+    changeType: text("change_type").notNull(),
+    content: jsonb("content"),
+    createdAt: timestamp("created_at", { withTimezone: true }).notNull().defaultNow(),
+  },
+  (table) => [
+    index("idx_entity_versions_entity_id").on(table.entityId, table.createdAt),
+    index("idx_entity_versions_watch_id").on(table.watchId, table.createdAt),
+  ],
+);
@@ -1,7 +1,11 @@
 export * from "./blueprints";
+export * from "./blueprint-revisions";
 export * from "./watches";
 export * from "./entities";
+export * from "./entity-schemas";
 export * from "./watch-runs";
+export * from "./watch-run-snapshots";
+export * from "./entity-versions";
 export * from "./events";
 export * from "./subscriptions";
 export * from "./deliveries";
@@ -0,0 +1,30 @@
+import { pgTable, text, uuid, timestamp, integer, index } from "drizzle-orm/pg-core";
+import { sql } from "drizzle-orm";
+import { watches } from "./watches";
+import { watchRuns } from "./watch-runs";
+
+export const watchRunSnapshots = pgTable(
+  "watch_run_snapshots",
+  {
+    id: uuid("id")
+      .primaryKey()
+      .default(sql`gen_random_uuid()`),
+    orgId: text("org_id").notNull(),
+    watchId: uuid("watch_id")
+      .notNull()
+      .references(() => watches.id),
+    watchRunId: uuid("watch_run_id")
+      .notNull()
+      .references(() => watchRuns.id),
+    url: text("url").notNull(),
+    rawHtmlKey: text("raw_html_key").notNull(),
+    cleanedHtmlKey: text("cleaned_html_key").notNull(),
+    rawSize: integer("raw_size").notNull(),
+    cleanedSize: integer("cleaned_size").notNull(),
+    createdAt: timestamp("created_at", { withTimezone: true }).notNull().defaultNow(),
+  },
+  (table) => [
+    index("idx_watch_run_snapshots_watch_run_id").on(table.watchRunId),
+    index("idx_watch_run_snapshots_created_at").on(table.createdAt),
+  ],
+);
@@ -1,6 +1,8 @@
-import { pgTable, text, uuid, timestamp, integer, index } from "drizzle-orm/pg-core";
+import { pgTable, text, uuid, timestamp, integer, jsonb, index } from "drizzle-orm/pg-core";
 import { sql } from "drizzle-orm";
+import type { PageError } from "../../lib/types";
 import { watches } from "./watches";
+import { blueprintRevisions } from "./blueprint-revisions";
 
 export const watchRuns = pgTable(
   "watch_runs",
@@ -12,6 +14,7 @@ export const watchRuns = pgTable(
     watchId: uuid("watch_id")
       .notNull()
       .references(() => watches.id),
+    blueprintRevisionId: uuid("blueprint_revision_id").references(() => blueprintRevisions.id),
     status: text("status").notNull().default("running"),
     startedAt: timestamp("started_at", { withTimezone: true }).notNull().defaultNow(),
     completedAt: timestamp("completed_at", { withTimezone: true }),
@@ -21,6 +24,11 @@ export const watchRuns = pgTable(
     entitiesRemoved: integer("entities_removed"),
     eventsEmitted: integer("events_emitted"),
     errorMessage: text("error_message"),
+    cancelRequestedAt: timestamp("cancel_requested_at", { withTimezone: true }),
+    phase: text("phase"),
+    identityCollisions: integer("identity_collisions"),
+    identityMissing: integer("identity_missing"),
+    pageErrors: jsonb("page_errors").$type<PageError[]>().notNull().default([]),
   },
   (table) => [
     index("idx_watch_runs_watch_id").on(table.watchId),
@@ -1,5 +1,16 @@
-import { pgTable, text, uuid, timestamp, index } from "drizzle-orm/pg-core";
+import {
+  pgTable,
+  text,
+  uuid,
+  timestamp,
+  integer,
+  boolean,
+  doublePrecision,
+  jsonb,
+  index,
+} from "drizzle-orm/pg-core";
 import { sql } from "drizzle-orm";
+import type { DiffPolicy } from "../../lib/types";
 import { blueprints } from "./blueprints";
 
 export const watches = pgTable(
@@ -14,13 +25,45 @@ export const watches = pgTable(
       .references(() => blueprints.id),
     name: text("name").notNull(),
     url: text("url").notNull(),
+    urls: text("urls")
+      .array()
+      .notNull()
+      .default(sql`ARRAY[]::text[]`),
+    urlParams: text("url_params")
+      .array()
+      .notNull()
+      .default(sql`ARRAY[]::text[]`),
     schedule: text("schedule").notNull(),
+    timezone: text("timezone").notNull().default("UTC"),
+    jitterSeconds: integer("jitter_seconds").notNull().default(0),
+    adaptiveSchedule: boolean("adaptive_schedule").notNull().default(false),
+    adaptiveMinSeconds: integer("adaptive_min_seconds").notNull().default(300),
+    adaptiveMaxSeconds: integer("adaptive_max_seconds").notNull().default(86400),
+    adaptiveIntervalSeconds: integer("adaptive_interval_seconds"),
     identityFields: text("identity_fields")
       .array()
       .notNull()
       .default(sql`ARRAY['name']::text[]`),
+    identityFallbacks: jsonb("identity_fallbacks").$type<string[][]>().notNull().default([]),
+    identityNormalize: text("identity_normalize")
+      .array()
+      .notNull()
+      .default(sql`ARRAY[]::text[]`),
+    trackPositions: boolean("track_positions").notNull().default(false),
+    renameThreshold: doublePrecision("rename_threshold").notNull().default(0),
+    diffPolicy: jsonb("diff_policy").$type<DiffPolicy>().notNull().default({}),
     status: text("status").notNull().default("active"),
     nextRunAt: timestamp("next_run_at", { withTimezone: true }),
+    lastRunAt: timestamp("last_run_at", { withTimezone: true }),
+    lastError: text("last_error"),
+    consecutiveFailures: integer("consecutive_failures").notNull().default(0),
+    minEntityCount: integer("min_entity_count").notNull().default(1),
+    maxDisappearanceRatio: doublePrecision("max_disappearance_ratio").notNull().default(0.5),
+    acceptNextRun: boolean("accept_next_run").notNull().default(false),
+    graceMissedRuns: integer("grace_missed_runs").notNull().default(1),
+    gracePeriodSeconds: integer("grace_period_seconds").notNull().default(0),
+    leaseOwner: text("lease_owner"),
+    leaseExpiresAt: timestamp("lease_expires_at", { withTimezone: true }),
     createdAt: timestamp("created_at", { withTimezone: true }).notNull().defaultNow(),
     updatedAt: timestamp("updated_at", { withTimezone: true }).notNull().defaultNow(),
     deletedAt: timestamp("deleted_at", { withTimezone: true }),
@@ -3,12 +3,20 @@
  * These map semantic user-facing labels to generic event_type + filter combinations.
  */
 
-export type FilterOperator = "changed" | "increased" | "decreased" | "eq";
+export type FilterOperator =
+  | "changed"
+  | "increased"
+  | "decreased"
+  | "eq"
+  | "moved_up"
+  | "moved_down"
+  | "entered_top";
 
 export interface PresetFilter {
   field: string;
   operator?: FilterOperator;
   new?: string;
+  value?: string;
 }
 
 export interface StoredCondition {
@@ -41,6 +49,9 @@ export function presetToFilters(presetFilters: PresetFilter[]): StoredFilters {
     if (f.new) {
       return { field: f.field, operator: "eq", value: f.new };
     }
+    if (f.value) {
+      return { field: f.field, operator: f.operator ?? "changed", value: f.value };
+    }
     return { field: f.field, operator: f.operator ?? "changed" };
   });
 
@@ -99,4 +110,24 @@ export const ecommercePresets: SubscriptionPreset[] = [
   },
 ];
 
-export const allPresets: SubscriptionPreset[] = [...ecommercePresets];
+/**
+ * Listing position presets, for watches that track positions.
+ */
+export const positionPresets: SubscriptionPreset[] = [
+  {
+    name: "position_moved_up",
+    label: "Moved up in the listing",
+    schemaType: "ecommerce_product",
+    eventTypes: ["entity_changed"],
+    filters: [{ field: "_position", operator: "moved_up", value: "1" }],
+  },
+  {
+    name: "position_entered_top_10",
+    label: "Entered the top 10",
+    schemaType: "ecommerce_product",
+    eventTypes: ["entity_changed", "entity_appeared"],
+    filters: [{ field: "_position", operator: "entered_top", value: "10" }],
+  },
+];
+
+export const allPresets: SubscriptionPreset[] = [...ecommercePresets, ...positionPresets];
@@ -23,3 +23,27 @@ export interface Blueprint {
   updatedAt: Date;
   deletedAt: Date | null;
 }
+
+// How a watch compares entity fields; mirrors the worker's differ.Policy.
+export interface FieldRule {
+  abs_tolerance?: number;
+  rel_tolerance?: number;
+  ignore_case?: boolean;
+  collapse_whitespace?: boolean;
+  normalize_unicode?: boolean;
+}
+
+export interface DiffPolicy {
+  ignore?: string[];
+  significant?: string[];
+  default?: FieldRule;
+  fields?: Record<string, FieldRule>;
+}
+
+// A page a watch run went on without; mirrors the worker's scheduler.PageError.
+export interface PageError {
+  url: string;
+  param?: string;
+  phase: "fetch" | "extract";
+  error: string;
+}
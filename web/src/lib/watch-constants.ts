@@ -1,3 +1,5 @@
+import cronstrue from "cronstrue";
+
 export interface BlueprintOption {
   id: string;
   name: string;
@@ -12,3 +14,79 @@ export const SCHEDULE_PRESETS = [
   { value: "0 */6 * * *", label: "Every 6 hours" },
   { value: "0 0 * * *", label: "Daily (midnight)" },
 ] as const;
+
+// Cron descriptors the worker accepts, spelled out for cronstrue.
+const SCHEDULE_DESCRIPTORS: Record<string, string> = {
+  "@yearly": "0 0 1 1 *",
+  "@annually": "0 0 1 1 *",
+  "@monthly": "0 0 1 * *",
+  "@weekly": "0 0 * * 0",
+  "@daily": "0 0 * * *",
+  "@midnight": "0 0 * * *",
+  "@hourly": "0 * * * *",
+};
+
+export function describeSchedule(schedule: string, timezone = "UTC"): string {
+  let text: string;
+  if (schedule.startsWith("@every ")) {
+    text = `Every ${schedule.slice("@every ".length)}`;
+  } else {
+    try {
+      text = cronstrue.toString(SCHEDULE_DESCRIPTORS[schedule] ?? schedule);
+    } catch {
+      return schedule;
+    }
+  }
+  return timezone === "UTC" ? text : `${text} (${timezone})`;
+}
+
+// Placeholder a watch URL is expanded with, once per URL parameter.
+export const URL_PARAM_PLACEHOLDER = "{param}";
+
+// Splits a whitespace-separated list of URLs.
+export function splitUrls(value: string): string[] {
+  return value.split(/\s+/).filter(Boolean);
+}
+
+// Splits a comma-separated list of URL parameters.
+export function splitParams(value: string): string[] {
+  return value
+    .split(",")
+    .map((p) => p.trim())
+    .filter(Boolean);
+}
+
+// Mirrors the worker's URL expansion rules: a URL template needs parameters,
+// and parameters need a template. Returns an error message, or null.
+export function checkWatchUrls(url: string, urls: string[], urlParams: string[]): string | null {
+  const templated = [url, ...urls].some((u) => u.includes(URL_PARAM_PLACEHOLDER));
+  if (templated && urlParams.length === 0) {
+    return `URL contains ${URL_PARAM_PLACEHOLDER} but no URL parameters are set`;
+  }
+  if (!templated && urlParams.length > 0) {
+    return `URL parameters are set but no URL contains ${URL_PARAM_PLACEHOLDER}`;
+  }
+  return null;
+}
+
+// Shortest interval the worker runs an adaptive watch at.
+export const MIN_ADAPTIVE_SECONDS = 60;
+
+// Adaptive interval bounds a new watch starts with.
+export const DEFAULT_ADAPTIVE_MIN_SECONDS = 300;
+export const DEFAULT_ADAPTIVE_MAX_SECONDS = 86400;
+
+// Mirrors the bounds the worker's adaptive scheduling needs. Returns an error
+// message, or null.
+export function checkAdaptiveBounds(minSeconds: number, maxSeconds: number): string | null {
+  if (!Number.isFinite(minSeconds) || !Number.isFinite(maxSeconds)) {
+    return "Adaptive interval bounds must be numbers";
+  }
+  if (minSeconds < MIN_ADAPTIVE_SECONDS) {
+    return `Minimum adaptive interval must be at least ${MIN_ADAPTIVE_SECONDS / 60} minute`;
+  }
+  if (minSeconds > maxSeconds) {
+    return "Minimum adaptive interval must not exceed the maximum";
+  }
+  return null;
+}
@@ -1,4 +1,4 @@
-import type { ExtractionRules } from "./types";
+import type { DiffPolicy, ExtractionRules } from "./types";
 
 const WORKER_URL = process.env.WORKER_URL ?? "http://localhost:8081";
 const WORKER_API_KEY = process.env.WORKER_API_KEY ?? "";
@@ -54,10 +54,39 @@ export async function workerTestBlueprint(
   });
 }
 
+export async function workerUpdateBlueprintRules(
+  orgId: string,
+  blueprintId: string,
+  extractionRules: ExtractionRules,
+): Promise<{ id: string; version: number }> {
+  return workerRequest(`/api/blueprints/${blueprintId}/rules`, {
+    org_id: orgId,
+    extraction_rules: extractionRules,
+  });
+}
+
+export async function workerValidateSchedule(
+  schedule: string,
+  timezone: string,
+  jitterSeconds: number,
+): Promise<{ next_runs: string[] }> {
+  return workerRequest("/api/validate-schedule", {
+    schedule,
+    timezone,
+    jitter_seconds: jitterSeconds,
+  });
+}
+
+export async function workerValidateDiffPolicy(
+  diffPolicy: DiffPolicy,
+): Promise<{ diff_policy: DiffPolicy }> {
+  return workerRequest("/api/validate-diff-policy", { diff_policy: diffPolicy });
+}
+
 export async function workerTriggerRun(
   orgId: string,
   watchId: string,
-): Promise<{ run_id: string }> {
+): Promise<{ run_id: string; status: string }> {
   return workerRequest("/api/run-watch", {
     org_id: orgId,
     watch_id: watchId,
@@ -2,9 +2,14 @@
 
 import { withAuth } from "@workos-inc/authkit-nextjs";
 import { db } from "@/db";
-import { blueprints } from "@/db/schema";
+import { blueprints, blueprintRevisions } from "@/db/schema";
 import { eq, and, isNull, desc } from "drizzle-orm";
-import { workerFetchHtml, workerGenerateBlueprint, workerTestBlueprint } from "@/lib/worker-client";
+import {
+  workerFetchHtml,
+  workerGenerateBlueprint,
+  workerTestBlueprint,
+  workerUpdateBlueprintRules,
+} from "@/lib/worker-client";
 import type { ExtractionRules } from "@/lib/types";
 
 async function getOrgId(): Promise<string> {
@@ -56,17 +61,33 @@ export async function createBlueprint(data: {
   extractionRules: ExtractionRules;
 }) {
   const orgId = await getOrgId();
-  const rows = await db
-    .insert(blueprints)
-    .values({
+  return db.transaction(async (tx) => {
+    const rows = await tx
+      .insert(blueprints)
+      .values({
+        orgId,
+        name: data.name,
+        url: data.url,
+        schemaType: data.schemaType,
+        extractionRules: data.extractionRules,
+      })
+      .returning();
+    const bp = rows[0];
+    await tx.insert(blueprintRevisions).values({
       orgId,
-      name: data.name,
-      url: data.url,
-      schemaType: data.schemaType,
-      extractionRules: data.extractionRules,
-    })
-    .returning();
-  return rows[0];
+      blueprintId: bp.id,
+      version: bp.version,
+      schemaType: bp.schemaType,
+      extractionRules: bp.extractionRules,
+    });
+    return bp;
+  });
+}
+
+// Rule edits go through the worker, which records the revision with them.
+export async function updateBlueprintRules(id: string, extractionRules: ExtractionRules) {
+  const orgId = await getOrgId();
+  return workerUpdateBlueprintRules(orgId, id, extractionRules);
 }
 
 export async function updateBlueprintStatus(id: string, status: string) {
@@ -4,20 +4,38 @@ import { withAuth } from "@workos-inc/authkit-nextjs";
 import { db } from "@/db";
 import { watches, watchRuns, entities, blueprints, events } from "@/db/schema";
 import { eq, and, isNull, desc, sql } from "drizzle-orm";
-import { workerTriggerRun } from "@/lib/worker-client";
+import {
+  workerTriggerRun,
+  workerValidateDiffPolicy,
+  workerValidateSchedule,
+} from "@/lib/worker-client";
+import {
+  DEFAULT_ADAPTIVE_MAX_SECONDS,
+  DEFAULT_ADAPTIVE_MIN_SECONDS,
+  checkAdaptiveBounds,
+  checkWatchUrls,
+} from "@/lib/watch-constants";
+import type { DiffPolicy } from "@/lib/types";
 
 export type WatchHealth = "operational" | "degraded" | "error";
 
-function computeHealth(statuses: string[]): WatchHealth {
+// Runs stopped by a user or a worker shutdown say nothing about the watch.
+function countsTowardHealth(status: string): boolean {
+  return status !== "cancelled" && status !== "interrupted";
+}
+
+function computeHealth(allStatuses: string[]): WatchHealth {
+  const statuses = allStatuses.filter(countsTowardHealth);
   if (statuses.length === 0) return "operational";
   const allCompleted = statuses.every((s) => s === "completed");
   if (allCompleted) return "operational";
-  const allFailed = statuses.every((s) => s === "failed");
+  const allFailed = statuses.every((s) => s === "failed" || s === "timed_out");
   if (allFailed) return "error";
   return "degraded";
 }
 
-function computeHealthDetail(statuses: string[]): string {
+function computeHealthDetail(allStatuses: string[]): string {
+  const statuses = allStatuses.filter(countsTowardHealth);
   if (statuses.length === 0) return "No runs yet";
   const succeeded = statuses.filter((s) => s === "completed").length;
   const total = statuses.length;
@@ -40,6 +58,7 @@ export async function listWatches() {
       name: watches.name,
       url: watches.url,
       schedule: watches.schedule,
+      timezone: watches.timezone,
       status: watches.status,
       nextRunAt: watches.nextRunAt,
       createdAt: watches.createdAt,
@@ -77,8 +96,25 @@ export async function getWatch(id: string) {
       blueprintId: watches.blueprintId,
       name: watches.name,
       url: watches.url,
+      urls: watches.urls,
+      urlParams: watches.urlParams,
       schedule: watches.schedule,
+      timezone: watches.timezone,
+      jitterSeconds: watches.jitterSeconds,
+      adaptiveSchedule: watches.adaptiveSchedule,
+      adaptiveMinSeconds: watches.adaptiveMinSeconds,
+      adaptiveMaxSeconds: watches.adaptiveMaxSeconds,
+      adaptiveIntervalSeconds: watches.adaptiveIntervalSeconds,
       identityFields: watches.identityFields,
+      identityFallbacks: watches.identityFallbacks,
+      identityNormalize: watches.identityNormalize,
+      trackPositions: watches.trackPositions,
+      renameThreshold: watches.renameThreshold,
+      diffPolicy: watches.diffPolicy,
+      minEntityCount: watches.minEntityCount,
+      maxDisappearanceRatio: watches.maxDisappearanceRatio,
+      graceMissedRuns: watches.graceMissedRuns,
+      gracePeriodSeconds: watches.gracePeriodSeconds,
       status: watches.status,
       nextRunAt: watches.nextRunAt,
       createdAt: watches.createdAt,
@@ -119,10 +155,41 @@ export async function createWatch(data: {
   blueprintId: string;
   name: string;
   url: string;
+  urls?: string[];
+  urlParams?: string[];
   schedule: string;
+  timezone?: string;
+  jitterSeconds?: number;
+  adaptiveSchedule?: boolean;
+  adaptiveMinSeconds?: number;
+  adaptiveMaxSeconds?: number;
   identityFields?: string[];
+  identityFallbacks?: string[][];
+  identityNormalize?: string[];
+  trackPositions?: boolean;
+  renameThreshold?: number;
+  diffPolicy?: DiffPolicy;
+  minEntityCount?: number;
+  maxDisappearanceRatio?: number;
+  graceMissedRuns?: number;
+  gracePeriodSeconds?: number;
 }) {
   const orgId = await getOrgId();
+  const urlError = checkWatchUrls(data.url, data.urls ?? [], data.urlParams ?? []);
+  if (urlError) {
+    throw new Error(urlError);
+  }
+  const boundsError = checkAdaptiveBounds(
+    data.adaptiveMinSeconds ?? DEFAULT_ADAPTIVE_MIN_SECONDS,
+    data.adaptiveMaxSeconds ?? DEFAULT_ADAPTIVE_MAX_SECONDS,
+  );
+  if (boundsError) {
+    throw new Error(boundsError);
+  }
+  await workerValidateSchedule(data.schedule, data.timezone ?? "UTC", data.jitterSeconds ?? 0);
+  if (data.diffPolicy) {
+    await workerValidateDiffPolicy(data.diffPolicy);
+  }
   const rows = await db
     .insert(watches)
     .values({
@@ -130,8 +197,24 @@ export async function createWatch(data: {
       blueprintId: data.blueprintId,
       name: data.name,
       url: data.url,
+      urls: data.urls,
+      urlParams: data.urlParams,
       schedule: data.schedule,
+      timezone: data.timezone,
+      jitterSeconds: data.jitterSeconds,
+      adaptiveSchedule: data.adaptiveSchedule,
+      adaptiveMinSeconds: data.adaptiveMinSeconds,
+      adaptiveMaxSeconds: data.adaptiveMaxSeconds,
       identityFields: data.identityFields ?? ["name"],
+      identityFallbacks: data.identityFallbacks,
+      identityNormalize: data.identityNormalize,
+      trackPositions: data.trackPositions,
+      renameThreshold: data.renameThreshold,
+      diffPolicy: data.diffPolicy,
+      minEntityCount: data.minEntityCount,
+      maxDisappearanceRatio: data.maxDisappearanceRatio,
+      graceMissedRuns: data.graceMissedRuns,
+      gracePeriodSeconds: data.gracePeriodSeconds,
       nextRunAt: new Date(),
     })
     .returning();
@@ -143,12 +226,92 @@ export async function updateWatch(
   data: Partial<{
     name: string;
     url: string;
+    urls: string[];
+    urlParams: string[];
     schedule: string;
+    timezone: string;
+    jitterSeconds: number;
+    adaptiveSchedule: boolean;
+    adaptiveMinSeconds: number;
+    adaptiveMaxSeconds: number;
     identityFields: string[];
+    identityFallbacks: string[][];
+    identityNormalize: string[];
+    trackPositions: boolean;
+    renameThreshold: number;
+    diffPolicy: DiffPolicy;
     blueprintId: string;
+    minEntityCount: number;
+    maxDisappearanceRatio: number;
+    graceMissedRuns: number;
+    gracePeriodSeconds: number;
   }>,
 ) {
   const orgId = await getOrgId();
+  if (
+    data.schedule !== undefined ||
+    data.timezone !== undefined ||
+    data.jitterSeconds !== undefined
+  ) {
+    const current = await db
+      .select({
+        schedule: watches.schedule,
+        timezone: watches.timezone,
+        jitterSeconds: watches.jitterSeconds,
+      })
+      .from(watches)
+      .where(and(eq(watches.id, id), eq(watches.orgId, orgId)))
+      .limit(1);
+    if (!current[0]) {
+      throw new Error("Watch not found");
+    }
+    await workerValidateSchedule(
+      data.schedule ?? current[0].schedule,
+      data.timezone ?? current[0].timezone,
+      data.jitterSeconds ?? current[0].jitterSeconds,
+    );
+  }
+  if (data.diffPolicy !== undefined) {
+    await workerValidateDiffPolicy(data.diffPolicy);
+  }
+  if (data.adaptiveMinSeconds !== undefined || data.adaptiveMaxSeconds !== undefined) {
+    const current = await db
+      .select({
+        adaptiveMinSeconds: watches.adaptiveMinSeconds,
+        adaptiveMaxSeconds: watches.adaptiveMaxSeconds,
+      })
+      .from(watches)
+      .where(and(eq(watches.id, id), eq(watches.orgId, orgId)))
+      .limit(1);
+    if (!current[0]) {
+      throw new Error("Watch not found");
+    }
+    const boundsError = checkAdaptiveBounds(
+      data.adaptiveMinSeconds ?? current[0].adaptiveMinSeconds,
+      data.adaptiveMaxSeconds ?? current[0].adaptiveMaxSeconds,
+    );
+    if (boundsError) {
+      throw new Error(boundsError);
+    }
+  }
+  if (data.url !== undefined || data.urls !== undefined || data.urlParams !== undefined) {
+    const current = await db
+      .select({ url: watches.url, urls: watches.urls, urlParams: watches.urlParams })
+      .from(watches)
+      .where(and(eq(watches.id, id), eq(watches.orgId, orgId)))
+      .limit(1);
+    if (!current[0]) {
+      throw new Error("Watch not found");
+    }
+    const urlError = checkWatchUrls(
+      data.url ?? current[0].url,
+      data.urls ?? current[0].urls,
+      data.urlParams ?? current[0].urlParams,
+    );
+    if (urlError) {
+      throw new Error(urlError);
+    }
+  }
   await db
     .update(watches)
     .set({ ...data, updatedAt: new Date() })
@@ -178,6 +341,8 @@ export async function unpauseWatch(id: string) {
     .set({
       status: "active",
       nextRunAt: new Date(),
+      consecutiveFailures: 0,
+      lastError: null,
       updatedAt: new Date(),
     })
     .where(and(eq(watches.id, id), eq(watches.orgId, orgId)));
@@ -230,3 +395,21 @@ export async function triggerWatchRun(watchId: string) {
 
   return workerTriggerRun(orgId, watchId);
 }
+
+// Runs a watch whose last run tripped a safety guard, applying whatever it
+// extracts: for when the page really did change that much.
+export async function acceptWatchRun(watchId: string) {
+  const orgId = await getOrgId();
+
+  const updated = await db
+    .update(watches)
+    .set({ acceptNextRun: true, updatedAt: new Date() })
+    .where(and(eq(watches.id, watchId), eq(watches.orgId, orgId), isNull(watches.deletedAt)))
+    .returning({ id: watches.id });
+
+  if (!updated[0]) {
+    throw new Error("Watch not found");
+  }
+
+  return workerTriggerRun(orgId, watchId);
+}
@@ -7,10 +7,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
-	"time"
+	_ "time/tzdata" // watch timezones must resolve without system zoneinfo
 
 	"github.com/blueprinter/worker/internal/api"
+	"github.com/blueprinter/worker/internal/archive"
 	"github.com/blueprinter/worker/internal/blueprint"
 	"github.com/blueprinter/worker/internal/config"
 	"github.com/blueprinter/worker/internal/db"
@@ -57,38 +59,61 @@ func run(logger *slog.Logger) error {
 	}
 
 	openaiClient := blueprint.NewOpenAIClient(cfg.OpenAIAPIKey, cfg.OpenAIModel, logger)
+	schemaRegistry := blueprint.NewSchemaRegistry(queries)
+	revisionStore := blueprint.NewRevisionStore(pool, queries)
+
+	// Snapshot archive (optional)
+	snapshotArchive, err := newArchive(cfg, queries, logger)
+	if err != nil {
+		return fmt.Errorf("creating snapshot archive: %w", err)
+	}
 
 	// Emitter + Matcher
 	eventEmitter := emitter.New(queries, logger)
-	eventMatcher := matcher.New(queries, logger)
+	eventMatcher := matcher.New(logger)
 	eventEmitter.SetMatcher(eventMatcher)
 
 	// Scheduler
-	executor := scheduler.NewExecutor(queries, fetcherClient, eventEmitter, logger)
-	sched := scheduler.NewScheduler(executor, queries, logger)
+	timeouts := scheduler.RunTimeouts{
+		Fetch:   cfg.RunFetchTimeout,
+		Extract: cfg.RunExtractTimeout,
+		Persist: cfg.RunPersistTimeout,
+	}
+	executor := scheduler.NewExecutor(pool, queries, fetcherClient, eventEmitter, schemaRegistry, snapshotArchive, cfg.WorkerID, timeouts, logger)
+	sched := scheduler.NewScheduler(executor, queries, cfg.SchedulerConcurrency, logger)
 
 	// HTTP server
-	handlers := api.NewHandlers(fetcherClient, openaiClient, sched, logger)
+	handlers := api.NewHandlers(fetcherClient, openaiClient, schemaRegistry, revisionStore, snapshotArchive, sched, logger)
 	srv := api.NewServer(cfg.Port, cfg.WorkerAPIKey, handlers, logger)
 
 	errCh := make(chan error, 1)
 
+	// Background work is stopped through Shutdown rather than by the signal,
+	// so that in-flight runs and deliveries can drain.
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
 	// Start delivery processor if Resend is configured
+	var processor *delivery.Processor
 	if cfg.ResendAPIKey != "" {
 		sender := delivery.NewResendSender(cfg.ResendAPIKey, cfg.ResendFromEmail)
-		processor := delivery.NewProcessor(queries, sender, logger)
-		go processor.Run(ctx)
+		processor = delivery.NewProcessor(queries, sender, cfg.WorkerID, logger)
+		go processor.Run(workCtx)
 		logger.Info("delivery processor enabled", "from_email", cfg.ResendFromEmail)
 	} else {
 		logger.Info("delivery processor disabled (RESEND_API_KEY not set)")
 	}
 
+	if snapshotArchive != nil {
+		go snapshotArchive.RunRetention(ctx)
+	}
+
 	// Start scheduler in background
-	go sched.Run(ctx)
+	go sched.Run(workCtx)
 
 	// Start HTTP server
 	go func() {
-		logger.Info("worker starting", "port", cfg.Port)
+		logger.Info("worker starting", "port", cfg.Port, "worker_id", cfg.WorkerID)
 		errCh <- srv.ListenAndServe()
 	}()
 
@@ -98,14 +123,76 @@ func run(logger *slog.Logger) error {
 			return fmt.Errorf("server error: %w", err)
 		}
 	case <-ctx.Done():
-		logger.Info("shutting down...")
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		logger.Info("shutting down...", "timeout", cfg.ShutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 		defer cancel()
-		if err := srv.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("shutdown error: %w", err)
-		}
+		shutdown(shutdownCtx, srv, sched, processor, logger)
 	}
 
 	logger.Info("worker stopped")
 	return nil
 }
+
+// shutdown stops accepting requests and drains the scheduler and the delivery
+// processor in parallel. Work still in progress when ctx ends is interrupted
+// and recorded as such.
+func shutdown(ctx context.Context, srv *http.Server, sched *scheduler.Scheduler, processor *delivery.Processor, logger *slog.Logger) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Error("http server shutdown", "error", err)
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := sched.Shutdown(ctx); err != nil {
+			logger.Warn("scheduler interrupted runs in progress", "error", err)
+		}
+	}()
+	if processor != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := processor.Shutdown(ctx); err != nil {
+				logger.Warn("delivery processor interrupted sends in progress", "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// newArchive builds the snapshot archive for the configured backend, or
+// returns nil when archiving is disabled.
+func newArchive(cfg *config.Config, queries *dbgen.Queries, logger *slog.Logger) (*archive.Archive, error) {
+	var store archive.Store
+	switch cfg.ArchiveBackend {
+	case "":
+		logger.Info("snapshot archive disabled (ARCHIVE_BACKEND not set)")
+		return nil, nil
+	case "fs":
+		fsStore, err := archive.NewFSStore(cfg.ArchiveDir)
+		if err != nil {
+			return nil, err
+		}
+		store = fsStore
+	case "s3":
+		s3Store, err := archive.NewS3Store(archive.S3Config{
+			Endpoint:        cfg.ArchiveS3Endpoint,
+			Region:          cfg.ArchiveS3Region,
+			Bucket:          cfg.ArchiveS3Bucket,
+			AccessKeyID:     cfg.ArchiveS3AccessKey,
+			SecretAccessKey: cfg.ArchiveS3SecretKey,
+			SessionToken:    cfg.ArchiveS3SessionToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		store = s3Store
+	}
+
+	logger.Info("snapshot archive enabled", "backend", cfg.ArchiveBackend, "retention", cfg.ArchiveRetention)
+	return archive.New(store, queries, cfg.ArchiveRetention, logger), nil
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/blueprinter/worker/internal/blueprint"
+)
+
+type listRevisionsResponse struct {
+	Revisions []blueprint.Revision `json:"revisions"`
+}
+
+// HandleListBlueprintRevisions returns the revision history of a blueprint.
+func (h *Handlers) HandleListBlueprintRevisions(w http.ResponseWriter, r *http.Request) {
+	orgID := r.URL.Query().Get("org_id")
+	if orgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	revisions, err := h.revisions.List(r.Context(), orgID, r.PathValue("id"))
+	if err != nil {
+		h.writeRevisionError(w, "list revisions", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listRevisionsResponse{Revisions: revisions})
+}
+
+type compareRevisionsRequest struct {
+	OrgID          string   `json:"org_id"`
+	FromVersion    int32    `json:"from_version"`
+	ToVersion      int32    `json:"to_version"`
+	CleanedHTML    string   `json:"cleaned_html,omitempty"`    // defaults to snapshot_id, then a fresh fetch of the blueprint URL
+	SnapshotID     string   `json:"snapshot_id,omitempty"`     // archived page to compare against
+	IdentityFields []string `json:"identity_fields,omitempty"` // defaults to the schema's identity fields
+}
+
+type compareRevisionsResponse struct {
+	FromVersion int32 `json:"from_version"`
+	ToVersion   int32 `json:"to_version"`
+	*blueprint.RuleComparison
+}
+
+// HandleCompareBlueprintRevisions extracts with two revisions of a blueprint
+// from the same HTML and reports how the results differ.
+func (h *Handlers) HandleCompareBlueprintRevisions(w http.ResponseWriter, r *http.Request) {
+	var req compareRevisionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.OrgID == "" || req.FromVersion == 0 || req.ToVersion == 0 {
+		writeError(w, http.StatusBadRequest, "org_id, from_version, and to_version are required")
+		return
+	}
+
+	blueprintID := r.PathValue("id")
+	from, err := h.revisions.Get(r.Context(), req.OrgID, blueprintID, req.FromVersion)
+	if err != nil {
+		h.writeRevisionError(w, "load revision", err)
+		return
+	}
+	to, err := h.revisions.Get(r.Context(), req.OrgID, blueprintID, req.ToVersion)
+	if err != nil {
+		h.writeRevisionError(w, "load revision", err)
+		return
+	}
+
+	cleanedHTML := req.CleanedHTML
+	if cleanedHTML == "" && req.SnapshotID != "" {
+		if !h.requireArchive(w) {
+			return
+		}
+		cleanedHTML, err = h.archive.CleanedHTML(r.Context(), req.OrgID, req.SnapshotID)
+		if err != nil {
+			h.writeSnapshotError(w, "load snapshot", err)
+			return
+		}
+	}
+	if cleanedHTML == "" {
+		bp, err := h.revisions.GetBlueprint(r.Context(), req.OrgID, blueprintID)
+		if err != nil {
+			h.writeRevisionError(w, "load blueprint", err)
+			return
+		}
+		rawHTML, err := h.fetcher.FetchHTML(r.Context(), bp.Url)
+		if err != nil {
+			h.logger.Error("fetch HTML failed", "url", bp.Url, "error", err)
+			writeError(w, http.StatusBadGateway, "failed to fetch HTML: "+err.Error())
+			return
+		}
+		cleanedHTML, err = blueprint.Clean(rawHTML)
+		if err != nil {
+			h.logger.Error("clean HTML failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to clean HTML: "+err.Error())
+			return
+		}
+	}
+
+	identityFields := req.IdentityFields
+	if len(identityFields) == 0 {
+		schema, err := h.schemas.GetSchema(r.Context(), req.OrgID, to.SchemaType)
+		if err != nil {
+			h.writeSchemaError(w, err)
+			return
+		}
+		identityFields = schema.IdentityFields
+	}
+
+	cmp, err := blueprint.CompareRules(cleanedHTML, from.ExtractionRules, to.ExtractionRules, identityFields)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compareRevisionsResponse{
+		FromVersion:    from.Version,
+		ToVersion:      to.Version,
+		RuleComparison: cmp,
+	})
+}
+
+type rollbackBlueprintRequest struct {
+	OrgID   string `json:"org_id"`
+	Version int32  `json:"version"`
+}
+
+// HandleRollbackBlueprint restores an earlier revision as the blueprint's new current version.
+func (h *Handlers) HandleRollbackBlueprint(w http.ResponseWriter, r *http.Request) {
+	var req rollbackBlueprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.OrgID == "" || req.Version == 0 {
+		writeError(w, http.StatusBadRequest, "org_id and version are required")
+		return
+	}
+
+	rev, err := h.revisions.Rollback(r.Context(), req.OrgID, r.PathValue("id"), req.Version)
+	if err != nil {
+		h.writeRevisionError(w, "rollback blueprint", err)
+		return
+	}
+
+	h.logger.Info("blueprint rolled back",
+		"blueprint_id", rev.BlueprintID,
+		"restored_version", req.Version,
+		"new_version", rev.Version,
+	)
+	writeJSON(w, http.StatusOK, rev)
+}
+
+type updateBlueprintRulesRequest struct {
+	OrgID           string                     `json:"org_id"`
+	ExtractionRules *blueprint.ExtractionRules `json:"extraction_rules"`
+}
+
+// HandleUpdateBlueprintRules saves new extraction rules as the blueprint's next version.
+func (h *Handlers) HandleUpdateBlueprintRules(w http.ResponseWriter, r *http.Request) {
+	var req updateBlueprintRulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.OrgID == "" || req.ExtractionRules == nil {
+		writeError(w, http.StatusBadRequest, "org_id and extraction_rules are required")
+		return
+	}
+
+	rev, err := h.revisions.UpdateRules(r.Context(), req.OrgID, r.PathValue("id"), req.ExtractionRules)
+	if err != nil {
+		h.writeRevisionError(w, "update blueprint rules", err)
+		return
+	}
+
+	h.logger.Info("blueprint rules updated", "blueprint_id", rev.BlueprintID, "version", rev.Version)
+	writeJSON(w, http.StatusOK, rev)
+}
+
+func (h *Handlers) writeRevisionError(w http.ResponseWriter, action string, err error) {
+	if errors.Is(err, blueprint.ErrBlueprintNotFound) || errors.Is(err, blueprint.ErrRevisionNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.logger.Error(action+" failed", "error", err)
+	writeError(w, http.StatusInternalServerError, "failed to "+action+": "+err.Error())
+}
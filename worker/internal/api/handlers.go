@@ -2,10 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/blueprinter/worker/internal/archive"
 	"github.com/blueprinter/worker/internal/blueprint"
+	"github.com/blueprinter/worker/internal/differ"
 	"github.com/blueprinter/worker/internal/fetcher"
 	"github.com/blueprinter/worker/internal/scheduler"
 )
@@ -14,15 +18,29 @@ import (
 type Handlers struct {
 	fetcher   *fetcher.Client
 	openai    *blueprint.OpenAIClient
+	schemas   *blueprint.SchemaRegistry
+	revisions *blueprint.RevisionStore
+	archive   *archive.Archive
 	scheduler *scheduler.Scheduler
 	logger    *slog.Logger
 }
 
 // NewHandlers creates a new Handlers instance.
-func NewHandlers(fetcher *fetcher.Client, openai *blueprint.OpenAIClient, sched *scheduler.Scheduler, logger *slog.Logger) *Handlers {
+func NewHandlers(
+	fetcher *fetcher.Client,
+	openai *blueprint.OpenAIClient,
+	schemas *blueprint.SchemaRegistry,
+	revisions *blueprint.RevisionStore,
+	archive *archive.Archive,
+	sched *scheduler.Scheduler,
+	logger *slog.Logger,
+) *Handlers {
 	return &Handlers{
 		fetcher:   fetcher,
 		openai:    openai,
+		schemas:   schemas,
+		revisions: revisions,
+		archive:   archive,
 		scheduler: sched,
 		logger:    logger,
 	}
@@ -65,6 +83,96 @@ func (h *Handlers) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// HandleSchedulerStats reports the scheduler's pool size, running watches and
+// queue depth.
+func (h *Handlers) HandleSchedulerStats(w http.ResponseWriter, r *http.Request) {
+	if h.scheduler == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.scheduler.Stats())
+}
+
+const (
+	defaultScheduleRuns = 5
+	maxScheduleRuns     = 50
+)
+
+type validateScheduleRequest struct {
+	Schedule      string `json:"schedule"`
+	Timezone      string `json:"timezone"`
+	JitterSeconds int32  `json:"jitter_seconds"`
+	Count         int    `json:"count"`
+}
+
+type validateScheduleResponse struct {
+	Schedule      string      `json:"schedule"`
+	Timezone      string      `json:"timezone"`
+	JitterSeconds int32       `json:"jitter_seconds"`
+	NextRuns      []time.Time `json:"next_runs"`
+}
+
+// HandleValidateSchedule checks a watch schedule and returns its next run
+// times (before jitter), or a 400 explaining why it is invalid.
+func (h *Handlers) HandleValidateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req validateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.Schedule == "" {
+		writeError(w, http.StatusBadRequest, "schedule is required")
+		return
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if req.Count <= 0 {
+		req.Count = defaultScheduleRuns
+	}
+	req.Count = min(req.Count, maxScheduleRuns)
+
+	sched, err := scheduler.ParseSchedule(req.Schedule, req.Timezone, req.JitterSeconds)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, validateScheduleResponse{
+		Schedule:      req.Schedule,
+		Timezone:      req.Timezone,
+		JitterSeconds: req.JitterSeconds,
+		NextRuns:      sched.Upcoming(time.Now(), req.Count),
+	})
+}
+
+type validateDiffPolicyRequest struct {
+	DiffPolicy json.RawMessage `json:"diff_policy"`
+}
+
+type validateDiffPolicyResponse struct {
+	DiffPolicy differ.Policy `json:"diff_policy"`
+}
+
+// HandleValidateDiffPolicy checks a watch diff policy with the same parser
+// runs use, so a bad policy is rejected on save rather than failing every run.
+func (h *Handlers) HandleValidateDiffPolicy(w http.ResponseWriter, r *http.Request) {
+	var req validateDiffPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	policy, err := differ.ParsePolicy(req.DiffPolicy)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid diff policy: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, validateDiffPolicyResponse{DiffPolicy: policy})
+}
+
 // HandleFetchHTML fetches HTML via Firecrawl and returns both raw and cleaned versions.
 func (h *Handlers) HandleFetchHTML(w http.ResponseWriter, r *http.Request) {
 	var req fetchHTMLRequest
@@ -109,9 +217,9 @@ func (h *Handlers) HandleGenerateBlueprint(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	schema, ok := blueprint.GetSchema(req.SchemaType)
-	if !ok {
-		writeError(w, http.StatusBadRequest, "unknown schema_type: "+req.SchemaType)
+	schema, err := h.schemas.GetSchema(r.Context(), req.OrgID, req.SchemaType)
+	if err != nil {
+		h.writeSchemaError(w, err)
 		return
 	}
 
@@ -162,28 +270,126 @@ func (h *Handlers) HandleTestBlueprint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var errors []string
+	var errs []string
 	entities, err := blueprint.Extract(cleanedHTML, req.ExtractionRules)
 	if err != nil {
-		errors = append(errors, err.Error())
+		errs = append(errs, err.Error())
+	}
+
+	// Validate against the schema when one is given; the extraction itself
+	// is still returned so the user can see what went wrong.
+	if req.SchemaType != "" {
+		schema, err := h.schemas.GetSchema(r.Context(), req.OrgID, req.SchemaType)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			errs = append(errs, blueprint.ValidateEntities(entities, schema)...)
+		}
 	}
 
 	writeJSON(w, http.StatusOK, testBlueprintResponse{
 		Entities: entities,
-		Errors:   errors,
+		Errors:   errs,
 	})
 }
 
+type listSchemasResponse struct {
+	Schemas []blueprint.EntitySchema `json:"schemas"`
+}
+
+// HandleListSchemas returns the built-in schemas plus the org's own schemas.
+func (h *Handlers) HandleListSchemas(w http.ResponseWriter, r *http.Request) {
+	orgID := r.URL.Query().Get("org_id")
+	if orgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	schemas, err := h.schemas.ListSchemas(r.Context(), orgID)
+	if err != nil {
+		h.logger.Error("list schemas failed", "org_id", orgID, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list schemas: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listSchemasResponse{Schemas: schemas})
+}
+
+type registerSchemaRequest struct {
+	OrgID  string                 `json:"org_id"`
+	Schema blueprint.EntitySchema `json:"schema"`
+}
+
+// HandleRegisterSchema stores a new version of an org-defined schema.
+func (h *Handlers) HandleRegisterSchema(w http.ResponseWriter, r *http.Request) {
+	var req registerSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.OrgID == "" || req.Schema.Type == "" {
+		writeError(w, http.StatusBadRequest, "org_id and schema.type are required")
+		return
+	}
+
+	schema, err := h.schemas.RegisterSchema(r.Context(), req.OrgID, req.Schema)
+	if err != nil {
+		if errors.Is(err, blueprint.ErrBuiltInSchema) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.logger.Warn("register schema failed", "org_id", req.OrgID, "schema_type", req.Schema.Type, "error", err)
+		writeError(w, http.StatusBadRequest, "failed to register schema: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schema)
+}
+
+// HandleDeleteSchema removes an org-defined schema (all versions).
+func (h *Handlers) HandleDeleteSchema(w http.ResponseWriter, r *http.Request) {
+	orgID := r.URL.Query().Get("org_id")
+	schemaType := r.PathValue("type")
+	if orgID == "" || schemaType == "" {
+		writeError(w, http.StatusBadRequest, "org_id and schema type are required")
+		return
+	}
+
+	if err := h.schemas.DeleteSchema(r.Context(), orgID, schemaType); err != nil {
+		if errors.Is(err, blueprint.ErrBuiltInSchema) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.logger.Error("delete schema failed", "org_id", orgID, "schema_type", schemaType, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete schema: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h *Handlers) writeSchemaError(w http.ResponseWriter, err error) {
+	if errors.Is(err, blueprint.ErrUnknownSchema) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.logger.Error("resolving schema failed", "error", err)
+	writeError(w, http.StatusInternalServerError, "failed to resolve schema: "+err.Error())
+}
+
 type runWatchRequest struct {
 	OrgID   string `json:"org_id"`
 	WatchID string `json:"watch_id"`
 }
 
 type runWatchResponse struct {
-	RunID string `json:"run_id"`
+	RunID  string `json:"run_id"`
+	Status string `json:"status"`
 }
 
-// HandleRunWatch triggers a manual watch run.
+// HandleRunWatch starts a manual watch run and returns its ID at once. Its
+// progress is polled through HandleGetRun.
 func (h *Handlers) HandleRunWatch(w http.ResponseWriter, r *http.Request) {
 	var req runWatchRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -201,19 +407,190 @@ func (h *Handlers) HandleRunWatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	runID, err := h.scheduler.RunSingle(r.Context(), req.WatchID)
+	runID, err := h.scheduler.RunSingle(r.Context(), req.OrgID, req.WatchID)
 	if err != nil {
-		h.logger.Error("run watch failed", "watch_id", req.WatchID, "error", err)
-		// Still return runID if we got one (run was created but execution failed)
-		if runID != "" {
-			writeJSON(w, http.StatusOK, runWatchResponse{RunID: runID})
+		switch {
+		case errors.Is(err, scheduler.ErrWatchNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, scheduler.ErrWatchRunning):
+			writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, scheduler.ErrShutdown):
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			h.logger.Error("run watch failed", "watch_id", req.WatchID, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to run watch: "+err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, runWatchResponse{RunID: runID, Status: "running"})
+}
+
+// HandleGetRun returns the phase, progress and stats of a watch run.
+func (h *Handlers) HandleGetRun(w http.ResponseWriter, r *http.Request) {
+	orgID := r.URL.Query().Get("org_id")
+	if orgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	if h.scheduler == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+
+	runID := r.PathValue("id")
+	run, err := h.scheduler.GetRun(r.Context(), orgID, runID)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrRunNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to run watch: "+err.Error())
+		h.logger.Error("get run failed", "run_id", runID, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get run: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, run)
+}
+
+type cancelRunRequest struct {
+	OrgID string `json:"org_id"`
+}
+
+type cancelRunResponse struct {
+	RunID  string `json:"run_id"`
+	Status string `json:"status"`
+}
+
+// HandleCancelRun cancels an in-flight watch run. The run completes as
+// cancelled once its worker notices.
+func (h *Handlers) HandleCancelRun(w http.ResponseWriter, r *http.Request) {
+	var req cancelRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.OrgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	if h.scheduler == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+
+	runID := r.PathValue("id")
+	if err := h.scheduler.CancelRun(r.Context(), req.OrgID, runID); err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrRunNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, scheduler.ErrRunNotRunning):
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.logger.Error("cancel run failed", "run_id", runID, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to cancel run: "+err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cancelRunResponse{RunID: runID, Status: "cancelling"})
+}
+
+type migrationReportRequest struct {
+	OrgID   string `json:"org_id"`
+	WatchID string `json:"watch_id"`
+}
+
+// HandleMigrationReport returns a dry-run report of how a watch's stored
+// entities would be reconciled with the current schema and blueprint.
+func (h *Handlers) HandleMigrationReport(w http.ResponseWriter, r *http.Request) {
+	var req migrationReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.OrgID == "" || req.WatchID == "" {
+		writeError(w, http.StatusBadRequest, "org_id and watch_id are required")
+		return
+	}
+
+	if h.scheduler == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+
+	report, err := h.scheduler.PlanMigration(r.Context(), req.OrgID, req.WatchID)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrWatchNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.logger.Error("migration report failed", "watch_id", req.WatchID, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to build migration report: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+type replayRequest struct {
+	OrgID           string                     `json:"org_id"`
+	WatchID         string                     `json:"watch_id"`
+	ExtractionRules *blueprint.ExtractionRules `json:"extraction_rules,omitempty"`
+	IdentityFields  []string                   `json:"identity_fields,omitempty"`
+	Since           time.Time                  `json:"since,omitempty"`
+	Until           time.Time                  `json:"until,omitempty"`
+	Limit           int                        `json:"limit,omitempty"`
+	EmitBaseline    bool                       `json:"emit_baseline,omitempty"`
+}
+
+// HandleReplay replays a watch over its archived snapshots and returns the
+// events that would have been emitted. Nothing is persisted.
+func (h *Handlers) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	var req replayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.OrgID == "" || req.WatchID == "" {
+		writeError(w, http.StatusBadRequest, "org_id and watch_id are required")
+		return
+	}
+
+	if h.scheduler == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+
+	result, err := h.scheduler.Replay(r.Context(), scheduler.ReplayParams{
+		OrgID:           req.OrgID,
+		WatchID:         req.WatchID,
+		ExtractionRules: req.ExtractionRules,
+		IdentityFields:  req.IdentityFields,
+		Since:           req.Since,
+		Until:           req.Until,
+		Limit:           req.Limit,
+		EmitBaseline:    req.EmitBaseline,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrWatchNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, scheduler.ErrArchiveDisabled):
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			h.logger.Error("replay failed", "watch_id", req.WatchID, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to replay watch: "+err.Error())
+		}
 		return
 	}
 
-	writeJSON(w, http.StatusOK, runWatchResponse{RunID: runID})
+	writeJSON(w, http.StatusOK, result)
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
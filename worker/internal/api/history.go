@@ -0,0 +1,77 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/blueprinter/worker/internal/scheduler"
+)
+
+// HandleEntityHistory returns the recorded versions of an entity, or with
+// ?field=name, the timeline of that field's value.
+func (h *Handlers) HandleEntityHistory(w http.ResponseWriter, r *http.Request) {
+	orgID := r.URL.Query().Get("org_id")
+	if orgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	if h.scheduler == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+
+	entityID := r.PathValue("id")
+	history, err := h.scheduler.EntityHistory(r.Context(), orgID, entityID, r.URL.Query().Get("field"))
+	if err != nil {
+		if errors.Is(err, scheduler.ErrEntityNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.logger.Error("get entity history failed", "entity_id", entityID, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get entity history: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// HandleWatchEntitiesAsOf returns the entities of a watch as they were at
+// ?as_of (RFC 3339), or now without it.
+func (h *Handlers) HandleWatchEntitiesAsOf(w http.ResponseWriter, r *http.Request) {
+	orgID := r.URL.Query().Get("org_id")
+	if orgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	asOf := time.Now()
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "as_of must be an RFC 3339 timestamp")
+			return
+		}
+		asOf = t
+	}
+
+	if h.scheduler == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+
+	watchID := r.PathValue("id")
+	set, err := h.scheduler.WatchEntitiesAsOf(r.Context(), orgID, watchID, asOf)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrWatchNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.logger.Error("get watch entities failed", "watch_id", watchID, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get watch entities: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, set)
+}
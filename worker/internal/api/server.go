@@ -10,10 +10,29 @@ func NewServer(port, apiKey string, h *Handlers, logger *slog.Logger) *http.Serv
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /api/health", h.HandleHealth)
+	mux.HandleFunc("GET /api/scheduler/stats", h.HandleSchedulerStats)
 	mux.HandleFunc("POST /api/fetch-html", h.HandleFetchHTML)
 	mux.HandleFunc("POST /api/generate-blueprint", h.HandleGenerateBlueprint)
 	mux.HandleFunc("POST /api/test-blueprint", h.HandleTestBlueprint)
+	mux.HandleFunc("POST /api/validate-schedule", h.HandleValidateSchedule)
+	mux.HandleFunc("POST /api/validate-diff-policy", h.HandleValidateDiffPolicy)
 	mux.HandleFunc("POST /api/run-watch", h.HandleRunWatch)
+	mux.HandleFunc("GET /api/runs/{id}", h.HandleGetRun)
+	mux.HandleFunc("POST /api/runs/{id}/cancel", h.HandleCancelRun)
+	mux.HandleFunc("POST /api/migration-report", h.HandleMigrationReport)
+	mux.HandleFunc("GET /api/schemas", h.HandleListSchemas)
+	mux.HandleFunc("POST /api/schemas", h.HandleRegisterSchema)
+	mux.HandleFunc("DELETE /api/schemas/{type}", h.HandleDeleteSchema)
+	mux.HandleFunc("GET /api/blueprints/{id}/revisions", h.HandleListBlueprintRevisions)
+	mux.HandleFunc("POST /api/blueprints/{id}/compare", h.HandleCompareBlueprintRevisions)
+	mux.HandleFunc("POST /api/blueprints/{id}/rollback", h.HandleRollbackBlueprint)
+	mux.HandleFunc("POST /api/blueprints/{id}/rules", h.HandleUpdateBlueprintRules)
+	mux.HandleFunc("GET /api/runs/{id}/snapshots", h.HandleListRunSnapshots)
+	mux.HandleFunc("GET /api/snapshots/{id}/html", h.HandleGetSnapshotHTML)
+	mux.HandleFunc("POST /api/snapshots/{id}/extract", h.HandleExtractSnapshot)
+	mux.HandleFunc("POST /api/replay", h.HandleReplay)
+	mux.HandleFunc("GET /api/entities/{id}/history", h.HandleEntityHistory)
+	mux.HandleFunc("GET /api/watches/{id}/entities", h.HandleWatchEntitiesAsOf)
 
 	var handler http.Handler = mux
 	handler = authMiddleware(apiKey, handler)
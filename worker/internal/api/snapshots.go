@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/blueprinter/worker/internal/archive"
+	"github.com/blueprinter/worker/internal/blueprint"
+)
+
+type listSnapshotsResponse struct {
+	Snapshots []archive.Snapshot `json:"snapshots"`
+}
+
+// HandleListRunSnapshots returns the pages archived during a watch run.
+func (h *Handlers) HandleListRunSnapshots(w http.ResponseWriter, r *http.Request) {
+	if !h.requireArchive(w) {
+		return
+	}
+
+	orgID := r.URL.Query().Get("org_id")
+	if orgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	snapshots, err := h.archive.ListForRun(r.Context(), orgID, r.PathValue("id"))
+	if err != nil {
+		h.writeSnapshotError(w, "list snapshots", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listSnapshotsResponse{Snapshots: snapshots})
+}
+
+// HandleGetSnapshotHTML returns the archived HTML of a snapshot. The cleaned
+// HTML is returned unless ?variant=raw is given.
+func (h *Handlers) HandleGetSnapshotHTML(w http.ResponseWriter, r *http.Request) {
+	if !h.requireArchive(w) {
+		return
+	}
+
+	orgID := r.URL.Query().Get("org_id")
+	if orgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	var (
+		html string
+		err  error
+	)
+	switch r.URL.Query().Get("variant") {
+	case "", "cleaned":
+		html, err = h.archive.CleanedHTML(r.Context(), orgID, r.PathValue("id"))
+	case "raw":
+		html, err = h.archive.RawHTML(r.Context(), orgID, r.PathValue("id"))
+	default:
+		writeError(w, http.StatusBadRequest, "variant must be \"raw\" or \"cleaned\"")
+		return
+	}
+	if err != nil {
+		h.writeSnapshotError(w, "load snapshot", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(html))
+}
+
+type extractSnapshotRequest struct {
+	OrgID           string                     `json:"org_id"`
+	ExtractionRules *blueprint.ExtractionRules `json:"extraction_rules,omitempty"` // defaults to the rules the run used
+	SchemaType      string                     `json:"schema_type,omitempty"`
+}
+
+type extractSnapshotResponse struct {
+	SnapshotID string           `json:"snapshot_id"`
+	Entities   []map[string]any `json:"entities"`
+	Errors     []string         `json:"errors"`
+}
+
+// HandleExtractSnapshot re-runs extraction against an archived page, either
+// with the rules the run originally used or with rules under test.
+func (h *Handlers) HandleExtractSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !h.requireArchive(w) {
+		return
+	}
+
+	var req extractSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.OrgID == "" {
+		writeError(w, http.StatusBadRequest, "org_id is required")
+		return
+	}
+
+	snapshotID := r.PathValue("id")
+	snapshot, err := h.archive.Get(r.Context(), req.OrgID, snapshotID)
+	if err != nil {
+		h.writeSnapshotError(w, "load snapshot", err)
+		return
+	}
+
+	rules := req.ExtractionRules
+	schemaType := req.SchemaType
+	if rules == nil {
+		rev, err := h.revisions.GetForRun(r.Context(), req.OrgID, snapshot.WatchRunID)
+		if err != nil {
+			h.writeRevisionError(w, "load run revision", err)
+			return
+		}
+		rules = rev.ExtractionRules
+		if schemaType == "" {
+			schemaType = rev.SchemaType
+		}
+	}
+
+	cleanedHTML, err := h.archive.CleanedHTML(r.Context(), req.OrgID, snapshotID)
+	if err != nil {
+		h.writeSnapshotError(w, "load snapshot", err)
+		return
+	}
+
+	var errs []string
+	entities, err := blueprint.Extract(cleanedHTML, rules)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if schemaType != "" {
+		schema, err := h.schemas.GetSchema(r.Context(), req.OrgID, schemaType)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			errs = append(errs, blueprint.ValidateEntities(entities, schema)...)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, extractSnapshotResponse{
+		SnapshotID: snapshot.ID,
+		Entities:   entities,
+		Errors:     errs,
+	})
+}
+
+// requireArchive writes a 503 and returns false when archiving is disabled.
+func (h *Handlers) requireArchive(w http.ResponseWriter) bool {
+	if h.archive == nil {
+		writeError(w, http.StatusServiceUnavailable, "snapshot archiving is not configured")
+		return false
+	}
+	return true
+}
+
+func (h *Handlers) writeSnapshotError(w http.ResponseWriter, action string, err error) {
+	if errors.Is(err, archive.ErrSnapshotNotFound) || errors.Is(err, archive.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.logger.Error(action+" failed", "error", err)
+	writeError(w, http.StatusInternalServerError, "failed to "+action+": "+err.Error())
+}
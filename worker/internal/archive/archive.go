@@ -0,0 +1,310 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/blueprinter/worker/internal/db"
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+const (
+	retentionInterval  = time.Hour
+	retentionBatchSize = 500
+)
+
+// ErrSnapshotNotFound is returned when a snapshot does not exist for the org.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot describes the archived HTML of one fetched page in a watch run.
+type Snapshot struct {
+	ID          string    `json:"id"`
+	WatchID     string    `json:"watch_id"`
+	WatchRunID  string    `json:"watch_run_id"`
+	URL         string    `json:"url"`
+	RawSize     int32     `json:"raw_size"`
+	CleanedSize int32     `json:"cleaned_size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SaveParams identifies and carries the page content to archive.
+type SaveParams struct {
+	OrgID       string
+	WatchID     pgtype.UUID
+	WatchRunID  pgtype.UUID
+	URL         string
+	RawHTML     string
+	CleanedHTML string
+}
+
+// Archive stores gzip-compressed page snapshots in a Store and indexes them
+// in watch_run_snapshots.
+type Archive struct {
+	store     Store
+	queries   *dbgen.Queries
+	retention time.Duration
+	logger    *slog.Logger
+}
+
+// New creates an Archive. A retention of zero keeps snapshots forever.
+func New(store Store, queries *dbgen.Queries, retention time.Duration, logger *slog.Logger) *Archive {
+	return &Archive{
+		store:     store,
+		queries:   queries,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Save compresses and stores the raw and cleaned HTML of a fetched page.
+func (a *Archive) Save(ctx context.Context, p SaveParams) (*Snapshot, error) {
+	prefix := objectPrefix(p.OrgID, p.WatchID, p.WatchRunID, p.URL)
+	rawKey := prefix + ".raw.html.gz"
+	cleanedKey := prefix + ".cleaned.html.gz"
+
+	if err := a.put(ctx, rawKey, p.RawHTML); err != nil {
+		return nil, fmt.Errorf("storing raw HTML: %w", err)
+	}
+	if err := a.put(ctx, cleanedKey, p.CleanedHTML); err != nil {
+		return nil, fmt.Errorf("storing cleaned HTML: %w", err)
+	}
+
+	row, err := a.queries.InsertWatchRunSnapshot(ctx, dbgen.InsertWatchRunSnapshotParams{
+		OrgID:          p.OrgID,
+		WatchID:        p.WatchID,
+		WatchRunID:     p.WatchRunID,
+		Url:            p.URL,
+		RawHtmlKey:     rawKey,
+		CleanedHtmlKey: cleanedKey,
+		RawSize:        int32(len(p.RawHTML)),
+		CleanedSize:    int32(len(p.CleanedHTML)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("recording snapshot: %w", err)
+	}
+	return snapshotFromRow(&row), nil
+}
+
+// Get returns a snapshot's metadata.
+func (a *Archive) Get(ctx context.Context, orgID, snapshotID string) (*Snapshot, error) {
+	row, err := a.getRow(ctx, orgID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotFromRow(&row), nil
+}
+
+// ListForRun returns the snapshots taken during a watch run.
+func (a *Archive) ListForRun(ctx context.Context, orgID, runID string) ([]Snapshot, error) {
+	var id pgtype.UUID
+	if err := id.Scan(runID); err != nil {
+		return nil, fmt.Errorf("invalid run ID: %w", err)
+	}
+
+	rows, err := a.queries.ListWatchRunSnapshots(ctx, dbgen.ListWatchRunSnapshotsParams{
+		WatchRunID: id,
+		OrgID:      orgID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+
+	snapshots := make([]Snapshot, len(rows))
+	for i := range rows {
+		snapshots[i] = *snapshotFromRow(&rows[i])
+	}
+	return snapshots, nil
+}
+
+// ListForWatch returns all snapshots of up to limit of the most recent runs
+// of a watch that took snapshots in [since, until), oldest first.
+func (a *Archive) ListForWatch(ctx context.Context, orgID string, watchID pgtype.UUID, since, until time.Time, limit int) ([]Snapshot, error) {
+	rows, err := a.queries.ListWatchSnapshots(ctx, dbgen.ListWatchSnapshotsParams{
+		WatchID: watchID,
+		OrgID:   orgID,
+		Since:   pgtype.Timestamptz{Time: since, Valid: true},
+		Until:   pgtype.Timestamptz{Time: until, Valid: true},
+		MaxRuns: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+
+	snapshots := make([]Snapshot, len(rows))
+	for i := range rows {
+		snapshots[len(rows)-1-i] = *snapshotFromRow(&rows[i])
+	}
+	return snapshots, nil
+}
+
+// RawHTML returns the decompressed raw HTML of a snapshot.
+func (a *Archive) RawHTML(ctx context.Context, orgID, snapshotID string) (string, error) {
+	row, err := a.getRow(ctx, orgID, snapshotID)
+	if err != nil {
+		return "", err
+	}
+	return a.get(ctx, row.RawHtmlKey)
+}
+
+// CleanedHTML returns the decompressed cleaned HTML of a snapshot.
+func (a *Archive) CleanedHTML(ctx context.Context, orgID, snapshotID string) (string, error) {
+	row, err := a.getRow(ctx, orgID, snapshotID)
+	if err != nil {
+		return "", err
+	}
+	return a.get(ctx, row.CleanedHtmlKey)
+}
+
+// RunRetention periodically deletes snapshots older than the retention
+// period. It blocks until the context is cancelled.
+func (a *Archive) RunRetention(ctx context.Context) {
+	if a.retention <= 0 {
+		return
+	}
+
+	a.logger.Info("snapshot retention started", "retention", a.retention)
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	a.sweep(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.logger.Info("snapshot retention stopping")
+			return
+		case <-ticker.C:
+			a.sweep(ctx)
+		}
+	}
+}
+
+func (a *Archive) sweep(ctx context.Context) {
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-a.retention), Valid: true}
+
+	for {
+		rows, err := a.queries.ListExpiredWatchRunSnapshots(ctx, dbgen.ListExpiredWatchRunSnapshotsParams{
+			CreatedAt: cutoff,
+			Limit:     retentionBatchSize,
+		})
+		if err != nil {
+			a.logger.Error("failed to list expired snapshots", "error", err)
+			return
+		}
+
+		deleted := 0
+		for i := range rows {
+			if err := a.delete(ctx, &rows[i]); err != nil {
+				a.logger.Warn("failed to delete expired snapshot", "snapshot_id", db.UUIDToString(rows[i].ID), "error", err)
+				continue
+			}
+			deleted++
+		}
+
+		if deleted > 0 {
+			a.logger.Info("expired snapshots deleted", "count", deleted)
+		}
+		// Stop when the batch wasn't full, or when nothing in it could be
+		// deleted (to avoid spinning on a broken store).
+		if len(rows) < retentionBatchSize || deleted == 0 {
+			return
+		}
+	}
+}
+
+func (a *Archive) delete(ctx context.Context, row *dbgen.WatchRunSnapshot) error {
+	if err := a.store.Delete(ctx, row.RawHtmlKey); err != nil {
+		return err
+	}
+	if err := a.store.Delete(ctx, row.CleanedHtmlKey); err != nil {
+		return err
+	}
+	return a.queries.DeleteWatchRunSnapshot(ctx, row.ID)
+}
+
+func (a *Archive) getRow(ctx context.Context, orgID, snapshotID string) (dbgen.WatchRunSnapshot, error) {
+	var id pgtype.UUID
+	if err := id.Scan(snapshotID); err != nil {
+		return dbgen.WatchRunSnapshot{}, fmt.Errorf("invalid snapshot ID: %w", err)
+	}
+
+	row, err := a.queries.GetWatchRunSnapshot(ctx, dbgen.GetWatchRunSnapshotParams{ID: id, OrgID: orgID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return row, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return row, fmt.Errorf("getting snapshot: %w", err)
+	}
+	return row, nil
+}
+
+func (a *Archive) put(ctx context.Context, key, content string) error {
+	compressed, err := compress(content)
+	if err != nil {
+		return err
+	}
+	return a.store.Put(ctx, key, compressed)
+}
+
+func (a *Archive) get(ctx context.Context, key string) (string, error) {
+	data, err := a.store.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", key, err)
+	}
+	return decompress(data)
+}
+
+// objectPrefix builds the storage key prefix for a page in a run. The URL is
+// hashed so several pages of one run get distinct, path-safe keys.
+func objectPrefix(orgID string, watchID, runID pgtype.UUID, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return fmt.Sprintf("%s/%s/%s/%s", orgID, db.UUIDToString(watchID), db.UUIDToString(runID), hex.EncodeToString(sum[:8]))
+}
+
+func compress(content string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		return nil, fmt.Errorf("compressing: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compressing: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) (string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("decompressing: %w", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("decompressing: %w", err)
+	}
+	return string(out), nil
+}
+
+func snapshotFromRow(row *dbgen.WatchRunSnapshot) *Snapshot {
+	return &Snapshot{
+		ID:          db.UUIDToString(row.ID),
+		WatchID:     db.UUIDToString(row.WatchID),
+		WatchRunID:  db.UUIDToString(row.WatchRunID),
+		URL:         row.Url,
+		RawSize:     row.RawSize,
+		CleanedSize: row.CleanedSize,
+		CreatedAt:   row.CreatedAt.Time,
+	}
+}
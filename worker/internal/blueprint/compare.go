@@ -0,0 +1,82 @@
+package blueprint
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/blueprinter/worker/internal/differ"
+	"github.com/blueprinter/worker/internal/identity"
+)
+
+// RuleComparison describes how two sets of extraction rules differ, both in
+// their definition and in what they extract from the same HTML.
+type RuleComparison struct {
+	ContainerChanged bool     `json:"container_changed"`
+	FieldsAdded      []string `json:"fields_added"`
+	FieldsRemoved    []string `json:"fields_removed"`
+	FieldsModified   []string `json:"fields_modified"`
+
+	FromCount   int                 `json:"from_count"`
+	ToCount     int                 `json:"to_count"`
+	Appeared    []differ.EntityDiff `json:"appeared"`    // only extracted by the "to" rules
+	Disappeared []differ.EntityDiff `json:"disappeared"` // only extracted by the "from" rules
+	Changed     []differ.EntityDiff `json:"changed"`
+	Unchanged   int                 `json:"unchanged"`
+}
+
+// CompareRules extracts entities from cleanedHTML with both rule sets and
+// diffs the results, keyed by the given identity fields.
+func CompareRules(cleanedHTML string, from, to *ExtractionRules, identityFields []string) (*RuleComparison, error) {
+	fromEntities, err := Extract(cleanedHTML, from)
+	if err != nil {
+		return nil, fmt.Errorf("extracting with old rules: %w", err)
+	}
+	toEntities, err := Extract(cleanedHTML, to)
+	if err != nil {
+		return nil, fmt.Errorf("extracting with new rules: %w", err)
+	}
+
+	cmp := &RuleComparison{
+		ContainerChanged: from.Container != to.Container,
+		FieldsAdded:      []string{},
+		FieldsRemoved:    []string{},
+		FieldsModified:   []string{},
+		FromCount:        len(fromEntities),
+		ToCount:          len(toEntities),
+	}
+
+	for name, mapping := range to.Fields {
+		old, ok := from.Fields[name]
+		switch {
+		case !ok:
+			cmp.FieldsAdded = append(cmp.FieldsAdded, name)
+		case !reflect.DeepEqual(old, mapping):
+			cmp.FieldsModified = append(cmp.FieldsModified, name)
+		}
+	}
+	for name := range from.Fields {
+		if _, ok := to.Fields[name]; !ok {
+			cmp.FieldsRemoved = append(cmp.FieldsRemoved, name)
+		}
+	}
+	sort.Strings(cmp.FieldsAdded)
+	sort.Strings(cmp.FieldsRemoved)
+	sort.Strings(cmp.FieldsModified)
+
+	result := differ.Diff(keyEntities(toEntities, identityFields), keyEntities(fromEntities, identityFields))
+	cmp.Appeared = result.Appeared
+	cmp.Disappeared = result.Disappeared
+	cmp.Changed = result.Changed
+	cmp.Unchanged = result.Unchanged
+
+	return cmp, nil
+}
+
+func keyEntities(entities []map[string]any, identityFields []string) map[string]map[string]any {
+	keyed := make(map[string]map[string]any, len(entities))
+	for _, entity := range entities {
+		keyed[identity.ExternalID(entity, identityFields)] = entity
+	}
+	return keyed
+}
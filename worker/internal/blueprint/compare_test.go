@@ -0,0 +1,39 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareRules(t *testing.T) {
+	from := &ExtractionRules{
+		Container: "//div[@class='product']",
+		Fields: map[string]*FieldMapping{
+			"name":  {XPath: ".//h3[@class='title']", Type: "string", Attribute: "text"},
+			"price": {XPath: ".//span[@class='price']", Type: "integer", Attribute: "text"},
+		},
+	}
+	to := &ExtractionRules{
+		Container: "//div[@class='product']",
+		Fields: map[string]*FieldMapping{
+			"name":  {XPath: ".//h3[@class='title']", Type: "string", Attribute: "text"},
+			"price": {XPath: ".//span[@class='price']", Type: "number", Attribute: "text"},
+			"url":   {XPath: ".//a[@class='link']", Type: "string", Attribute: "href"},
+		},
+	}
+
+	cmp, err := CompareRules(testHTML, from, to, []string{"name"})
+	require.NoError(t, err)
+
+	assert.False(t, cmp.ContainerChanged)
+	assert.Equal(t, []string{"url"}, cmp.FieldsAdded)
+	assert.Empty(t, cmp.FieldsRemoved)
+	assert.Equal(t, []string{"price"}, cmp.FieldsModified)
+	assert.Equal(t, 2, cmp.FromCount)
+	assert.Equal(t, 2, cmp.ToCount)
+	assert.Empty(t, cmp.Appeared)
+	assert.Empty(t, cmp.Disappeared)
+	assert.Len(t, cmp.Changed, 2)
+}
@@ -0,0 +1,27 @@
+package blueprint
+
+// Listing position system fields. They are derived by the worker rather than
+// extracted, and start with an underscore, which schema field names cannot,
+// so they never clash with a schema's own fields.
+const (
+	// PositionField is an entity's 1-based rank in its listing, counted
+	// across all pages of that listing.
+	PositionField = "_position"
+
+	// PagePositionField is an entity's 1-based rank on the page it was
+	// found on.
+	PagePositionField = "_page_position"
+)
+
+// PositionFields are the system fields set by RecordPositions.
+var PositionFields = []string{PositionField, PagePositionField}
+
+// RecordPositions sets the position fields on the entities of one page, which
+// Extract returns in container order. offset is the number of entities on the
+// earlier pages of the same listing.
+func RecordPositions(entities []map[string]any, offset int) {
+	for i, entity := range entities {
+		entity[PagePositionField] = i + 1
+		entity[PositionField] = offset + i + 1
+	}
+}
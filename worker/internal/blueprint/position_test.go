@@ -0,0 +1,17 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordPositions(t *testing.T) {
+	entities := []map[string]any{{"name": "a"}, {"name": "b"}}
+	RecordPositions(entities, 20)
+
+	assert.Equal(t, []map[string]any{
+		{"name": "a", PagePositionField: 1, PositionField: 21},
+		{"name": "b", PagePositionField: 2, PositionField: 22},
+	}, entities)
+}
@@ -0,0 +1,154 @@
+package blueprint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+var (
+	// ErrUnknownSchema is returned when a schema type is neither built in nor defined by the org.
+	ErrUnknownSchema = errors.New("unknown schema type")
+	// ErrBuiltInSchema is returned when an org tries to redefine or delete a built-in schema.
+	ErrBuiltInSchema = errors.New("built-in schemas are read-only")
+)
+
+// SchemaRegistry resolves entity schemas for an org. Built-in schemas take
+// precedence and cannot be overridden; anything else is looked up in the
+// entity_schemas table, latest version first.
+type SchemaRegistry struct {
+	queries *dbgen.Queries
+}
+
+// NewSchemaRegistry creates a new SchemaRegistry.
+func NewSchemaRegistry(queries *dbgen.Queries) *SchemaRegistry {
+	return &SchemaRegistry{queries: queries}
+}
+
+// GetSchema returns the current schema for the given org and type.
+func (r *SchemaRegistry) GetSchema(ctx context.Context, orgID, schemaType string) (*EntitySchema, error) {
+	if s, ok := GetSchema(schemaType); ok {
+		return s, nil
+	}
+
+	row, err := r.queries.GetLatestEntitySchema(ctx, dbgen.GetLatestEntitySchemaParams{
+		OrgID:      orgID,
+		SchemaType: schemaType,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSchema, schemaType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading schema %q: %w", schemaType, err)
+	}
+	return schemaFromRow(row)
+}
+
+// ListSchemas returns the built-in schemas followed by the latest version of
+// each org-defined schema.
+func (r *SchemaRegistry) ListSchemas(ctx context.Context, orgID string) ([]EntitySchema, error) {
+	out := BuiltInSchemas()
+
+	rows, err := r.queries.ListEntitySchemas(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("listing schemas: %w", err)
+	}
+	custom := make([]EntitySchema, 0, len(rows))
+	for i := range rows {
+		s, err := schemaFromRow(rows[i])
+		if err != nil {
+			return nil, err
+		}
+		custom = append(custom, *s)
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Type < custom[j].Type })
+
+	return append(out, custom...), nil
+}
+
+// RegisterSchema validates and stores a new version of an org-defined schema.
+// The returned schema carries the assigned version number.
+func (r *SchemaRegistry) RegisterSchema(ctx context.Context, orgID string, s EntitySchema) (*EntitySchema, error) {
+	if _, ok := GetSchema(s.Type); ok {
+		return nil, fmt.Errorf("%w: %s", ErrBuiltInSchema, s.Type)
+	}
+	if err := ValidateSchema(&s); err != nil {
+		return nil, err
+	}
+
+	fieldsJSON, err := json.Marshal(s.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling fields: %w", err)
+	}
+
+	identity := s.IdentityFields
+	if identity == nil {
+		identity = []string{}
+	}
+
+	// The next version is computed in the insert; a concurrent registration
+	// can take it first, which the unique (org, type, version) constraint
+	// rejects, so the insert is retried with the version after that.
+	params := dbgen.InsertEntitySchemaParams{
+		OrgID:          orgID,
+		SchemaType:     s.Type,
+		Description:    s.Description,
+		Fields:         fieldsJSON,
+		IdentityFields: identity,
+	}
+	for attempt := 1; ; attempt++ {
+		row, err := r.queries.InsertEntitySchema(ctx, params)
+		if isUniqueViolation(err) && attempt < registerAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("inserting schema %q: %w", s.Type, err)
+		}
+		return schemaFromRow(row)
+	}
+}
+
+// registerAttempts is how many times RegisterSchema tries to claim the next
+// version before giving up.
+const registerAttempts = 5
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// DeleteSchema soft-deletes every version of an org-defined schema.
+func (r *SchemaRegistry) DeleteSchema(ctx context.Context, orgID, schemaType string) error {
+	if _, ok := GetSchema(schemaType); ok {
+		return fmt.Errorf("%w: %s", ErrBuiltInSchema, schemaType)
+	}
+	if err := r.queries.DeleteEntitySchema(ctx, dbgen.DeleteEntitySchemaParams{
+		OrgID:      orgID,
+		SchemaType: schemaType,
+	}); err != nil {
+		return fmt.Errorf("deleting schema %q: %w", schemaType, err)
+	}
+	return nil
+}
+
+func schemaFromRow(row dbgen.EntitySchema) (*EntitySchema, error) {
+	var fields []FieldDef
+	if err := json.Unmarshal(row.Fields, &fields); err != nil {
+		return nil, fmt.Errorf("parsing fields of schema %q: %w", row.SchemaType, err)
+	}
+	return &EntitySchema{
+		Type:           row.SchemaType,
+		Version:        int(row.Version),
+		Description:    row.Description,
+		Fields:         fields,
+		IdentityFields: row.IdentityFields,
+	}, nil
+}
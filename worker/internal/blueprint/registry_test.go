@@ -0,0 +1,20 @@
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	conflict := &pgconn.PgError{Code: "23505", ConstraintName: "entity_schemas_org_id_schema_type_version_key"}
+
+	assert.True(t, isUniqueViolation(conflict))
+	assert.True(t, isUniqueViolation(fmt.Errorf("inserting: %w", conflict)))
+	assert.False(t, isUniqueViolation(&pgconn.PgError{Code: "23503"}))
+	assert.False(t, isUniqueViolation(errors.New("connection reset")))
+	assert.False(t, isUniqueViolation(nil))
+}
@@ -0,0 +1,252 @@
+package blueprint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/blueprinter/worker/internal/db"
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+var (
+	// ErrBlueprintNotFound is returned when a blueprint doesn't exist or belongs to another org.
+	ErrBlueprintNotFound = errors.New("blueprint not found")
+	// ErrRevisionNotFound is returned when a blueprint has no revision with the requested version.
+	ErrRevisionNotFound = errors.New("blueprint revision not found")
+)
+
+// Revision is an immutable snapshot of a blueprint's extraction rules.
+type Revision struct {
+	ID              string           `json:"id"`
+	BlueprintID     string           `json:"blueprint_id"`
+	Version         int32            `json:"version"`
+	SchemaType      string           `json:"schema_type"`
+	ExtractionRules *ExtractionRules `json:"extraction_rules"`
+	CreatedAt       time.Time        `json:"created_at"`
+}
+
+// RevisionStore reads and writes blueprint revision history.
+type RevisionStore struct {
+	pool    *pgxpool.Pool
+	queries *dbgen.Queries
+}
+
+// NewRevisionStore creates a new RevisionStore.
+func NewRevisionStore(pool *pgxpool.Pool, queries *dbgen.Queries) *RevisionStore {
+	return &RevisionStore{pool: pool, queries: queries}
+}
+
+// List returns all revisions of a blueprint, newest first. The blueprint's
+// current version is recorded first if it hasn't been yet.
+func (s *RevisionStore) List(ctx context.Context, orgID, blueprintID string) ([]Revision, error) {
+	bp, err := s.getBlueprint(ctx, s.queries, orgID, blueprintID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.queries.EnsureBlueprintRevision(ctx, bp.ID); err != nil {
+		return nil, fmt.Errorf("recording current revision: %w", err)
+	}
+
+	rows, err := s.queries.ListBlueprintRevisions(ctx, dbgen.ListBlueprintRevisionsParams{
+		BlueprintID: bp.ID,
+		OrgID:       orgID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing revisions: %w", err)
+	}
+
+	revisions := make([]Revision, 0, len(rows))
+	for i := range rows {
+		rev, err := revisionFromRow(rows[i])
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, nil
+}
+
+// GetBlueprint returns the current blueprint row.
+func (s *RevisionStore) GetBlueprint(ctx context.Context, orgID, blueprintID string) (dbgen.Blueprint, error) {
+	return s.getBlueprint(ctx, s.queries, orgID, blueprintID)
+}
+
+// Get returns a single revision of a blueprint.
+func (s *RevisionStore) Get(ctx context.Context, orgID, blueprintID string, version int32) (*Revision, error) {
+	bp, err := s.getBlueprint(ctx, s.queries, orgID, blueprintID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.queries.EnsureBlueprintRevision(ctx, bp.ID); err != nil {
+		return nil, fmt.Errorf("recording current revision: %w", err)
+	}
+	return s.getRevision(ctx, s.queries, orgID, bp.ID, version)
+}
+
+// GetForRun returns the revision a watch run extracted with.
+func (s *RevisionStore) GetForRun(ctx context.Context, orgID, runID string) (*Revision, error) {
+	var id pgtype.UUID
+	if err := id.Scan(runID); err != nil {
+		return nil, fmt.Errorf("invalid run ID: %w", err)
+	}
+
+	row, err := s.queries.GetRunBlueprintRevision(ctx, dbgen.GetRunBlueprintRevisionParams{ID: id, OrgID: orgID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: run has no recorded revision", ErrRevisionNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading run revision: %w", err)
+	}
+	return revisionFromRow(row)
+}
+
+// Rollback restores the extraction rules of an earlier revision. History is
+// never rewritten: the restored rules become a new version on top.
+func (s *RevisionStore) Rollback(ctx context.Context, orgID, blueprintID string, version int32) (*Revision, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := s.queries.WithTx(tx)
+
+	bp, err := s.getBlueprint(ctx, q, orgID, blueprintID)
+	if err != nil {
+		return nil, err
+	}
+	if bp.Version == version {
+		return nil, fmt.Errorf("blueprint is already at version %d", version)
+	}
+
+	// Make sure the version being rolled back from is kept in history.
+	if _, err := q.EnsureBlueprintRevision(ctx, bp.ID); err != nil {
+		return nil, fmt.Errorf("recording current revision: %w", err)
+	}
+
+	target, err := q.GetBlueprintRevision(ctx, dbgen.GetBlueprintRevisionParams{
+		BlueprintID: bp.ID,
+		OrgID:       orgID,
+		Version:     version,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: version %d", ErrRevisionNotFound, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading revision %d: %w", version, err)
+	}
+
+	rev, err := s.setRules(ctx, q, orgID, bp.ID, target.ExtractionRules)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing rollback: %w", err)
+	}
+	return rev, nil
+}
+
+// UpdateRules replaces a blueprint's extraction rules. The previous and the
+// new version are both recorded as revisions in the same transaction, so no
+// edit is lost between runs.
+func (s *RevisionStore) UpdateRules(ctx context.Context, orgID, blueprintID string, rules *ExtractionRules) (*Revision, error) {
+	raw, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling extraction rules: %w", err)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := s.queries.WithTx(tx)
+
+	bp, err := s.getBlueprint(ctx, q, orgID, blueprintID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := q.EnsureBlueprintRevision(ctx, bp.ID); err != nil {
+		return nil, fmt.Errorf("recording current revision: %w", err)
+	}
+
+	rev, err := s.setRules(ctx, q, orgID, bp.ID, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing rules update: %w", err)
+	}
+	return rev, nil
+}
+
+// setRules writes new extraction rules as the blueprint's next version and
+// records that version as a revision.
+func (s *RevisionStore) setRules(ctx context.Context, q *dbgen.Queries, orgID string, blueprintID pgtype.UUID, rules []byte) (*Revision, error) {
+	updated, err := q.UpdateBlueprintRules(ctx, dbgen.UpdateBlueprintRulesParams{
+		ID:              blueprintID,
+		OrgID:           orgID,
+		ExtractionRules: rules,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updating blueprint rules: %w", err)
+	}
+	if _, err := q.EnsureBlueprintRevision(ctx, blueprintID); err != nil {
+		return nil, fmt.Errorf("recording new revision: %w", err)
+	}
+	return s.getRevision(ctx, q, orgID, blueprintID, updated.Version)
+}
+
+func (s *RevisionStore) getBlueprint(ctx context.Context, q *dbgen.Queries, orgID, blueprintID string) (dbgen.Blueprint, error) {
+	var id pgtype.UUID
+	if err := id.Scan(blueprintID); err != nil {
+		return dbgen.Blueprint{}, fmt.Errorf("invalid blueprint ID: %w", err)
+	}
+
+	bp, err := q.GetBlueprintByID(ctx, dbgen.GetBlueprintByIDParams{ID: id, OrgID: orgID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dbgen.Blueprint{}, ErrBlueprintNotFound
+	}
+	if err != nil {
+		return dbgen.Blueprint{}, fmt.Errorf("loading blueprint: %w", err)
+	}
+	return bp, nil
+}
+
+func (s *RevisionStore) getRevision(ctx context.Context, q *dbgen.Queries, orgID string, blueprintID pgtype.UUID, version int32) (*Revision, error) {
+	row, err := q.GetBlueprintRevision(ctx, dbgen.GetBlueprintRevisionParams{
+		BlueprintID: blueprintID,
+		OrgID:       orgID,
+		Version:     version,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: version %d", ErrRevisionNotFound, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading revision %d: %w", version, err)
+	}
+	return revisionFromRow(row)
+}
+
+func revisionFromRow(row dbgen.BlueprintRevision) (*Revision, error) {
+	var rules ExtractionRules
+	if err := json.Unmarshal(row.ExtractionRules, &rules); err != nil {
+		return nil, fmt.Errorf("parsing rules of revision %d: %w", row.Version, err)
+	}
+	return &Revision{
+		ID:              db.UUIDToString(row.ID),
+		BlueprintID:     db.UUIDToString(row.BlueprintID),
+		Version:         row.Version,
+		SchemaType:      row.SchemaType,
+		ExtractionRules: &rules,
+		CreatedAt:       row.CreatedAt.Time,
+	}, nil
+}
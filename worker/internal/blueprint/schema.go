@@ -1,12 +1,16 @@
 package blueprint
 
+import "sort"
+
 var schemas = map[string]EntitySchema{
 	"ecommerce_product": EcommerceProductSchema,
 }
 
 // EcommerceProductSchema is the pre-defined schema for e-commerce product listings.
 var EcommerceProductSchema = EntitySchema{
-	Type: "ecommerce_product",
+	Type:        "ecommerce_product",
+	Version:     1,
+	Description: "E-commerce product listing",
 	Fields: []FieldDef{
 		{Name: "name", Type: "string", Description: "Product name/title"},
 		{Name: "price", Type: "integer", Description: "Price in cents"},
@@ -17,9 +21,12 @@ var EcommerceProductSchema = EntitySchema{
 		{Name: "review_count", Type: "integer", Description: "Number of reviews"},
 		{Name: "availability", Type: "string", Description: "Stock status (in_stock, out_of_stock, etc.)"},
 	},
+	IdentityFields: []string{"name"},
+	BuiltIn:        true,
 }
 
-// GetSchema returns the entity schema for the given type.
+// GetSchema returns the built-in entity schema for the given type.
+// Use SchemaRegistry.GetSchema to also resolve org-defined schemas.
 func GetSchema(schemaType string) (*EntitySchema, bool) {
 	s, ok := schemas[schemaType]
 	if !ok {
@@ -27,3 +34,13 @@ func GetSchema(schemaType string) (*EntitySchema, bool) {
 	}
 	return &s, true
 }
+
+// BuiltInSchemas returns all built-in schemas.
+func BuiltInSchemas() []EntitySchema {
+	out := make([]EntitySchema, 0, len(schemas))
+	for _, s := range schemas {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out
+}
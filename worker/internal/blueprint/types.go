@@ -10,16 +10,32 @@ type ExtractionRules struct {
 
 // FieldMapping describes how to extract a single field value.
 type FieldMapping struct {
-	XPath     string `json:"xpath"`
-	Type      string `json:"type"`                // string, integer, number
-	Attribute string `json:"attribute"`           // text, href, src, etc.
-	Expression string `json:"expression,omitempty"` // expr-lang expression; value is the raw extracted string
+	XPath       string `json:"xpath"`
+	Type        string `json:"type"`                   // string, integer, number
+	Attribute   string `json:"attribute"`              // text, href, src, etc.
+	Expression  string `json:"expression,omitempty"`   // expr-lang expression; value is the raw extracted string
+	RenamedFrom string `json:"renamed_from,omitempty"` // previous field name; stored values are carried over on migration
 }
 
 // EntitySchema defines the expected shape of extracted entities.
+// Built-in schemas are compiled into the worker; org-defined schemas are
+// stored in the entity_schemas table and versioned on every change.
 type EntitySchema struct {
-	Type   string     `json:"type"`
-	Fields []FieldDef `json:"fields"`
+	Type           string     `json:"type"`
+	Version        int        `json:"version"`
+	Description    string     `json:"description,omitempty"`
+	Fields         []FieldDef `json:"fields"`
+	IdentityFields []string   `json:"identity_fields,omitempty"` // default identity for watches that don't set one
+	BuiltIn        bool       `json:"built_in"`
+}
+
+// FieldNames returns the names of the schema's fields, in schema order.
+func (s *EntitySchema) FieldNames() []string {
+	names := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		names[i] = f.Name
+	}
+	return names
 }
 
 // FieldDef describes a single field in an entity schema.
@@ -27,4 +43,5 @@ type FieldDef struct {
 	Name        string `json:"name"`
 	Type        string `json:"type"`
 	Description string `json:"description"`
+	RenamedFrom string `json:"renamed_from,omitempty"` // previous field name; stored values are carried over on migration
 }
@@ -0,0 +1,101 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var identifierPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+var fieldTypes = map[string]bool{
+	"string":  true,
+	"integer": true,
+	"number":  true,
+}
+
+// ValidateSchema checks that a schema definition is well-formed: a valid type
+// name, at least one field, unique field names with known types, and identity
+// fields that refer to declared fields.
+func ValidateSchema(s *EntitySchema) error {
+	if !identifierPattern.MatchString(s.Type) {
+		return fmt.Errorf("invalid schema type %q: must be lowercase letters, digits and underscores", s.Type)
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("schema %q has no fields", s.Type)
+	}
+
+	seen := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		if !identifierPattern.MatchString(f.Name) {
+			return fmt.Errorf("invalid field name %q: must be lowercase letters, digits and underscores", f.Name)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("duplicate field %q", f.Name)
+		}
+		if !fieldTypes[f.Type] {
+			return fmt.Errorf("field %q has unknown type %q (expected string, integer or number)", f.Name, f.Type)
+		}
+		seen[f.Name] = true
+	}
+
+	for _, name := range s.IdentityFields {
+		if !seen[name] {
+			return fmt.Errorf("identity field %q is not defined in the schema", name)
+		}
+	}
+	return nil
+}
+
+// ValidateEntities checks extracted entities against a schema and returns a
+// human-readable message for every unknown field or type mismatch.
+func ValidateEntities(entities []map[string]any, schema *EntitySchema) []string {
+	types := make(map[string]string, len(schema.Fields))
+	for _, f := range schema.Fields {
+		types[f.Name] = f.Type
+	}
+
+	var problems []string
+	for i, entity := range entities {
+		names := make([]string, 0, len(entity))
+		for name := range entity {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			want, ok := types[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("entity %d: field %q is not part of schema %q", i, name, schema.Type))
+				continue
+			}
+			if !valueHasType(entity[name], want) {
+				problems = append(problems, fmt.Sprintf("entity %d: field %q has type %T, expected %s", i, name, entity[name], want))
+			}
+		}
+	}
+	return problems
+}
+
+func valueHasType(v any, fieldType string) bool {
+	if v == nil {
+		return true
+	}
+	switch fieldType {
+	case "integer":
+		switch v.(type) {
+		case int, int32, int64:
+			return true
+		}
+		return false
+	case "number":
+		switch v.(type) {
+		case float32, float64, int, int32, int64:
+			return true
+		}
+		return false
+	default:
+		_, ok := v.(string)
+		return ok
+	}
+}
@@ -0,0 +1,57 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSchema(t *testing.T) {
+	valid := EntitySchema{
+		Type: "job_vacancy",
+		Fields: []FieldDef{
+			{Name: "title", Type: "string"},
+			{Name: "salary_min", Type: "integer"},
+		},
+		IdentityFields: []string{"title"},
+	}
+	require.NoError(t, ValidateSchema(&valid))
+
+	tests := []struct {
+		name   string
+		mutate func(s *EntitySchema)
+	}{
+		{"bad type name", func(s *EntitySchema) { s.Type = "Job Vacancy" }},
+		{"no fields", func(s *EntitySchema) { s.Fields = nil }},
+		{"duplicate field", func(s *EntitySchema) { s.Fields = append(s.Fields, FieldDef{Name: "title", Type: "string"}) }},
+		{"unknown field type", func(s *EntitySchema) { s.Fields = []FieldDef{{Name: "title", Type: "date"}} }},
+		{"unknown identity field", func(s *EntitySchema) { s.IdentityFields = []string{"company"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			s.Fields = append([]FieldDef(nil), valid.Fields...)
+			tt.mutate(&s)
+			assert.Error(t, ValidateSchema(&s))
+		})
+	}
+}
+
+func TestValidateSchema_BuiltIns(t *testing.T) {
+	for _, s := range BuiltInSchemas() {
+		assert.NoError(t, ValidateSchema(&s), s.Type)
+	}
+}
+
+func TestValidateEntities(t *testing.T) {
+	entities := []map[string]any{
+		{"name": "Widget", "price": int64(2999), "rating": 4.5},
+		{"name": "Gadget", "price": "29.99", "color": "red"},
+	}
+
+	problems := ValidateEntities(entities, &EcommerceProductSchema)
+	require.Len(t, problems, 2)
+	assert.Contains(t, problems[0], `field "color" is not part of schema`)
+	assert.Contains(t, problems[1], `field "price" has type string, expected integer`)
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 )
 
 // Config holds all worker configuration.
@@ -15,6 +17,35 @@ type Config struct {
 	OpenAIModel     string
 	ResendAPIKey    string
 	ResendFromEmail string
+
+	// WorkerID identifies this process on the leases it takes. It must be
+	// unique among workers sharing the database.
+	WorkerID string
+
+	// SchedulerConcurrency is the number of watches run at once. Zero leaves
+	// it to the scheduler's default.
+	SchedulerConcurrency int
+
+	// Per-phase deadlines of a watch run. Zero leaves them to the scheduler's
+	// defaults.
+	RunFetchTimeout   time.Duration
+	RunExtractTimeout time.Duration
+	RunPersistTimeout time.Duration
+
+	// ShutdownTimeout is how long in-flight runs and deliveries get to finish
+	// on shutdown before they are interrupted.
+	ShutdownTimeout time.Duration
+
+	// Snapshot archive. ArchiveBackend is "fs", "s3", or empty to disable.
+	ArchiveBackend        string
+	ArchiveDir            string
+	ArchiveS3Endpoint     string
+	ArchiveS3Region       string
+	ArchiveS3Bucket       string
+	ArchiveS3AccessKey    string
+	ArchiveS3SecretKey    string
+	ArchiveS3SessionToken string
+	ArchiveRetention      time.Duration
 }
 
 // Load parses configuration from environment variables.
@@ -28,8 +59,47 @@ func Load() (*Config, error) {
 		OpenAIModel:     getEnv("OPENAI_MODEL", "gpt-4o-mini"),
 		ResendAPIKey:    os.Getenv("RESEND_API_KEY"),
 		ResendFromEmail: getEnv("RESEND_FROM_EMAIL", "Blueprinter <notifications@notify.blueprinter.io>"),
+		WorkerID:        getEnv("WORKER_ID", defaultWorkerID()),
+
+		ArchiveBackend:        os.Getenv("ARCHIVE_BACKEND"),
+		ArchiveDir:            getEnv("ARCHIVE_DIR", "./data/snapshots"),
+		ArchiveS3Endpoint:     os.Getenv("ARCHIVE_S3_ENDPOINT"),
+		ArchiveS3Region:       os.Getenv("ARCHIVE_S3_REGION"),
+		ArchiveS3Bucket:       os.Getenv("ARCHIVE_S3_BUCKET"),
+		ArchiveS3AccessKey:    os.Getenv("ARCHIVE_S3_ACCESS_KEY_ID"),
+		ArchiveS3SecretKey:    os.Getenv("ARCHIVE_S3_SECRET_ACCESS_KEY"),
+		ArchiveS3SessionToken: os.Getenv("ARCHIVE_S3_SESSION_TOKEN"),
+	}
+
+	var err error
+	if v := os.Getenv("SCHEDULER_CONCURRENCY"); v != "" {
+		concurrency, err := strconv.Atoi(v)
+		if err != nil || concurrency < 1 {
+			return nil, fmt.Errorf("SCHEDULER_CONCURRENCY must be a positive integer")
+		}
+		cfg.SchedulerConcurrency = concurrency
+	}
+
+	if cfg.RunFetchTimeout, err = getEnvDuration("RUN_FETCH_TIMEOUT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.RunExtractTimeout, err = getEnvDuration("RUN_EXTRACT_TIMEOUT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.RunPersistTimeout, err = getEnvDuration("RUN_PERSIST_TIMEOUT", 0); err != nil {
+		return nil, err
 	}
 
+	if cfg.ShutdownTimeout, err = getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	retentionDays, err := strconv.Atoi(getEnv("ARCHIVE_RETENTION_DAYS", "30"))
+	if err != nil || retentionDays < 0 {
+		return nil, fmt.Errorf("ARCHIVE_RETENTION_DAYS must be a non-negative integer")
+	}
+	cfg.ArchiveRetention = time.Duration(retentionDays) * 24 * time.Hour
+
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL is required")
 	}
@@ -42,6 +112,11 @@ func Load() (*Config, error) {
 	if cfg.OpenAIAPIKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY is required")
 	}
+	switch cfg.ArchiveBackend {
+	case "", "fs", "s3":
+	default:
+		return nil, fmt.Errorf("ARCHIVE_BACKEND must be \"fs\" or \"s3\"")
+	}
 
 	return cfg, nil
 }
@@ -52,3 +127,25 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvDuration parses a duration such as "90s" or "2m" from the environment.
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration", key)
+	}
+	return d, nil
+}
+
+// defaultWorkerID derives a worker ID from the hostname and process ID.
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -11,23 +11,36 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const getPendingDeliveries = `-- name: GetPendingDeliveries :many
-SELECT
+const claimPendingDeliveries = `-- name: ClaimPendingDeliveries :many
+WITH due AS (
+  SELECT id FROM deliveries
+  WHERE status = 'pending'
+    AND next_retry_at <= now()
+  ORDER BY next_retry_at ASC
+  LIMIT 50
+  FOR UPDATE SKIP LOCKED
+)
+UPDATE deliveries d
+SET next_retry_at = now() + make_interval(secs => $1::int),
+    claimed_by = $2::text
+FROM due, subscriptions s, events e
+WHERE d.id = due.id
+  AND s.id = d.subscription_id
+  AND e.id = d.event_id
+RETURNING
   d.id, d.org_id, d.event_id, d.subscription_id, d.status,
   d.attempts, d.max_attempts, d.next_retry_at, d.last_error,
   d.delivered_at, d.created_at,
   s.name AS subscription_name, s.channel_type, s.channel_config,
   e.event_type, e.payload AS event_payload
-FROM deliveries d
-JOIN subscriptions s ON s.id = d.subscription_id
-JOIN events e ON e.id = d.event_id
-WHERE d.status = 'pending'
-  AND d.next_retry_at <= now()
-ORDER BY d.next_retry_at ASC
-LIMIT 50
 `
 
-type GetPendingDeliveriesRow struct {
+type ClaimPendingDeliveriesParams struct {
+	LeaseSeconds int32  `json:"lease_seconds"`
+	Owner        string `json:"owner"`
+}
+
+type ClaimPendingDeliveriesRow struct {
 	ID               pgtype.UUID        `json:"id"`
 	OrgID            string             `json:"org_id"`
 	EventID          pgtype.UUID        `json:"event_id"`
@@ -46,15 +59,18 @@ type GetPendingDeliveriesRow struct {
 	EventPayload     []byte             `json:"event_payload"`
 }
 
-func (q *Queries) GetPendingDeliveries(ctx context.Context) ([]GetPendingDeliveriesRow, error) {
-	rows, err := q.db.Query(ctx, getPendingDeliveries)
+// Claims up to 50 due deliveries for one worker by pushing next_retry_at
+// out by the lease. Rows locked by another worker's claim are skipped, and a
+// delivery whose worker crashed becomes due again when the lease runs out.
+func (q *Queries) ClaimPendingDeliveries(ctx context.Context, arg ClaimPendingDeliveriesParams) ([]ClaimPendingDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimPendingDeliveries, arg.LeaseSeconds, arg.Owner)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetPendingDeliveriesRow{}
+	items := []ClaimPendingDeliveriesRow{}
 	for rows.Next() {
-		var i GetPendingDeliveriesRow
+		var i ClaimPendingDeliveriesRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.OrgID,
@@ -83,83 +99,146 @@ func (q *Queries) GetPendingDeliveries(ctx context.Context) ([]GetPendingDeliver
 	return items, nil
 }
 
-const insertDelivery = `-- name: InsertDelivery :one
+const extendDeliveryClaims = `-- name: ExtendDeliveryClaims :execrows
+UPDATE deliveries
+SET next_retry_at = now() + make_interval(secs => $1::int)
+WHERE id = ANY($2::uuid[])
+  AND claimed_by = $3::text
+  AND status = 'pending'
+`
+
+type ExtendDeliveryClaimsParams struct {
+	LeaseSeconds int32         `json:"lease_seconds"`
+	Ids          []pgtype.UUID `json:"ids"`
+	Owner        string        `json:"owner"`
+}
+
+// Heartbeat for claimed deliveries, so that a slow send isn't claimed and
+// sent again by another worker. Skips those settled or no longer held.
+func (q *Queries) ExtendDeliveryClaims(ctx context.Context, arg ExtendDeliveryClaimsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, extendDeliveryClaims, arg.LeaseSeconds, arg.Ids, arg.Owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertDeliveries = `-- name: InsertDeliveries :execrows
 INSERT INTO deliveries (org_id, event_id, subscription_id, status, attempts, max_attempts, next_retry_at)
-VALUES ($1, $2, $3, 'pending', 0, 5, now())
-RETURNING id, org_id, event_id, subscription_id, status, attempts, max_attempts, next_retry_at, last_error, delivered_at, created_at
+SELECT $1::text, u.event_id, u.subscription_id, 'pending', 0, 5, now()
+FROM unnest($2::uuid[], $3::uuid[]) AS u(event_id, subscription_id)
 `
 
-type InsertDeliveryParams struct {
-	OrgID          string      `json:"org_id"`
-	EventID        pgtype.UUID `json:"event_id"`
-	SubscriptionID pgtype.UUID `json:"subscription_id"`
+type InsertDeliveriesParams struct {
+	OrgID           string        `json:"org_id"`
+	EventIds        []pgtype.UUID `json:"event_ids"`
+	SubscriptionIds []pgtype.UUID `json:"subscription_ids"`
 }
 
-func (q *Queries) InsertDelivery(ctx context.Context, arg InsertDeliveryParams) (Delivery, error) {
-	row := q.db.QueryRow(ctx, insertDelivery, arg.OrgID, arg.EventID, arg.SubscriptionID)
-	var i Delivery
-	err := row.Scan(
-		&i.ID,
-		&i.OrgID,
-		&i.EventID,
-		&i.SubscriptionID,
-		&i.Status,
-		&i.Attempts,
-		&i.MaxAttempts,
-		&i.NextRetryAt,
-		&i.LastError,
-		&i.DeliveredAt,
-		&i.CreatedAt,
-	)
-	return i, err
+// Inserts a batch of pending deliveries. event_ids and subscription_ids are
+// parallel arrays.
+func (q *Queries) InsertDeliveries(ctx context.Context, arg InsertDeliveriesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertDeliveries, arg.OrgID, arg.EventIds, arg.SubscriptionIds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const markDeliveryDelivered = `-- name: MarkDeliveryDelivered :exec
+const markDeliveryDelivered = `-- name: MarkDeliveryDelivered :execrows
 UPDATE deliveries
 SET status = 'delivered',
     delivered_at = now(),
-    attempts = attempts + 1
-WHERE id = $1
+    attempts = attempts + 1,
+    claimed_by = NULL
+WHERE id = $1 AND claimed_by = $2::text
 `
 
-func (q *Queries) MarkDeliveryDelivered(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, markDeliveryDelivered, id)
-	return err
+type MarkDeliveryDeliveredParams struct {
+	ID    pgtype.UUID `json:"id"`
+	Owner string      `json:"owner"`
+}
+
+// Affects no rows once the worker no longer holds the delivery's claim.
+func (q *Queries) MarkDeliveryDelivered(ctx context.Context, arg MarkDeliveryDeliveredParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markDeliveryDelivered, arg.ID, arg.Owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const markDeliveryFailed = `-- name: MarkDeliveryFailed :exec
+const markDeliveryFailed = `-- name: MarkDeliveryFailed :execrows
 UPDATE deliveries
 SET status = 'failed',
     attempts = attempts + 1,
-    last_error = $2
-WHERE id = $1
+    last_error = $1,
+    claimed_by = NULL
+WHERE id = $2 AND claimed_by = $3::text
 `
 
 type MarkDeliveryFailedParams struct {
-	ID        pgtype.UUID `json:"id"`
 	LastError pgtype.Text `json:"last_error"`
+	ID        pgtype.UUID `json:"id"`
+	Owner     string      `json:"owner"`
 }
 
-func (q *Queries) MarkDeliveryFailed(ctx context.Context, arg MarkDeliveryFailedParams) error {
-	_, err := q.db.Exec(ctx, markDeliveryFailed, arg.ID, arg.LastError)
-	return err
+// Affects no rows once the worker no longer holds the delivery's claim.
+func (q *Queries) MarkDeliveryFailed(ctx context.Context, arg MarkDeliveryFailedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markDeliveryFailed, arg.LastError, arg.ID, arg.Owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const markDeliveryRetry = `-- name: MarkDeliveryRetry :exec
+const markDeliveryRetry = `-- name: MarkDeliveryRetry :execrows
 UPDATE deliveries
 SET attempts = attempts + 1,
-    next_retry_at = $2,
-    last_error = $3
-WHERE id = $1
+    next_retry_at = $1,
+    last_error = $2,
+    claimed_by = NULL
+WHERE id = $3 AND claimed_by = $4::text
 `
 
 type MarkDeliveryRetryParams struct {
-	ID          pgtype.UUID        `json:"id"`
 	NextRetryAt pgtype.Timestamptz `json:"next_retry_at"`
 	LastError   pgtype.Text        `json:"last_error"`
+	ID          pgtype.UUID        `json:"id"`
+	Owner       string             `json:"owner"`
+}
+
+// Affects no rows once the worker no longer holds the delivery's claim.
+func (q *Queries) MarkDeliveryRetry(ctx context.Context, arg MarkDeliveryRetryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markDeliveryRetry,
+		arg.NextRetryAt,
+		arg.LastError,
+		arg.ID,
+		arg.Owner,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const releaseDeliveries = `-- name: ReleaseDeliveries :exec
+UPDATE deliveries
+SET next_retry_at = now(),
+    claimed_by = NULL
+WHERE id = ANY($1::uuid[])
+  AND claimed_by = $2::text
+  AND status = 'pending'
+`
+
+type ReleaseDeliveriesParams struct {
+	Ids   []pgtype.UUID `json:"ids"`
+	Owner string        `json:"owner"`
 }
 
-func (q *Queries) MarkDeliveryRetry(ctx context.Context, arg MarkDeliveryRetryParams) error {
-	_, err := q.db.Exec(ctx, markDeliveryRetry, arg.ID, arg.NextRetryAt, arg.LastError)
+// Makes claimed deliveries due again at once, for a worker that is shutting
+// down before sending them.
+func (q *Queries) ReleaseDeliveries(ctx context.Context, arg ReleaseDeliveriesParams) error {
+	_, err := q.db.Exec(ctx, releaseDeliveries, arg.Ids, arg.Owner)
 	return err
 }
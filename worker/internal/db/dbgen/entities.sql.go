@@ -12,7 +12,7 @@ import (
 )
 
 const getEntitiesByWatch = `-- name: GetEntitiesByWatch :many
-SELECT id, org_id, watch_id, schema_type, external_id, content, url, status, first_seen_at, last_seen_at, created_at, updated_at FROM entities
+SELECT id, org_id, watch_id, schema_type, external_id, content, url, source_url, source_param, status, schema_version, blueprint_version, first_seen_at, last_seen_at, missed_runs, created_at, updated_at FROM entities
 WHERE watch_id = $1 AND status = 'active'
 ORDER BY external_id
 `
@@ -34,9 +34,14 @@ func (q *Queries) GetEntitiesByWatch(ctx context.Context, watchID pgtype.UUID) (
 			&i.ExternalID,
 			&i.Content,
 			&i.Url,
+			&i.SourceUrl,
+			&i.SourceParam,
 			&i.Status,
+			&i.SchemaVersion,
+			&i.BlueprintVersion,
 			&i.FirstSeenAt,
 			&i.LastSeenAt,
+			&i.MissedRuns,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -50,9 +55,63 @@ func (q *Queries) GetEntitiesByWatch(ctx context.Context, watchID pgtype.UUID) (
 	return items, nil
 }
 
+const getEntity = `-- name: GetEntity :one
+SELECT id, org_id, watch_id, schema_type, external_id, content, url, source_url, source_param, status, schema_version, blueprint_version, first_seen_at, last_seen_at, missed_runs, created_at, updated_at FROM entities
+WHERE id = $1 AND org_id = $2
+`
+
+type GetEntityParams struct {
+	ID    pgtype.UUID `json:"id"`
+	OrgID string      `json:"org_id"`
+}
+
+func (q *Queries) GetEntity(ctx context.Context, arg GetEntityParams) (Entity, error) {
+	row := q.db.QueryRow(ctx, getEntity, arg.ID, arg.OrgID)
+	var i Entity
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.WatchID,
+		&i.SchemaType,
+		&i.ExternalID,
+		&i.Content,
+		&i.Url,
+		&i.SourceUrl,
+		&i.SourceParam,
+		&i.Status,
+		&i.SchemaVersion,
+		&i.BlueprintVersion,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.MissedRuns,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const markEntitiesMissed = `-- name: MarkEntitiesMissed :exec
+UPDATE entities
+SET missed_runs = missed_runs + 1, updated_at = now()
+WHERE watch_id = $1 AND external_id = ANY($2::text[])
+  AND status = 'active'
+`
+
+type MarkEntitiesMissedParams struct {
+	WatchID pgtype.UUID `json:"watch_id"`
+	Column2 []string    `json:"column_2"`
+}
+
+// Counts a missed run for active entities that are still within their
+// watch's disappearance grace window.
+func (q *Queries) MarkEntitiesMissed(ctx context.Context, arg MarkEntitiesMissedParams) error {
+	_, err := q.db.Exec(ctx, markEntitiesMissed, arg.WatchID, arg.Column2)
+	return err
+}
+
 const markEntitiesStale = `-- name: MarkEntitiesStale :exec
 UPDATE entities
-SET status = 'stale', updated_at = now()
+SET status = 'stale', missed_runs = 0, updated_at = now()
 WHERE watch_id = $1 AND external_id = ANY($2::text[])
   AND status = 'active'
 `
@@ -67,9 +126,63 @@ func (q *Queries) MarkEntitiesStale(ctx context.Context, arg MarkEntitiesStalePa
 	return err
 }
 
+const migrateEntityContent = `-- name: MigrateEntityContent :exec
+UPDATE entities
+SET content = $2,
+    schema_version = $3,
+    blueprint_version = $4,
+    updated_at = now()
+WHERE id = $1
+`
+
+type MigrateEntityContentParams struct {
+	ID               pgtype.UUID `json:"id"`
+	Content          []byte      `json:"content"`
+	SchemaVersion    int32       `json:"schema_version"`
+	BlueprintVersion int32       `json:"blueprint_version"`
+}
+
+func (q *Queries) MigrateEntityContent(ctx context.Context, arg MigrateEntityContentParams) error {
+	_, err := q.db.Exec(ctx, migrateEntityContent,
+		arg.ID,
+		arg.Content,
+		arg.SchemaVersion,
+		arg.BlueprintVersion,
+	)
+	return err
+}
+
+const renameEntity = `-- name: RenameEntity :execrows
+UPDATE entities e
+SET external_id = $1, updated_at = now()
+WHERE e.watch_id = $2 AND e.external_id = $3
+  AND e.status = 'active'
+  AND NOT EXISTS (
+    SELECT 1 FROM entities o
+    WHERE o.org_id = e.org_id AND o.watch_id = e.watch_id
+      AND o.schema_type = e.schema_type AND o.external_id = $1
+  )
+`
+
+type RenameEntityParams struct {
+	NewExternalID string      `json:"new_external_id"`
+	WatchID       pgtype.UUID `json:"watch_id"`
+	OldExternalID string      `json:"old_external_id"`
+}
+
+// Moves an active entity to the new external ID it got when its identity
+// fields changed, unless an entity with that ID already exists.
+func (q *Queries) RenameEntity(ctx context.Context, arg RenameEntityParams) (int64, error) {
+	result, err := q.db.Exec(ctx, renameEntity, arg.NewExternalID, arg.WatchID, arg.OldExternalID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const touchEntitiesLastSeen = `-- name: TouchEntitiesLastSeen :exec
 UPDATE entities
-SET last_seen_at = now(), updated_at = now()
+SET last_seen_at = now(), missed_runs = 0, updated_at = now()
 WHERE watch_id = $1 AND external_id = ANY($2::text[])
   AND status = 'active'
 `
@@ -84,50 +197,103 @@ func (q *Queries) TouchEntitiesLastSeen(ctx context.Context, arg TouchEntitiesLa
 	return err
 }
 
-const upsertEntity = `-- name: UpsertEntity :one
-INSERT INTO entities (org_id, watch_id, schema_type, external_id, content, url, status, first_seen_at, last_seen_at)
-VALUES ($1, $2, $3, $4, $5, $6, 'active', now(), now())
+const updateEntitySources = `-- name: UpdateEntitySources :exec
+UPDATE entities e
+SET source_url = u.source_url, source_param = NULLIF(u.source_param, ''), updated_at = now()
+FROM unnest($1::text[], $2::text[], $3::text[])
+    AS u(external_id, source_url, source_param)
+WHERE e.watch_id = $4::uuid AND e.external_id = u.external_id
+  AND e.status = 'active'
+`
+
+type UpdateEntitySourcesParams struct {
+	ExternalIds  []string    `json:"external_ids"`
+	SourceUrls   []string    `json:"source_urls"`
+	SourceParams []string    `json:"source_params"`
+	WatchID      pgtype.UUID `json:"watch_id"`
+}
+
+// Records the page an active entity was last found on, for entities that
+// moved between the pages of a multi-URL watch without changing.
+func (q *Queries) UpdateEntitySources(ctx context.Context, arg UpdateEntitySourcesParams) error {
+	_, err := q.db.Exec(ctx, updateEntitySources,
+		arg.ExternalIds,
+		arg.SourceUrls,
+		arg.SourceParams,
+		arg.WatchID,
+	)
+	return err
+}
+
+const upsertEntities = `-- name: UpsertEntities :many
+INSERT INTO entities (org_id, watch_id, schema_type, external_id, content, source_url, source_param, status, schema_version, blueprint_version, first_seen_at, last_seen_at)
+SELECT $1::text, $2::uuid, $3::text,
+       u.external_id, u.content::jsonb, u.source_url, NULLIF(u.source_param, ''), 'active',
+       $4::int, $5::int, now(), now()
+FROM unnest($6::text[], $7::text[], $8::text[], $9::text[])
+    AS u(external_id, content, source_url, source_param)
 ON CONFLICT (org_id, watch_id, schema_type, external_id) DO UPDATE
 SET content = EXCLUDED.content,
-    url = EXCLUDED.url,
+    source_url = EXCLUDED.source_url,
+    source_param = EXCLUDED.source_param,
     status = 'active',
+    schema_version = EXCLUDED.schema_version,
+    blueprint_version = EXCLUDED.blueprint_version,
     last_seen_at = now(),
+    missed_runs = 0,
     updated_at = now()
-RETURNING id, org_id, watch_id, schema_type, external_id, content, url, status, first_seen_at, last_seen_at, created_at, updated_at
+RETURNING id, external_id
 `
 
-type UpsertEntityParams struct {
-	OrgID      string      `json:"org_id"`
-	WatchID    pgtype.UUID `json:"watch_id"`
-	SchemaType string      `json:"schema_type"`
+type UpsertEntitiesParams struct {
+	OrgID            string      `json:"org_id"`
+	WatchID          pgtype.UUID `json:"watch_id"`
+	SchemaType       string      `json:"schema_type"`
+	SchemaVersion    int32       `json:"schema_version"`
+	BlueprintVersion int32       `json:"blueprint_version"`
+	ExternalIds      []string    `json:"external_ids"`
+	Contents         []string    `json:"contents"`
+	SourceUrls       []string    `json:"source_urls"`
+	SourceParams     []string    `json:"source_params"`
+}
+
+type UpsertEntitiesRow struct {
+	ID         pgtype.UUID `json:"id"`
 	ExternalID string      `json:"external_id"`
-	Content    []byte      `json:"content"`
-	Url        pgtype.Text `json:"url"`
 }
 
-func (q *Queries) UpsertEntity(ctx context.Context, arg UpsertEntityParams) (Entity, error) {
-	row := q.db.QueryRow(ctx, upsertEntity,
+// Upserts a batch of entities of one watch in a single statement.
+// external_ids, contents, source_urls and source_params are parallel
+// arrays; an empty source_param is stored as NULL.
+func (q *Queries) UpsertEntities(ctx context.Context, arg UpsertEntitiesParams) ([]UpsertEntitiesRow, error) {
+	rows, err := q.db.Query(ctx, upsertEntities,
 		arg.OrgID,
 		arg.WatchID,
 		arg.SchemaType,
-		arg.ExternalID,
-		arg.Content,
-		arg.Url,
+		arg.SchemaVersion,
+		arg.BlueprintVersion,
+		arg.ExternalIds,
+		arg.Contents,
+		arg.SourceUrls,
+		arg.SourceParams,
 	)
-	var i Entity
-	err := row.Scan(
-		&i.ID,
-		&i.OrgID,
-		&i.WatchID,
-		&i.SchemaType,
-		&i.ExternalID,
-		&i.Content,
-		&i.Url,
-		&i.Status,
-		&i.FirstSeenAt,
-		&i.LastSeenAt,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UpsertEntitiesRow{}
+	for rows.Next() {
+		var i UpsertEntitiesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
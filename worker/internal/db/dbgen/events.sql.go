@@ -101,40 +101,58 @@ func (q *Queries) GetEventsByWatch(ctx context.Context, arg GetEventsByWatchPara
 	return items, nil
 }
 
-const insertEvent = `-- name: InsertEvent :one
+const insertEvents = `-- name: InsertEvents :many
 INSERT INTO events (org_id, event_type, watch_id, watch_run_id, entity_id, payload, occurred_at)
-VALUES ($1, $2, $3, $4, $5, $6, now())
+SELECT $1::text, u.event_type, $2::uuid, $3::uuid,
+       u.entity_id, u.payload::jsonb, now()
+FROM unnest($4::text[], $5::uuid[], $6::text[])
+  AS u(event_type, entity_id, payload)
 RETURNING id, org_id, event_type, watch_id, watch_run_id, entity_id, payload, occurred_at
 `
 
-type InsertEventParams struct {
-	OrgID      string      `json:"org_id"`
-	EventType  string      `json:"event_type"`
-	WatchID    pgtype.UUID `json:"watch_id"`
-	WatchRunID pgtype.UUID `json:"watch_run_id"`
-	EntityID   pgtype.UUID `json:"entity_id"`
-	Payload    []byte      `json:"payload"`
+type InsertEventsParams struct {
+	OrgID      string        `json:"org_id"`
+	WatchID    pgtype.UUID   `json:"watch_id"`
+	WatchRunID pgtype.UUID   `json:"watch_run_id"`
+	EventTypes []string      `json:"event_types"`
+	EntityIds  []pgtype.UUID `json:"entity_ids"`
+	Payloads   []string      `json:"payloads"`
 }
 
-func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) (Event, error) {
-	row := q.db.QueryRow(ctx, insertEvent,
+// Inserts a batch of events of one watch run. event_types, entity_ids and
+// payloads are parallel arrays.
+func (q *Queries) InsertEvents(ctx context.Context, arg InsertEventsParams) ([]Event, error) {
+	rows, err := q.db.Query(ctx, insertEvents,
 		arg.OrgID,
-		arg.EventType,
 		arg.WatchID,
 		arg.WatchRunID,
-		arg.EntityID,
-		arg.Payload,
+		arg.EventTypes,
+		arg.EntityIds,
+		arg.Payloads,
 	)
-	var i Event
-	err := row.Scan(
-		&i.ID,
-		&i.OrgID,
-		&i.EventType,
-		&i.WatchID,
-		&i.WatchRunID,
-		&i.EntityID,
-		&i.Payload,
-		&i.OccurredAt,
-	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Event{}
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrgID,
+			&i.EventType,
+			&i.WatchID,
+			&i.WatchRunID,
+			&i.EntityID,
+			&i.Payload,
+			&i.OccurredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
@@ -22,6 +22,16 @@ type Blueprint struct {
 	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
 }
 
+type BlueprintRevision struct {
+	ID              pgtype.UUID        `json:"id"`
+	OrgID           string             `json:"org_id"`
+	BlueprintID     pgtype.UUID        `json:"blueprint_id"`
+	Version         int32              `json:"version"`
+	SchemaType      string             `json:"schema_type"`
+	ExtractionRules []byte             `json:"extraction_rules"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
 type Delivery struct {
 	ID             pgtype.UUID        `json:"id"`
 	OrgID          string             `json:"org_id"`
@@ -31,24 +41,54 @@ type Delivery struct {
 	Attempts       int32              `json:"attempts"`
 	MaxAttempts    int32              `json:"max_attempts"`
 	NextRetryAt    pgtype.Timestamptz `json:"next_retry_at"`
+	ClaimedBy      pgtype.Text        `json:"claimed_by"`
 	LastError      pgtype.Text        `json:"last_error"`
 	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
 	CreatedAt      pgtype.Timestamptz `json:"created_at"`
 }
 
 type Entity struct {
-	ID          pgtype.UUID        `json:"id"`
-	OrgID       string             `json:"org_id"`
-	WatchID     pgtype.UUID        `json:"watch_id"`
-	SchemaType  string             `json:"schema_type"`
-	ExternalID  string             `json:"external_id"`
-	Content     []byte             `json:"content"`
-	Url         pgtype.Text        `json:"url"`
-	Status      string             `json:"status"`
-	FirstSeenAt pgtype.Timestamptz `json:"first_seen_at"`
-	LastSeenAt  pgtype.Timestamptz `json:"last_seen_at"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	ID               pgtype.UUID        `json:"id"`
+	OrgID            string             `json:"org_id"`
+	WatchID          pgtype.UUID        `json:"watch_id"`
+	SchemaType       string             `json:"schema_type"`
+	ExternalID       string             `json:"external_id"`
+	Content          []byte             `json:"content"`
+	Url              pgtype.Text        `json:"url"`
+	SourceUrl        pgtype.Text        `json:"source_url"`
+	SourceParam      pgtype.Text        `json:"source_param"`
+	Status           string             `json:"status"`
+	SchemaVersion    int32              `json:"schema_version"`
+	BlueprintVersion int32              `json:"blueprint_version"`
+	FirstSeenAt      pgtype.Timestamptz `json:"first_seen_at"`
+	LastSeenAt       pgtype.Timestamptz `json:"last_seen_at"`
+	MissedRuns       int32              `json:"missed_runs"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
+type EntitySchema struct {
+	ID             pgtype.UUID        `json:"id"`
+	OrgID          string             `json:"org_id"`
+	SchemaType     string             `json:"schema_type"`
+	Version        int32              `json:"version"`
+	Description    string             `json:"description"`
+	Fields         []byte             `json:"fields"`
+	IdentityFields []string           `json:"identity_fields"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	DeletedAt      pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type EntityVersion struct {
+	ID         pgtype.UUID        `json:"id"`
+	OrgID      string             `json:"org_id"`
+	WatchID    pgtype.UUID        `json:"watch_id"`
+	EntityID   pgtype.UUID        `json:"entity_id"`
+	WatchRunID pgtype.UUID        `json:"watch_run_id"`
+	ExternalID string             `json:"external_id"`
+	ChangeType string             `json:"change_type"`
+	Content    []byte             `json:"content"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 }
 
 type Event struct {
@@ -78,31 +118,73 @@ type Subscription struct {
 }
 
 type Watch struct {
+	ID                      pgtype.UUID        `json:"id"`
+	OrgID                   string             `json:"org_id"`
+	BlueprintID             pgtype.UUID        `json:"blueprint_id"`
+	Name                    string             `json:"name"`
+	Url                     string             `json:"url"`
+	Urls                    []string           `json:"urls"`
+	UrlParams               []string           `json:"url_params"`
+	Schedule                string             `json:"schedule"`
+	Timezone                string             `json:"timezone"`
+	JitterSeconds           int32              `json:"jitter_seconds"`
+	AdaptiveSchedule        bool               `json:"adaptive_schedule"`
+	AdaptiveMinSeconds      int32              `json:"adaptive_min_seconds"`
+	AdaptiveMaxSeconds      int32              `json:"adaptive_max_seconds"`
+	AdaptiveIntervalSeconds pgtype.Int4        `json:"adaptive_interval_seconds"`
+	IdentityFields          []string           `json:"identity_fields"`
+	IdentityFallbacks       []byte             `json:"identity_fallbacks"`
+	IdentityNormalize       []string           `json:"identity_normalize"`
+	TrackPositions          bool               `json:"track_positions"`
+	RenameThreshold         float64            `json:"rename_threshold"`
+	DiffPolicy              []byte             `json:"diff_policy"`
+	Status                  string             `json:"status"`
+	NextRunAt               pgtype.Timestamptz `json:"next_run_at"`
+	LastRunAt               pgtype.Timestamptz `json:"last_run_at"`
+	LastError               pgtype.Text        `json:"last_error"`
+	ConsecutiveFailures     int32              `json:"consecutive_failures"`
+	MinEntityCount          int32              `json:"min_entity_count"`
+	MaxDisappearanceRatio   float64            `json:"max_disappearance_ratio"`
+	AcceptNextRun           bool               `json:"accept_next_run"`
+	GraceMissedRuns         int32              `json:"grace_missed_runs"`
+	GracePeriodSeconds      int32              `json:"grace_period_seconds"`
+	LeaseOwner              pgtype.Text        `json:"lease_owner"`
+	LeaseExpiresAt          pgtype.Timestamptz `json:"lease_expires_at"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt               pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt               pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type WatchRun struct {
+	ID                  pgtype.UUID        `json:"id"`
+	OrgID               string             `json:"org_id"`
+	WatchID             pgtype.UUID        `json:"watch_id"`
+	BlueprintRevisionID pgtype.UUID        `json:"blueprint_revision_id"`
+	Status              string             `json:"status"`
+	StartedAt           pgtype.Timestamptz `json:"started_at"`
+	CompletedAt         pgtype.Timestamptz `json:"completed_at"`
+	EntitiesFound       pgtype.Int4        `json:"entities_found"`
+	EntitiesNew         pgtype.Int4        `json:"entities_new"`
+	EntitiesChanged     pgtype.Int4        `json:"entities_changed"`
+	EntitiesRemoved     pgtype.Int4        `json:"entities_removed"`
+	EventsEmitted       pgtype.Int4        `json:"events_emitted"`
+	ErrorMessage        pgtype.Text        `json:"error_message"`
+	CancelRequestedAt   pgtype.Timestamptz `json:"cancel_requested_at"`
+	Phase               pgtype.Text        `json:"phase"`
+	IdentityCollisions  pgtype.Int4        `json:"identity_collisions"`
+	IdentityMissing     pgtype.Int4        `json:"identity_missing"`
+	PageErrors          []byte             `json:"page_errors"`
+}
+
+type WatchRunSnapshot struct {
 	ID             pgtype.UUID        `json:"id"`
 	OrgID          string             `json:"org_id"`
-	BlueprintID    pgtype.UUID        `json:"blueprint_id"`
-	Name           string             `json:"name"`
+	WatchID        pgtype.UUID        `json:"watch_id"`
+	WatchRunID     pgtype.UUID        `json:"watch_run_id"`
 	Url            string             `json:"url"`
-	Schedule       string             `json:"schedule"`
-	IdentityFields []string           `json:"identity_fields"`
-	Status         string             `json:"status"`
-	NextRunAt      pgtype.Timestamptz `json:"next_run_at"`
+	RawHtmlKey     string             `json:"raw_html_key"`
+	CleanedHtmlKey string             `json:"cleaned_html_key"`
+	RawSize        int32              `json:"raw_size"`
+	CleanedSize    int32              `json:"cleaned_size"`
 	CreatedAt      pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
-	DeletedAt      pgtype.Timestamptz `json:"deleted_at"`
-}
-
-type WatchRun struct {
-	ID              pgtype.UUID        `json:"id"`
-	OrgID           string             `json:"org_id"`
-	WatchID         pgtype.UUID        `json:"watch_id"`
-	Status          string             `json:"status"`
-	StartedAt       pgtype.Timestamptz `json:"started_at"`
-	CompletedAt     pgtype.Timestamptz `json:"completed_at"`
-	EntitiesFound   pgtype.Int4        `json:"entities_found"`
-	EntitiesNew     pgtype.Int4        `json:"entities_new"`
-	EntitiesChanged pgtype.Int4        `json:"entities_changed"`
-	EntitiesRemoved pgtype.Int4        `json:"entities_removed"`
-	EventsEmitted   pgtype.Int4        `json:"events_emitted"`
-	ErrorMessage    pgtype.Text        `json:"error_message"`
 }
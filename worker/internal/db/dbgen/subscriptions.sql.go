@@ -11,24 +11,24 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const matchSubscriptions = `-- name: MatchSubscriptions :many
+const listWatchSubscriptions = `-- name: ListWatchSubscriptions :many
 SELECT id, org_id, name, event_types, watch_id, filters, channel_type, channel_config, status, created_at, updated_at, deleted_at FROM subscriptions
 WHERE org_id = $1
   AND status = 'active'
   AND deleted_at IS NULL
-  AND $2::text = ANY(event_types)
-  AND (watch_id IS NULL OR watch_id = $3)
+  AND (watch_id IS NULL OR watch_id = $2)
 ORDER BY created_at ASC
 `
 
-type MatchSubscriptionsParams struct {
+type ListWatchSubscriptionsParams struct {
 	OrgID   string      `json:"org_id"`
-	Column2 string      `json:"column_2"`
 	WatchID pgtype.UUID `json:"watch_id"`
 }
 
-func (q *Queries) MatchSubscriptions(ctx context.Context, arg MatchSubscriptionsParams) ([]Subscription, error) {
-	rows, err := q.db.Query(ctx, matchSubscriptions, arg.OrgID, arg.Column2, arg.WatchID)
+// Returns every active subscription that may match events of a watch,
+// regardless of event type, so matching can be done once per run.
+func (q *Queries) ListWatchSubscriptions(ctx context.Context, arg ListWatchSubscriptionsParams) ([]Subscription, error) {
+	rows, err := q.db.Query(ctx, listWatchSubscriptions, arg.OrgID, arg.WatchID)
 	if err != nil {
 		return nil, err
 	}
@@ -20,19 +20,25 @@ SET status = $2,
     entities_changed = $5,
     entities_removed = $6,
     events_emitted = $7,
-    error_message = $8
+    error_message = $8,
+    identity_collisions = $9,
+    identity_missing = $10,
+    page_errors = $11
 WHERE id = $1
 `
 
 type CompleteWatchRunParams struct {
-	ID              pgtype.UUID `json:"id"`
-	Status          string      `json:"status"`
-	EntitiesFound   pgtype.Int4 `json:"entities_found"`
-	EntitiesNew     pgtype.Int4 `json:"entities_new"`
-	EntitiesChanged pgtype.Int4 `json:"entities_changed"`
-	EntitiesRemoved pgtype.Int4 `json:"entities_removed"`
-	EventsEmitted   pgtype.Int4 `json:"events_emitted"`
-	ErrorMessage    pgtype.Text `json:"error_message"`
+	ID                 pgtype.UUID `json:"id"`
+	Status             string      `json:"status"`
+	EntitiesFound      pgtype.Int4 `json:"entities_found"`
+	EntitiesNew        pgtype.Int4 `json:"entities_new"`
+	EntitiesChanged    pgtype.Int4 `json:"entities_changed"`
+	EntitiesRemoved    pgtype.Int4 `json:"entities_removed"`
+	EventsEmitted      pgtype.Int4 `json:"events_emitted"`
+	ErrorMessage       pgtype.Text `json:"error_message"`
+	IdentityCollisions pgtype.Int4 `json:"identity_collisions"`
+	IdentityMissing    pgtype.Int4 `json:"identity_missing"`
+	PageErrors         []byte      `json:"page_errors"`
 }
 
 func (q *Queries) CompleteWatchRun(ctx context.Context, arg CompleteWatchRunParams) error {
@@ -45,28 +51,33 @@ func (q *Queries) CompleteWatchRun(ctx context.Context, arg CompleteWatchRunPara
 		arg.EntitiesRemoved,
 		arg.EventsEmitted,
 		arg.ErrorMessage,
+		arg.IdentityCollisions,
+		arg.IdentityMissing,
+		arg.PageErrors,
 	)
 	return err
 }
 
 const createWatchRun = `-- name: CreateWatchRun :one
-INSERT INTO watch_runs (org_id, watch_id, status, started_at)
-VALUES ($1, $2, 'running', now())
-RETURNING id, org_id, watch_id, status, started_at, completed_at, entities_found, entities_new, entities_changed, entities_removed, events_emitted, error_message
+INSERT INTO watch_runs (org_id, watch_id, blueprint_revision_id, status, started_at)
+VALUES ($1, $2, $3, 'running', now())
+RETURNING id, org_id, watch_id, blueprint_revision_id, status, started_at, completed_at, entities_found, entities_new, entities_changed, entities_removed, events_emitted, error_message, cancel_requested_at, phase, identity_collisions, identity_missing, page_errors
 `
 
 type CreateWatchRunParams struct {
-	OrgID   string      `json:"org_id"`
-	WatchID pgtype.UUID `json:"watch_id"`
+	OrgID               string      `json:"org_id"`
+	WatchID             pgtype.UUID `json:"watch_id"`
+	BlueprintRevisionID pgtype.UUID `json:"blueprint_revision_id"`
 }
 
 func (q *Queries) CreateWatchRun(ctx context.Context, arg CreateWatchRunParams) (WatchRun, error) {
-	row := q.db.QueryRow(ctx, createWatchRun, arg.OrgID, arg.WatchID)
+	row := q.db.QueryRow(ctx, createWatchRun, arg.OrgID, arg.WatchID, arg.BlueprintRevisionID)
 	var i WatchRun
 	err := row.Scan(
 		&i.ID,
 		&i.OrgID,
 		&i.WatchID,
+		&i.BlueprintRevisionID,
 		&i.Status,
 		&i.StartedAt,
 		&i.CompletedAt,
@@ -76,6 +87,198 @@ func (q *Queries) CreateWatchRun(ctx context.Context, arg CreateWatchRunParams)
 		&i.EntitiesRemoved,
 		&i.EventsEmitted,
 		&i.ErrorMessage,
+		&i.CancelRequestedAt,
+		&i.Phase,
+		&i.IdentityCollisions,
+		&i.IdentityMissing,
+		&i.PageErrors,
 	)
 	return i, err
 }
+
+const getPreviousWatchRunStatus = `-- name: GetPreviousWatchRunStatus :one
+SELECT status FROM watch_runs
+WHERE watch_id = $1 AND id <> $2 AND status <> 'running'
+ORDER BY started_at DESC
+LIMIT 1
+`
+
+type GetPreviousWatchRunStatusParams struct {
+	WatchID pgtype.UUID `json:"watch_id"`
+	ID      pgtype.UUID `json:"id"`
+}
+
+// Returns the status of the most recent finished run of a watch other than
+// the given one.
+func (q *Queries) GetPreviousWatchRunStatus(ctx context.Context, arg GetPreviousWatchRunStatusParams) (string, error) {
+	row := q.db.QueryRow(ctx, getPreviousWatchRunStatus, arg.WatchID, arg.ID)
+	var status string
+	err := row.Scan(&status)
+	return status, err
+}
+
+const getRecentRunChanges = `-- name: GetRecentRunChanges :many
+SELECT (COALESCE(entities_new, 0) + COALESCE(entities_changed, 0) + COALESCE(entities_removed, 0))::int AS changes
+FROM watch_runs
+WHERE watch_id = $1 AND status = 'completed'
+ORDER BY started_at DESC
+LIMIT $2
+`
+
+type GetRecentRunChangesParams struct {
+	WatchID pgtype.UUID `json:"watch_id"`
+	Limit   int32       `json:"limit"`
+}
+
+// Returns how many entities appeared, changed or disappeared in each of the
+// latest completed runs of a watch, newest first.
+func (q *Queries) GetRecentRunChanges(ctx context.Context, arg GetRecentRunChangesParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getRecentRunChanges, arg.WatchID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int32{}
+	for rows.Next() {
+		var changes int32
+		if err := rows.Scan(&changes); err != nil {
+			return nil, err
+		}
+		items = append(items, changes)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWatchRun = `-- name: GetWatchRun :one
+SELECT id, org_id, watch_id, blueprint_revision_id, status, started_at, completed_at, entities_found, entities_new, entities_changed, entities_removed, events_emitted, error_message, cancel_requested_at, phase, identity_collisions, identity_missing, page_errors FROM watch_runs
+WHERE id = $1 AND org_id = $2
+`
+
+type GetWatchRunParams struct {
+	ID    pgtype.UUID `json:"id"`
+	OrgID string      `json:"org_id"`
+}
+
+func (q *Queries) GetWatchRun(ctx context.Context, arg GetWatchRunParams) (WatchRun, error) {
+	row := q.db.QueryRow(ctx, getWatchRun, arg.ID, arg.OrgID)
+	var i WatchRun
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.WatchID,
+		&i.BlueprintRevisionID,
+		&i.Status,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.EntitiesFound,
+		&i.EntitiesNew,
+		&i.EntitiesChanged,
+		&i.EntitiesRemoved,
+		&i.EventsEmitted,
+		&i.ErrorMessage,
+		&i.CancelRequestedAt,
+		&i.Phase,
+		&i.IdentityCollisions,
+		&i.IdentityMissing,
+		&i.PageErrors,
+	)
+	return i, err
+}
+
+const interruptExpiredWatchRuns = `-- name: InterruptExpiredWatchRuns :execrows
+UPDATE watch_runs r
+SET status = 'interrupted',
+    completed_at = now(),
+    error_message = 'worker stopped before the run completed'
+FROM watches w
+WHERE w.id = r.watch_id
+  AND r.status = 'running'
+  AND (w.lease_expires_at IS NULL OR w.lease_expires_at < now())
+`
+
+// Completes runs whose watch's lease has lapsed: the worker running them
+// stopped renewing it without recording them.
+func (q *Queries) InterruptExpiredWatchRuns(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, interruptExpiredWatchRuns)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const interruptOrphanedWatchRuns = `-- name: InterruptOrphanedWatchRuns :execrows
+UPDATE watch_runs r
+SET status = 'interrupted',
+    completed_at = now(),
+    error_message = 'worker stopped before the run completed'
+FROM watches w
+WHERE w.id = r.watch_id
+  AND r.status = 'running'
+  AND (w.lease_expires_at IS NULL
+       OR w.lease_expires_at < now()
+       OR w.lease_owner = $1::text)
+`
+
+// Completes runs left running by a worker that stopped without recording
+// them: their watch's lease has lapsed, or is still held under this worker's
+// ID by a previous process.
+func (q *Queries) InterruptOrphanedWatchRuns(ctx context.Context, owner string) (int64, error) {
+	result, err := q.db.Exec(ctx, interruptOrphanedWatchRuns, owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const isWatchRunCancelRequested = `-- name: IsWatchRunCancelRequested :one
+SELECT cancel_requested_at IS NOT NULL AS cancel_requested FROM watch_runs
+WHERE id = $1
+`
+
+func (q *Queries) IsWatchRunCancelRequested(ctx context.Context, id pgtype.UUID) (bool, error) {
+	row := q.db.QueryRow(ctx, isWatchRunCancelRequested, id)
+	var cancel_requested bool
+	err := row.Scan(&cancel_requested)
+	return cancel_requested, err
+}
+
+const requestWatchRunCancel = `-- name: RequestWatchRunCancel :execrows
+UPDATE watch_runs
+SET cancel_requested_at = now()
+WHERE id = $1 AND org_id = $2 AND status = 'running'
+`
+
+type RequestWatchRunCancelParams struct {
+	ID    pgtype.UUID `json:"id"`
+	OrgID string      `json:"org_id"`
+}
+
+// Flags a running run for cancellation. The worker running it picks the flag
+// up on its next lease heartbeat.
+func (q *Queries) RequestWatchRunCancel(ctx context.Context, arg RequestWatchRunCancelParams) (int64, error) {
+	result, err := q.db.Exec(ctx, requestWatchRunCancel, arg.ID, arg.OrgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setWatchRunPhase = `-- name: SetWatchRunPhase :exec
+UPDATE watch_runs
+SET phase = $1::text
+WHERE id = $2
+`
+
+type SetWatchRunPhaseParams struct {
+	Phase string      `json:"phase"`
+	ID    pgtype.UUID `json:"id"`
+}
+
+// Records the phase a running run has reached.
+func (q *Queries) SetWatchRunPhase(ctx context.Context, arg SetWatchRunPhaseParams) error {
+	_, err := q.db.Exec(ctx, setWatchRunPhase, arg.Phase, arg.ID)
+	return err
+}
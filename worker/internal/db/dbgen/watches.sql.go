@@ -11,58 +11,132 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const getDueWatches = `-- name: GetDueWatches :many
-SELECT w.id, w.org_id, w.blueprint_id, w.name, w.url, w.schedule, w.identity_fields, w.status, w.next_run_at, w.created_at, w.updated_at, w.deleted_at, b.extraction_rules, b.schema_type
-FROM watches w
-JOIN blueprints b ON b.id = w.blueprint_id
-WHERE w.status = 'active'
-  AND w.deleted_at IS NULL
-  AND w.next_run_at <= now()
-ORDER BY w.next_run_at ASC
-LIMIT 50
+const claimDueWatches = `-- name: ClaimDueWatches :many
+WITH ranked AS (
+  SELECT id, next_run_at,
+         row_number() OVER (PARTITION BY org_id ORDER BY next_run_at) AS org_rank
+  FROM watches
+  WHERE status = 'active'
+    AND deleted_at IS NULL
+    AND next_run_at <= now()
+    AND (lease_expires_at IS NULL OR lease_expires_at < now())
+),
+due AS (
+  SELECT w.id FROM watches w
+  JOIN ranked r ON r.id = w.id
+  WHERE w.next_run_at <= now()
+    AND (w.lease_expires_at IS NULL OR w.lease_expires_at < now())
+  ORDER BY r.org_rank, r.next_run_at
+  LIMIT $1
+  FOR UPDATE OF w SKIP LOCKED
+)
+UPDATE watches w
+SET lease_owner = $2::text,
+    lease_expires_at = now() + make_interval(secs => $3::int)
+FROM due, blueprints b
+WHERE w.id = due.id AND b.id = w.blueprint_id
+RETURNING w.id, w.org_id, w.blueprint_id, w.name, w.url, w.urls, w.url_params, w.schedule, w.timezone, w.jitter_seconds, w.adaptive_schedule, w.adaptive_min_seconds, w.adaptive_max_seconds, w.adaptive_interval_seconds, w.identity_fields, w.identity_fallbacks, w.identity_normalize, w.track_positions, w.rename_threshold, w.diff_policy, w.status, w.next_run_at, w.last_run_at, w.last_error, w.consecutive_failures, w.min_entity_count, w.max_disappearance_ratio, w.accept_next_run, w.grace_missed_runs, w.grace_period_seconds, w.lease_owner, w.lease_expires_at, w.created_at, w.updated_at, w.deleted_at, b.extraction_rules, b.schema_type, b.version AS blueprint_version
 `
 
-type GetDueWatchesRow struct {
-	ID              pgtype.UUID        `json:"id"`
-	OrgID           string             `json:"org_id"`
-	BlueprintID     pgtype.UUID        `json:"blueprint_id"`
-	Name            string             `json:"name"`
-	Url             string             `json:"url"`
-	Schedule        string             `json:"schedule"`
-	IdentityFields  []string           `json:"identity_fields"`
-	Status          string             `json:"status"`
-	NextRunAt       pgtype.Timestamptz `json:"next_run_at"`
-	CreatedAt       pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
-	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
-	ExtractionRules []byte             `json:"extraction_rules"`
-	SchemaType      string             `json:"schema_type"`
-}
-
-func (q *Queries) GetDueWatches(ctx context.Context) ([]GetDueWatchesRow, error) {
-	rows, err := q.db.Query(ctx, getDueWatches)
+type ClaimDueWatchesParams struct {
+	MaxWatches   int32  `json:"max_watches"`
+	Owner        string `json:"owner"`
+	LeaseSeconds int32  `json:"lease_seconds"`
+}
+
+type ClaimDueWatchesRow struct {
+	ID                      pgtype.UUID        `json:"id"`
+	OrgID                   string             `json:"org_id"`
+	BlueprintID             pgtype.UUID        `json:"blueprint_id"`
+	Name                    string             `json:"name"`
+	Url                     string             `json:"url"`
+	Urls                    []string           `json:"urls"`
+	UrlParams               []string           `json:"url_params"`
+	Schedule                string             `json:"schedule"`
+	Timezone                string             `json:"timezone"`
+	JitterSeconds           int32              `json:"jitter_seconds"`
+	AdaptiveSchedule        bool               `json:"adaptive_schedule"`
+	AdaptiveMinSeconds      int32              `json:"adaptive_min_seconds"`
+	AdaptiveMaxSeconds      int32              `json:"adaptive_max_seconds"`
+	AdaptiveIntervalSeconds pgtype.Int4        `json:"adaptive_interval_seconds"`
+	IdentityFields          []string           `json:"identity_fields"`
+	IdentityFallbacks       []byte             `json:"identity_fallbacks"`
+	IdentityNormalize       []string           `json:"identity_normalize"`
+	TrackPositions          bool               `json:"track_positions"`
+	RenameThreshold         float64            `json:"rename_threshold"`
+	DiffPolicy              []byte             `json:"diff_policy"`
+	Status                  string             `json:"status"`
+	NextRunAt               pgtype.Timestamptz `json:"next_run_at"`
+	LastRunAt               pgtype.Timestamptz `json:"last_run_at"`
+	LastError               pgtype.Text        `json:"last_error"`
+	ConsecutiveFailures     int32              `json:"consecutive_failures"`
+	MinEntityCount          int32              `json:"min_entity_count"`
+	MaxDisappearanceRatio   float64            `json:"max_disappearance_ratio"`
+	AcceptNextRun           bool               `json:"accept_next_run"`
+	GraceMissedRuns         int32              `json:"grace_missed_runs"`
+	GracePeriodSeconds      int32              `json:"grace_period_seconds"`
+	LeaseOwner              pgtype.Text        `json:"lease_owner"`
+	LeaseExpiresAt          pgtype.Timestamptz `json:"lease_expires_at"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt               pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt               pgtype.Timestamptz `json:"deleted_at"`
+	ExtractionRules         []byte             `json:"extraction_rules"`
+	SchemaType              string             `json:"schema_type"`
+	BlueprintVersion        int32              `json:"blueprint_version"`
+}
+
+// Leases up to max_watches due watches to one worker. Orgs take turns: each
+// org's most overdue watch comes before any org's second. Rows locked by
+// another worker's claim are skipped, and a watch whose lease has expired
+// (its worker crashed) is due again.
+func (q *Queries) ClaimDueWatches(ctx context.Context, arg ClaimDueWatchesParams) ([]ClaimDueWatchesRow, error) {
+	rows, err := q.db.Query(ctx, claimDueWatches, arg.MaxWatches, arg.Owner, arg.LeaseSeconds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetDueWatchesRow{}
+	items := []ClaimDueWatchesRow{}
 	for rows.Next() {
-		var i GetDueWatchesRow
+		var i ClaimDueWatchesRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.OrgID,
 			&i.BlueprintID,
 			&i.Name,
 			&i.Url,
+			&i.Urls,
+			&i.UrlParams,
 			&i.Schedule,
+			&i.Timezone,
+			&i.JitterSeconds,
+			&i.AdaptiveSchedule,
+			&i.AdaptiveMinSeconds,
+			&i.AdaptiveMaxSeconds,
+			&i.AdaptiveIntervalSeconds,
 			&i.IdentityFields,
+			&i.IdentityFallbacks,
+			&i.IdentityNormalize,
+			&i.TrackPositions,
+			&i.RenameThreshold,
+			&i.DiffPolicy,
 			&i.Status,
 			&i.NextRunAt,
+			&i.LastRunAt,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.MinEntityCount,
+			&i.MaxDisappearanceRatio,
+			&i.AcceptNextRun,
+			&i.GraceMissedRuns,
+			&i.GracePeriodSeconds,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
 			&i.ExtractionRules,
 			&i.SchemaType,
+			&i.BlueprintVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -74,28 +148,215 @@ func (q *Queries) GetDueWatches(ctx context.Context) ([]GetDueWatchesRow, error)
 	return items, nil
 }
 
+const claimWatch = `-- name: ClaimWatch :one
+UPDATE watches w
+SET lease_owner = $1::text,
+    lease_expires_at = now() + make_interval(secs => $2::int)
+FROM blueprints b
+WHERE w.id = $3
+  AND w.org_id = $4
+  AND b.id = w.blueprint_id
+  AND w.deleted_at IS NULL
+  AND (w.lease_expires_at IS NULL OR w.lease_expires_at < now())
+RETURNING w.id, w.org_id, w.blueprint_id, w.name, w.url, w.urls, w.url_params, w.schedule, w.timezone, w.jitter_seconds, w.adaptive_schedule, w.adaptive_min_seconds, w.adaptive_max_seconds, w.adaptive_interval_seconds, w.identity_fields, w.identity_fallbacks, w.identity_normalize, w.track_positions, w.rename_threshold, w.diff_policy, w.status, w.next_run_at, w.last_run_at, w.last_error, w.consecutive_failures, w.min_entity_count, w.max_disappearance_ratio, w.accept_next_run, w.grace_missed_runs, w.grace_period_seconds, w.lease_owner, w.lease_expires_at, w.created_at, w.updated_at, w.deleted_at, b.extraction_rules, b.schema_type, b.version AS blueprint_version
+`
+
+type ClaimWatchParams struct {
+	Owner        string      `json:"owner"`
+	LeaseSeconds int32       `json:"lease_seconds"`
+	ID           pgtype.UUID `json:"id"`
+	OrgID        string      `json:"org_id"`
+}
+
+type ClaimWatchRow struct {
+	ID                      pgtype.UUID        `json:"id"`
+	OrgID                   string             `json:"org_id"`
+	BlueprintID             pgtype.UUID        `json:"blueprint_id"`
+	Name                    string             `json:"name"`
+	Url                     string             `json:"url"`
+	Urls                    []string           `json:"urls"`
+	UrlParams               []string           `json:"url_params"`
+	Schedule                string             `json:"schedule"`
+	Timezone                string             `json:"timezone"`
+	JitterSeconds           int32              `json:"jitter_seconds"`
+	AdaptiveSchedule        bool               `json:"adaptive_schedule"`
+	AdaptiveMinSeconds      int32              `json:"adaptive_min_seconds"`
+	AdaptiveMaxSeconds      int32              `json:"adaptive_max_seconds"`
+	AdaptiveIntervalSeconds pgtype.Int4        `json:"adaptive_interval_seconds"`
+	IdentityFields          []string           `json:"identity_fields"`
+	IdentityFallbacks       []byte             `json:"identity_fallbacks"`
+	IdentityNormalize       []string           `json:"identity_normalize"`
+	TrackPositions          bool               `json:"track_positions"`
+	RenameThreshold         float64            `json:"rename_threshold"`
+	DiffPolicy              []byte             `json:"diff_policy"`
+	Status                  string             `json:"status"`
+	NextRunAt               pgtype.Timestamptz `json:"next_run_at"`
+	LastRunAt               pgtype.Timestamptz `json:"last_run_at"`
+	LastError               pgtype.Text        `json:"last_error"`
+	ConsecutiveFailures     int32              `json:"consecutive_failures"`
+	MinEntityCount          int32              `json:"min_entity_count"`
+	MaxDisappearanceRatio   float64            `json:"max_disappearance_ratio"`
+	AcceptNextRun           bool               `json:"accept_next_run"`
+	GraceMissedRuns         int32              `json:"grace_missed_runs"`
+	GracePeriodSeconds      int32              `json:"grace_period_seconds"`
+	LeaseOwner              pgtype.Text        `json:"lease_owner"`
+	LeaseExpiresAt          pgtype.Timestamptz `json:"lease_expires_at"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt               pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt               pgtype.Timestamptz `json:"deleted_at"`
+	ExtractionRules         []byte             `json:"extraction_rules"`
+	SchemaType              string             `json:"schema_type"`
+	BlueprintVersion        int32              `json:"blueprint_version"`
+}
+
+// Leases a single watch of an org for a manual run. Returns no rows when the
+// org has no such watch or another run holds its lease.
+func (q *Queries) ClaimWatch(ctx context.Context, arg ClaimWatchParams) (ClaimWatchRow, error) {
+	row := q.db.QueryRow(ctx, claimWatch,
+		arg.Owner,
+		arg.LeaseSeconds,
+		arg.ID,
+		arg.OrgID,
+	)
+	var i ClaimWatchRow
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.BlueprintID,
+		&i.Name,
+		&i.Url,
+		&i.Urls,
+		&i.UrlParams,
+		&i.Schedule,
+		&i.Timezone,
+		&i.JitterSeconds,
+		&i.AdaptiveSchedule,
+		&i.AdaptiveMinSeconds,
+		&i.AdaptiveMaxSeconds,
+		&i.AdaptiveIntervalSeconds,
+		&i.IdentityFields,
+		&i.IdentityFallbacks,
+		&i.IdentityNormalize,
+		&i.TrackPositions,
+		&i.RenameThreshold,
+		&i.DiffPolicy,
+		&i.Status,
+		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.MinEntityCount,
+		&i.MaxDisappearanceRatio,
+		&i.AcceptNextRun,
+		&i.GraceMissedRuns,
+		&i.GracePeriodSeconds,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ExtractionRules,
+		&i.SchemaType,
+		&i.BlueprintVersion,
+	)
+	return i, err
+}
+
+const clearAcceptNextRun = `-- name: ClearAcceptNextRun :exec
+UPDATE watches
+SET accept_next_run = false
+WHERE id = $1
+`
+
+// Clears a watch's accept_next_run once a run has applied its results.
+func (q *Queries) ClearAcceptNextRun(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, clearAcceptNextRun, id)
+	return err
+}
+
+const countDueWatches = `-- name: CountDueWatches :one
+SELECT count(*) FROM watches
+WHERE status = 'active'
+  AND deleted_at IS NULL
+  AND next_run_at <= now()
+  AND (lease_expires_at IS NULL OR lease_expires_at < now())
+`
+
+// Due watches no worker has claimed yet: the scheduler's queue depth.
+func (q *Queries) CountDueWatches(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countDueWatches)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const extendWatchLease = `-- name: ExtendWatchLease :execrows
+UPDATE watches
+SET lease_expires_at = now() + make_interval(secs => $1::int)
+WHERE id = $2 AND lease_owner = $3::text
+`
+
+type ExtendWatchLeaseParams struct {
+	LeaseSeconds int32       `json:"lease_seconds"`
+	ID           pgtype.UUID `json:"id"`
+	Owner        string      `json:"owner"`
+}
+
+// Heartbeat for a running watch. Affects no rows once the lease was lost.
+func (q *Queries) ExtendWatchLease(ctx context.Context, arg ExtendWatchLeaseParams) (int64, error) {
+	result, err := q.db.Exec(ctx, extendWatchLease, arg.LeaseSeconds, arg.ID, arg.Owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getWatchByID = `-- name: GetWatchByID :one
-SELECT w.id, w.org_id, w.blueprint_id, w.name, w.url, w.schedule, w.identity_fields, w.status, w.next_run_at, w.created_at, w.updated_at, w.deleted_at, b.extraction_rules, b.schema_type
+SELECT w.id, w.org_id, w.blueprint_id, w.name, w.url, w.urls, w.url_params, w.schedule, w.timezone, w.jitter_seconds, w.adaptive_schedule, w.adaptive_min_seconds, w.adaptive_max_seconds, w.adaptive_interval_seconds, w.identity_fields, w.identity_fallbacks, w.identity_normalize, w.track_positions, w.rename_threshold, w.diff_policy, w.status, w.next_run_at, w.last_run_at, w.last_error, w.consecutive_failures, w.min_entity_count, w.max_disappearance_ratio, w.accept_next_run, w.grace_missed_runs, w.grace_period_seconds, w.lease_owner, w.lease_expires_at, w.created_at, w.updated_at, w.deleted_at, b.extraction_rules, b.schema_type, b.version AS blueprint_version
 FROM watches w
 JOIN blueprints b ON b.id = w.blueprint_id
 WHERE w.id = $1 AND w.deleted_at IS NULL
 `
 
 type GetWatchByIDRow struct {
-	ID              pgtype.UUID        `json:"id"`
-	OrgID           string             `json:"org_id"`
-	BlueprintID     pgtype.UUID        `json:"blueprint_id"`
-	Name            string             `json:"name"`
-	Url             string             `json:"url"`
-	Schedule        string             `json:"schedule"`
-	IdentityFields  []string           `json:"identity_fields"`
-	Status          string             `json:"status"`
-	NextRunAt       pgtype.Timestamptz `json:"next_run_at"`
-	CreatedAt       pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
-	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
-	ExtractionRules []byte             `json:"extraction_rules"`
-	SchemaType      string             `json:"schema_type"`
+	ID                      pgtype.UUID        `json:"id"`
+	OrgID                   string             `json:"org_id"`
+	BlueprintID             pgtype.UUID        `json:"blueprint_id"`
+	Name                    string             `json:"name"`
+	Url                     string             `json:"url"`
+	Urls                    []string           `json:"urls"`
+	UrlParams               []string           `json:"url_params"`
+	Schedule                string             `json:"schedule"`
+	Timezone                string             `json:"timezone"`
+	JitterSeconds           int32              `json:"jitter_seconds"`
+	AdaptiveSchedule        bool               `json:"adaptive_schedule"`
+	AdaptiveMinSeconds      int32              `json:"adaptive_min_seconds"`
+	AdaptiveMaxSeconds      int32              `json:"adaptive_max_seconds"`
+	AdaptiveIntervalSeconds pgtype.Int4        `json:"adaptive_interval_seconds"`
+	IdentityFields          []string           `json:"identity_fields"`
+	IdentityFallbacks       []byte             `json:"identity_fallbacks"`
+	IdentityNormalize       []string           `json:"identity_normalize"`
+	TrackPositions          bool               `json:"track_positions"`
+	RenameThreshold         float64            `json:"rename_threshold"`
+	DiffPolicy              []byte             `json:"diff_policy"`
+	Status                  string             `json:"status"`
+	NextRunAt               pgtype.Timestamptz `json:"next_run_at"`
+	LastRunAt               pgtype.Timestamptz `json:"last_run_at"`
+	LastError               pgtype.Text        `json:"last_error"`
+	ConsecutiveFailures     int32              `json:"consecutive_failures"`
+	MinEntityCount          int32              `json:"min_entity_count"`
+	MaxDisappearanceRatio   float64            `json:"max_disappearance_ratio"`
+	AcceptNextRun           bool               `json:"accept_next_run"`
+	GraceMissedRuns         int32              `json:"grace_missed_runs"`
+	GracePeriodSeconds      int32              `json:"grace_period_seconds"`
+	LeaseOwner              pgtype.Text        `json:"lease_owner"`
+	LeaseExpiresAt          pgtype.Timestamptz `json:"lease_expires_at"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt               pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt               pgtype.Timestamptz `json:"deleted_at"`
+	ExtractionRules         []byte             `json:"extraction_rules"`
+	SchemaType              string             `json:"schema_type"`
+	BlueprintVersion        int32              `json:"blueprint_version"`
 }
 
 func (q *Queries) GetWatchByID(ctx context.Context, id pgtype.UUID) (GetWatchByIDRow, error) {
@@ -107,32 +368,177 @@ func (q *Queries) GetWatchByID(ctx context.Context, id pgtype.UUID) (GetWatchByI
 		&i.BlueprintID,
 		&i.Name,
 		&i.Url,
+		&i.Urls,
+		&i.UrlParams,
 		&i.Schedule,
+		&i.Timezone,
+		&i.JitterSeconds,
+		&i.AdaptiveSchedule,
+		&i.AdaptiveMinSeconds,
+		&i.AdaptiveMaxSeconds,
+		&i.AdaptiveIntervalSeconds,
 		&i.IdentityFields,
+		&i.IdentityFallbacks,
+		&i.IdentityNormalize,
+		&i.TrackPositions,
+		&i.RenameThreshold,
+		&i.DiffPolicy,
 		&i.Status,
 		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.MinEntityCount,
+		&i.MaxDisappearanceRatio,
+		&i.AcceptNextRun,
+		&i.GraceMissedRuns,
+		&i.GracePeriodSeconds,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.ExtractionRules,
 		&i.SchemaType,
+		&i.BlueprintVersion,
 	)
 	return i, err
 }
 
-const updateWatchAfterRun = `-- name: UpdateWatchAfterRun :exec
+const recordWatchCancelled = `-- name: RecordWatchCancelled :exec
 UPDATE watches
-SET next_run_at = $2,
+SET next_run_at = $1,
+    last_run_at = now(),
+    lease_owner = NULL,
+    lease_expires_at = NULL,
     updated_at = now()
-WHERE id = $1
+WHERE id = $2 AND lease_owner = $3::text
 `
 
-type UpdateWatchAfterRunParams struct {
-	ID        pgtype.UUID        `json:"id"`
+type RecordWatchCancelledParams struct {
 	NextRunAt pgtype.Timestamptz `json:"next_run_at"`
+	ID        pgtype.UUID        `json:"id"`
+	Owner     string             `json:"owner"`
 }
 
-func (q *Queries) UpdateWatchAfterRun(ctx context.Context, arg UpdateWatchAfterRunParams) error {
-	_, err := q.db.Exec(ctx, updateWatchAfterRun, arg.ID, arg.NextRunAt)
+// Schedules the next run after a cancelled or interrupted run without
+// touching failure tracking.
+func (q *Queries) RecordWatchCancelled(ctx context.Context, arg RecordWatchCancelledParams) error {
+	_, err := q.db.Exec(ctx, recordWatchCancelled, arg.NextRunAt, arg.ID, arg.Owner)
 	return err
 }
+
+const recordWatchFailure = `-- name: RecordWatchFailure :one
+UPDATE watches
+SET next_run_at = $1,
+    last_run_at = now(),
+    last_error = $2,
+    consecutive_failures = consecutive_failures + 1,
+    status = CASE
+      WHEN status = 'active' AND consecutive_failures + 1 >= $3::int THEN 'error'
+      ELSE status
+    END,
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = now()
+WHERE id = $4 AND lease_owner = $5::text
+RETURNING consecutive_failures, status
+`
+
+type RecordWatchFailureParams struct {
+	NextRunAt   pgtype.Timestamptz `json:"next_run_at"`
+	LastError   pgtype.Text        `json:"last_error"`
+	MaxFailures int32              `json:"max_failures"`
+	ID          pgtype.UUID        `json:"id"`
+	Owner       string             `json:"owner"`
+}
+
+type RecordWatchFailureRow struct {
+	ConsecutiveFailures int32  `json:"consecutive_failures"`
+	Status              string `json:"status"`
+}
+
+// Counts a failed run. An active watch moves to error once it reaches
+// max_failures consecutive failures. Returns no rows when owner no longer
+// holds the watch's lease.
+func (q *Queries) RecordWatchFailure(ctx context.Context, arg RecordWatchFailureParams) (RecordWatchFailureRow, error) {
+	row := q.db.QueryRow(ctx, recordWatchFailure,
+		arg.NextRunAt,
+		arg.LastError,
+		arg.MaxFailures,
+		arg.ID,
+		arg.Owner,
+	)
+	var i RecordWatchFailureRow
+	err := row.Scan(&i.ConsecutiveFailures, &i.Status)
+	return i, err
+}
+
+const recordWatchSuccess = `-- name: RecordWatchSuccess :exec
+UPDATE watches
+SET next_run_at = $1,
+    adaptive_interval_seconds = $2,
+    last_run_at = now(),
+    last_error = NULL,
+    consecutive_failures = 0,
+    status = CASE WHEN status = 'error' THEN 'active' ELSE status END,
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = now()
+WHERE id = $3 AND lease_owner = $4::text
+`
+
+type RecordWatchSuccessParams struct {
+	NextRunAt               pgtype.Timestamptz `json:"next_run_at"`
+	AdaptiveIntervalSeconds pgtype.Int4        `json:"adaptive_interval_seconds"`
+	ID                      pgtype.UUID        `json:"id"`
+	Owner                   string             `json:"owner"`
+}
+
+// Resets failure tracking after a successful run. A watch in error recovers
+// to active. adaptive_interval_seconds is the interval adaptive scheduling
+// settled on, or NULL. Like the other Record queries, it only applies while
+// owner still holds the watch's lease.
+func (q *Queries) RecordWatchSuccess(ctx context.Context, arg RecordWatchSuccessParams) error {
+	_, err := q.db.Exec(ctx, recordWatchSuccess,
+		arg.NextRunAt,
+		arg.AdaptiveIntervalSeconds,
+		arg.ID,
+		arg.Owner,
+	)
+	return err
+}
+
+const releaseWatchLease = `-- name: ReleaseWatchLease :exec
+UPDATE watches
+SET lease_owner = NULL,
+    lease_expires_at = NULL
+WHERE id = $1 AND lease_owner = $2::text
+`
+
+type ReleaseWatchLeaseParams struct {
+	ID    pgtype.UUID `json:"id"`
+	Owner string      `json:"owner"`
+}
+
+// Drops a worker's lease on one watch without recording a run.
+func (q *Queries) ReleaseWatchLease(ctx context.Context, arg ReleaseWatchLeaseParams) error {
+	_, err := q.db.Exec(ctx, releaseWatchLease, arg.ID, arg.Owner)
+	return err
+}
+
+const releaseWatchLeases = `-- name: ReleaseWatchLeases :execrows
+UPDATE watches
+SET lease_owner = NULL,
+    lease_expires_at = NULL
+WHERE lease_owner = $1::text
+`
+
+// Drops the leases still held under a worker ID by a previous process.
+func (q *Queries) ReleaseWatchLeases(ctx context.Context, owner string) (int64, error) {
+	result, err := q.db.Exec(ctx, releaseWatchLeases, owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,44 @@
+-- name: GetBlueprintByID :one
+SELECT * FROM blueprints
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL;
+
+-- name: UpdateBlueprintRules :one
+UPDATE blueprints
+SET extraction_rules = $3,
+    version = version + 1,
+    updated_at = now()
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: EnsureBlueprintRevision :one
+-- Snapshots the blueprint's current version into blueprint_revisions (if it
+-- isn't there yet) and returns the revision ID.
+WITH inserted AS (
+  INSERT INTO blueprint_revisions (org_id, blueprint_id, version, schema_type, extraction_rules)
+  SELECT b.org_id, b.id, b.version, b.schema_type, b.extraction_rules
+  FROM blueprints b
+  WHERE b.id = sqlc.arg(blueprint_id)
+  ON CONFLICT (blueprint_id, version) DO NOTHING
+  RETURNING blueprint_revisions.id
+)
+SELECT inserted.id FROM inserted
+UNION ALL
+SELECT r.id
+FROM blueprint_revisions r
+JOIN blueprints b ON b.id = r.blueprint_id AND b.version = r.version
+WHERE r.blueprint_id = sqlc.arg(blueprint_id)
+LIMIT 1;
+
+-- name: GetBlueprintRevision :one
+SELECT * FROM blueprint_revisions
+WHERE blueprint_id = $1 AND org_id = $2 AND version = $3;
+
+-- name: ListBlueprintRevisions :many
+SELECT * FROM blueprint_revisions
+WHERE blueprint_id = $1 AND org_id = $2
+ORDER BY version DESC;
+
+-- name: GetRunBlueprintRevision :one
+SELECT r.* FROM blueprint_revisions r
+JOIN watch_runs wr ON wr.blueprint_revision_id = r.id
+WHERE wr.id = $1 AND wr.org_id = $2;
@@ -1,40 +1,78 @@
--- name: InsertDelivery :one
-INSERT INTO deliveries (org_id, event_id, subscription_id, status, attempts, max_attempts, next_retry_at)
-VALUES ($1, $2, $3, 'pending', 0, 5, now())
-RETURNING *;
-
--- name: GetPendingDeliveries :many
-SELECT
+-- name: ClaimPendingDeliveries :many
+-- Claims up to 50 due deliveries for one worker by pushing next_retry_at
+-- out by the lease. Rows locked by another worker's claim are skipped, and a
+-- delivery whose worker crashed becomes due again when the lease runs out.
+WITH due AS (
+  SELECT id FROM deliveries
+  WHERE status = 'pending'
+    AND next_retry_at <= now()
+  ORDER BY next_retry_at ASC
+  LIMIT 50
+  FOR UPDATE SKIP LOCKED
+)
+UPDATE deliveries d
+SET next_retry_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::int),
+    claimed_by = sqlc.arg(owner)::text
+FROM due, subscriptions s, events e
+WHERE d.id = due.id
+  AND s.id = d.subscription_id
+  AND e.id = d.event_id
+RETURNING
   d.id, d.org_id, d.event_id, d.subscription_id, d.status,
   d.attempts, d.max_attempts, d.next_retry_at, d.last_error,
   d.delivered_at, d.created_at,
   s.name AS subscription_name, s.channel_type, s.channel_config,
-  e.event_type, e.payload AS event_payload
-FROM deliveries d
-JOIN subscriptions s ON s.id = d.subscription_id
-JOIN events e ON e.id = d.event_id
-WHERE d.status = 'pending'
-  AND d.next_retry_at <= now()
-ORDER BY d.next_retry_at ASC
-LIMIT 50;
+  e.event_type, e.payload AS event_payload;
 
--- name: MarkDeliveryDelivered :exec
+-- name: ExtendDeliveryClaims :execrows
+-- Heartbeat for claimed deliveries, so that a slow send isn't claimed and
+-- sent again by another worker. Skips those settled or no longer held.
+UPDATE deliveries
+SET next_retry_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+  AND claimed_by = sqlc.arg(owner)::text
+  AND status = 'pending';
+
+-- name: MarkDeliveryDelivered :execrows
+-- Affects no rows once the worker no longer holds the delivery's claim.
 UPDATE deliveries
 SET status = 'delivered',
     delivered_at = now(),
-    attempts = attempts + 1
-WHERE id = $1;
+    attempts = attempts + 1,
+    claimed_by = NULL
+WHERE id = sqlc.arg(id) AND claimed_by = sqlc.arg(owner)::text;
 
--- name: MarkDeliveryRetry :exec
+-- name: MarkDeliveryRetry :execrows
+-- Affects no rows once the worker no longer holds the delivery's claim.
 UPDATE deliveries
 SET attempts = attempts + 1,
-    next_retry_at = $2,
-    last_error = $3
-WHERE id = $1;
+    next_retry_at = sqlc.arg(next_retry_at),
+    last_error = sqlc.arg(last_error),
+    claimed_by = NULL
+WHERE id = sqlc.arg(id) AND claimed_by = sqlc.arg(owner)::text;
 
--- name: MarkDeliveryFailed :exec
+-- name: MarkDeliveryFailed :execrows
+-- Affects no rows once the worker no longer holds the delivery's claim.
 UPDATE deliveries
 SET status = 'failed',
     attempts = attempts + 1,
-    last_error = $2
-WHERE id = $1;
+    last_error = sqlc.arg(last_error),
+    claimed_by = NULL
+WHERE id = sqlc.arg(id) AND claimed_by = sqlc.arg(owner)::text;
+
+-- name: InsertDeliveries :execrows
+-- Inserts a batch of pending deliveries. event_ids and subscription_ids are
+-- parallel arrays.
+INSERT INTO deliveries (org_id, event_id, subscription_id, status, attempts, max_attempts, next_retry_at)
+SELECT sqlc.arg(org_id)::text, u.event_id, u.subscription_id, 'pending', 0, 5, now()
+FROM unnest(sqlc.arg(event_ids)::uuid[], sqlc.arg(subscription_ids)::uuid[]) AS u(event_id, subscription_id);
+
+-- name: ReleaseDeliveries :exec
+-- Makes claimed deliveries due again at once, for a worker that is shutting
+-- down before sending them.
+UPDATE deliveries
+SET next_retry_at = now(),
+    claimed_by = NULL
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+  AND claimed_by = sqlc.arg(owner)::text
+  AND status = 'pending';
@@ -3,25 +3,79 @@ SELECT * FROM entities
 WHERE watch_id = $1 AND status = 'active'
 ORDER BY external_id;
 
--- name: UpsertEntity :one
-INSERT INTO entities (org_id, watch_id, schema_type, external_id, content, url, status, first_seen_at, last_seen_at)
-VALUES ($1, $2, $3, $4, $5, $6, 'active', now(), now())
-ON CONFLICT (org_id, watch_id, schema_type, external_id) DO UPDATE
-SET content = EXCLUDED.content,
-    url = EXCLUDED.url,
-    status = 'active',
-    last_seen_at = now(),
-    updated_at = now()
-RETURNING *;
+-- name: GetEntity :one
+SELECT * FROM entities
+WHERE id = $1 AND org_id = $2;
 
 -- name: TouchEntitiesLastSeen :exec
 UPDATE entities
-SET last_seen_at = now(), updated_at = now()
+SET last_seen_at = now(), missed_runs = 0, updated_at = now()
+WHERE watch_id = $1 AND external_id = ANY($2::text[])
+  AND status = 'active';
+
+-- name: MarkEntitiesMissed :exec
+-- Counts a missed run for active entities that are still within their
+-- watch's disappearance grace window.
+UPDATE entities
+SET missed_runs = missed_runs + 1, updated_at = now()
 WHERE watch_id = $1 AND external_id = ANY($2::text[])
   AND status = 'active';
 
 -- name: MarkEntitiesStale :exec
 UPDATE entities
-SET status = 'stale', updated_at = now()
+SET status = 'stale', missed_runs = 0, updated_at = now()
 WHERE watch_id = $1 AND external_id = ANY($2::text[])
   AND status = 'active';
+
+-- name: MigrateEntityContent :exec
+UPDATE entities
+SET content = $2,
+    schema_version = $3,
+    blueprint_version = $4,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: RenameEntity :execrows
+-- Moves an active entity to the new external ID it got when its identity
+-- fields changed, unless an entity with that ID already exists.
+UPDATE entities e
+SET external_id = sqlc.arg(new_external_id), updated_at = now()
+WHERE e.watch_id = sqlc.arg(watch_id) AND e.external_id = sqlc.arg(old_external_id)
+  AND e.status = 'active'
+  AND NOT EXISTS (
+    SELECT 1 FROM entities o
+    WHERE o.org_id = e.org_id AND o.watch_id = e.watch_id
+      AND o.schema_type = e.schema_type AND o.external_id = sqlc.arg(new_external_id)
+  );
+
+-- name: UpsertEntities :many
+-- Upserts a batch of entities of one watch in a single statement.
+-- external_ids, contents, source_urls and source_params are parallel
+-- arrays; an empty source_param is stored as NULL.
+INSERT INTO entities (org_id, watch_id, schema_type, external_id, content, source_url, source_param, status, schema_version, blueprint_version, first_seen_at, last_seen_at)
+SELECT sqlc.arg(org_id)::text, sqlc.arg(watch_id)::uuid, sqlc.arg(schema_type)::text,
+       u.external_id, u.content::jsonb, u.source_url, NULLIF(u.source_param, ''), 'active',
+       sqlc.arg(schema_version)::int, sqlc.arg(blueprint_version)::int, now(), now()
+FROM unnest(sqlc.arg(external_ids)::text[], sqlc.arg(contents)::text[], sqlc.arg(source_urls)::text[], sqlc.arg(source_params)::text[])
+    AS u(external_id, content, source_url, source_param)
+ON CONFLICT (org_id, watch_id, schema_type, external_id) DO UPDATE
+SET content = EXCLUDED.content,
+    source_url = EXCLUDED.source_url,
+    source_param = EXCLUDED.source_param,
+    status = 'active',
+    schema_version = EXCLUDED.schema_version,
+    blueprint_version = EXCLUDED.blueprint_version,
+    last_seen_at = now(),
+    missed_runs = 0,
+    updated_at = now()
+RETURNING id, external_id;
+
+-- name: UpdateEntitySources :exec
+-- Records the page an active entity was last found on, for entities that
+-- moved between the pages of a multi-URL watch without changing.
+UPDATE entities e
+SET source_url = u.source_url, source_param = NULLIF(u.source_param, ''), updated_at = now()
+FROM unnest(sqlc.arg(external_ids)::text[], sqlc.arg(source_urls)::text[], sqlc.arg(source_params)::text[])
+    AS u(external_id, source_url, source_param)
+WHERE e.watch_id = sqlc.arg(watch_id)::uuid AND e.external_id = u.external_id
+  AND e.status = 'active';
@@ -0,0 +1,25 @@
+-- name: GetLatestEntitySchema :one
+SELECT * FROM entity_schemas
+WHERE org_id = $1 AND schema_type = $2 AND deleted_at IS NULL
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: ListEntitySchemas :many
+SELECT DISTINCT ON (schema_type) *
+FROM entity_schemas
+WHERE org_id = $1 AND deleted_at IS NULL
+ORDER BY schema_type, version DESC;
+
+-- name: InsertEntitySchema :one
+INSERT INTO entity_schemas (org_id, schema_type, version, description, fields, identity_fields)
+VALUES (
+  $1, $2,
+  COALESCE((SELECT MAX(version) FROM entity_schemas WHERE org_id = $1 AND schema_type = $2), 0) + 1,
+  $3, $4, $5
+)
+RETURNING *;
+
+-- name: DeleteEntitySchema :exec
+UPDATE entity_schemas
+SET deleted_at = now()
+WHERE org_id = $1 AND schema_type = $2 AND deleted_at IS NULL;
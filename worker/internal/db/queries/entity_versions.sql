@@ -0,0 +1,46 @@
+-- name: InsertEntityVersions :exec
+-- Appends a version for each entity that appeared, changed or disappeared in
+-- a run. entity_ids, external_ids, change_types and contents are parallel
+-- arrays; an empty content, for a disappeared entity, is stored as NULL.
+INSERT INTO entity_versions (org_id, watch_id, watch_run_id, entity_id, external_id, change_type, content)
+SELECT sqlc.arg(org_id)::text, sqlc.arg(watch_id)::uuid, sqlc.arg(watch_run_id)::uuid,
+       u.entity_id, u.external_id, u.change_type, NULLIF(u.content, '')::jsonb
+FROM unnest(sqlc.arg(entity_ids)::uuid[], sqlc.arg(external_ids)::text[], sqlc.arg(change_types)::text[], sqlc.arg(contents)::text[])
+    AS u(entity_id, external_id, change_type, content);
+
+-- name: ListEntityVersions :many
+SELECT * FROM entity_versions
+WHERE entity_id = $1 AND org_id = $2
+ORDER BY created_at;
+
+-- name: ListWatchEntitiesAsOf :many
+-- The latest version of each entity of a watch recorded at or before as_of,
+-- leaving out entities that had disappeared by then. Active entities with no
+-- versions yet are taken from their stored content since first_seen_at.
+SELECT v.entity_id, v.external_id, v.content, v.created_at
+FROM (
+    SELECT DISTINCT ON (entity_id) entity_id, external_id, change_type, content, created_at
+    FROM entity_versions
+    WHERE watch_id = sqlc.arg(watch_id) AND org_id = sqlc.arg(org_id)
+      AND created_at <= sqlc.arg(as_of)::timestamptz
+    ORDER BY entity_id, created_at DESC
+) v
+WHERE v.change_type <> 'disappeared'
+UNION ALL
+SELECT e.id, e.external_id, e.content, e.first_seen_at
+FROM entities e
+WHERE e.watch_id = sqlc.arg(watch_id) AND e.org_id = sqlc.arg(org_id)
+  AND e.status = 'active'
+  AND e.first_seen_at <= sqlc.arg(as_of)::timestamptz
+  AND NOT EXISTS (SELECT 1 FROM entity_versions ev WHERE ev.entity_id = e.id)
+ORDER BY external_id;
+
+-- name: SeedEntityVersions :exec
+-- Records an appeared version, dated first_seen_at, for each active entity of
+-- a watch that has none yet, so history covers entities stored before
+-- versions were kept.
+INSERT INTO entity_versions (org_id, watch_id, watch_run_id, entity_id, external_id, change_type, content, created_at)
+SELECT e.org_id, e.watch_id, sqlc.arg(watch_run_id)::uuid, e.id, e.external_id, 'appeared', e.content, e.first_seen_at
+FROM entities e
+WHERE e.watch_id = sqlc.arg(watch_id) AND e.status = 'active'
+  AND NOT EXISTS (SELECT 1 FROM entity_versions v WHERE v.entity_id = e.id);
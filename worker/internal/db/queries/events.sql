@@ -1,8 +1,3 @@
--- name: InsertEvent :one
-INSERT INTO events (org_id, event_type, watch_id, watch_run_id, entity_id, payload, occurred_at)
-VALUES ($1, $2, $3, $4, $5, $6, now())
-RETURNING *;
-
 -- name: GetEventsByWatch :many
 SELECT * FROM events
 WHERE org_id = $1 AND watch_id = $2
@@ -14,3 +9,13 @@ SELECT * FROM events
 WHERE org_id = $1
 ORDER BY occurred_at DESC
 LIMIT $2 OFFSET $3;
+
+-- name: InsertEvents :many
+-- Inserts a batch of events of one watch run. event_types, entity_ids and
+-- payloads are parallel arrays.
+INSERT INTO events (org_id, event_type, watch_id, watch_run_id, entity_id, payload, occurred_at)
+SELECT sqlc.arg(org_id)::text, u.event_type, sqlc.arg(watch_id)::uuid, sqlc.arg(watch_run_id)::uuid,
+       u.entity_id, u.payload::jsonb, now()
+FROM unnest(sqlc.arg(event_types)::text[], sqlc.arg(entity_ids)::uuid[], sqlc.arg(payloads)::text[])
+  AS u(event_type, entity_id, payload)
+RETURNING *;
@@ -1,8 +1,9 @@
--- name: MatchSubscriptions :many
+-- name: ListWatchSubscriptions :many
+-- Returns every active subscription that may match events of a watch,
+-- regardless of event type, so matching can be done once per run.
 SELECT * FROM subscriptions
 WHERE org_id = $1
   AND status = 'active'
   AND deleted_at IS NULL
-  AND $2::text = ANY(event_types)
-  AND (watch_id IS NULL OR watch_id = $3)
+  AND (watch_id IS NULL OR watch_id = $2)
 ORDER BY created_at ASC;
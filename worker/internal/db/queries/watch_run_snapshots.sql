@@ -0,0 +1,39 @@
+-- name: InsertWatchRunSnapshot :one
+INSERT INTO watch_run_snapshots (org_id, watch_id, watch_run_id, url, raw_html_key, cleaned_html_key, raw_size, cleaned_size)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetWatchRunSnapshot :one
+SELECT * FROM watch_run_snapshots
+WHERE id = $1 AND org_id = $2;
+
+-- name: ListWatchRunSnapshots :many
+SELECT * FROM watch_run_snapshots
+WHERE watch_run_id = $1 AND org_id = $2
+ORDER BY created_at;
+
+-- name: ListWatchSnapshots :many
+-- Returns every snapshot of the most recent runs of a watch that took
+-- snapshots in [since, until), newest first. Runs are counted, not pages, so
+-- no run is cut short.
+SELECT * FROM watch_run_snapshots
+WHERE watch_run_id IN (
+    SELECT s.watch_run_id FROM watch_run_snapshots s
+    WHERE s.watch_id = sqlc.arg(watch_id) AND s.org_id = sqlc.arg(org_id)
+      AND s.created_at >= sqlc.arg(since) AND s.created_at < sqlc.arg(until)
+    GROUP BY s.watch_run_id
+    ORDER BY max(s.created_at) DESC
+    LIMIT sqlc.arg(max_runs)
+  )
+  AND org_id = sqlc.arg(org_id)
+ORDER BY created_at DESC;
+
+-- name: ListExpiredWatchRunSnapshots :many
+SELECT * FROM watch_run_snapshots
+WHERE created_at < $1
+ORDER BY created_at
+LIMIT $2;
+
+-- name: DeleteWatchRunSnapshot :exec
+DELETE FROM watch_run_snapshots
+WHERE id = $1;
@@ -1,6 +1,6 @@
 -- name: CreateWatchRun :one
-INSERT INTO watch_runs (org_id, watch_id, status, started_at)
-VALUES ($1, $2, 'running', now())
+INSERT INTO watch_runs (org_id, watch_id, blueprint_revision_id, status, started_at)
+VALUES ($1, $2, $3, 'running', now())
 RETURNING *;
 
 -- name: CompleteWatchRun :exec
@@ -12,5 +12,73 @@ SET status = $2,
     entities_changed = $5,
     entities_removed = $6,
     events_emitted = $7,
-    error_message = $8
+    error_message = $8,
+    identity_collisions = $9,
+    identity_missing = $10,
+    page_errors = $11
 WHERE id = $1;
+
+-- name: GetPreviousWatchRunStatus :one
+-- Returns the status of the most recent finished run of a watch other than
+-- the given one.
+SELECT status FROM watch_runs
+WHERE watch_id = $1 AND id <> $2 AND status <> 'running'
+ORDER BY started_at DESC
+LIMIT 1;
+
+-- name: GetRecentRunChanges :many
+-- Returns how many entities appeared, changed or disappeared in each of the
+-- latest completed runs of a watch, newest first.
+SELECT (COALESCE(entities_new, 0) + COALESCE(entities_changed, 0) + COALESCE(entities_removed, 0))::int AS changes
+FROM watch_runs
+WHERE watch_id = $1 AND status = 'completed'
+ORDER BY started_at DESC
+LIMIT $2;
+
+-- name: GetWatchRun :one
+SELECT * FROM watch_runs
+WHERE id = $1 AND org_id = $2;
+
+-- name: RequestWatchRunCancel :execrows
+-- Flags a running run for cancellation. The worker running it picks the flag
+-- up on its next lease heartbeat.
+UPDATE watch_runs
+SET cancel_requested_at = now()
+WHERE id = $1 AND org_id = $2 AND status = 'running';
+
+-- name: IsWatchRunCancelRequested :one
+SELECT cancel_requested_at IS NOT NULL AS cancel_requested FROM watch_runs
+WHERE id = $1;
+
+-- name: InterruptOrphanedWatchRuns :execrows
+-- Completes runs left running by a worker that stopped without recording
+-- them: their watch's lease has lapsed, or is still held under this worker's
+-- ID by a previous process.
+UPDATE watch_runs r
+SET status = 'interrupted',
+    completed_at = now(),
+    error_message = 'worker stopped before the run completed'
+FROM watches w
+WHERE w.id = r.watch_id
+  AND r.status = 'running'
+  AND (w.lease_expires_at IS NULL
+       OR w.lease_expires_at < now()
+       OR w.lease_owner = sqlc.arg(owner)::text);
+
+-- name: InterruptExpiredWatchRuns :execrows
+-- Completes runs whose watch's lease has lapsed: the worker running them
+-- stopped renewing it without recording them.
+UPDATE watch_runs r
+SET status = 'interrupted',
+    completed_at = now(),
+    error_message = 'worker stopped before the run completed'
+FROM watches w
+WHERE w.id = r.watch_id
+  AND r.status = 'running'
+  AND (w.lease_expires_at IS NULL OR w.lease_expires_at < now());
+
+-- name: SetWatchRunPhase :exec
+-- Records the phase a running run has reached.
+UPDATE watch_runs
+SET phase = sqlc.arg(phase)::text
+WHERE id = sqlc.arg(id);
@@ -1,21 +1,130 @@
--- name: GetDueWatches :many
-SELECT w.*, b.extraction_rules, b.schema_type
-FROM watches w
-JOIN blueprints b ON b.id = w.blueprint_id
-WHERE w.status = 'active'
+-- name: ClaimDueWatches :many
+-- Leases up to max_watches due watches to one worker. Orgs take turns: each
+-- org's most overdue watch comes before any org's second. Rows locked by
+-- another worker's claim are skipped, and a watch whose lease has expired
+-- (its worker crashed) is due again.
+WITH ranked AS (
+  SELECT id, next_run_at,
+         row_number() OVER (PARTITION BY org_id ORDER BY next_run_at) AS org_rank
+  FROM watches
+  WHERE status = 'active'
+    AND deleted_at IS NULL
+    AND next_run_at <= now()
+    AND (lease_expires_at IS NULL OR lease_expires_at < now())
+),
+due AS (
+  SELECT w.id FROM watches w
+  JOIN ranked r ON r.id = w.id
+  WHERE w.next_run_at <= now()
+    AND (w.lease_expires_at IS NULL OR w.lease_expires_at < now())
+  ORDER BY r.org_rank, r.next_run_at
+  LIMIT sqlc.arg(max_watches)
+  FOR UPDATE OF w SKIP LOCKED
+)
+UPDATE watches w
+SET lease_owner = sqlc.arg(owner)::text,
+    lease_expires_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+FROM due, blueprints b
+WHERE w.id = due.id AND b.id = w.blueprint_id
+RETURNING w.*, b.extraction_rules, b.schema_type, b.version AS blueprint_version;
+
+-- name: ClaimWatch :one
+-- Leases a single watch of an org for a manual run. Returns no rows when the
+-- org has no such watch or another run holds its lease.
+UPDATE watches w
+SET lease_owner = sqlc.arg(owner)::text,
+    lease_expires_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+FROM blueprints b
+WHERE w.id = sqlc.arg(id)
+  AND w.org_id = sqlc.arg(org_id)
+  AND b.id = w.blueprint_id
   AND w.deleted_at IS NULL
-  AND w.next_run_at <= now()
-ORDER BY w.next_run_at ASC
-LIMIT 50;
+  AND (w.lease_expires_at IS NULL OR w.lease_expires_at < now())
+RETURNING w.*, b.extraction_rules, b.schema_type, b.version AS blueprint_version;
+
+-- name: ClearAcceptNextRun :exec
+-- Clears a watch's accept_next_run once a run has applied its results.
+UPDATE watches
+SET accept_next_run = false
+WHERE id = $1;
+
+-- name: CountDueWatches :one
+-- Due watches no worker has claimed yet: the scheduler's queue depth.
+SELECT count(*) FROM watches
+WHERE status = 'active'
+  AND deleted_at IS NULL
+  AND next_run_at <= now()
+  AND (lease_expires_at IS NULL OR lease_expires_at < now());
+
+-- name: ExtendWatchLease :execrows
+-- Heartbeat for a running watch. Affects no rows once the lease was lost.
+UPDATE watches
+SET lease_expires_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id = sqlc.arg(id) AND lease_owner = sqlc.arg(owner)::text;
 
 -- name: GetWatchByID :one
-SELECT w.*, b.extraction_rules, b.schema_type
+SELECT w.*, b.extraction_rules, b.schema_type, b.version AS blueprint_version
 FROM watches w
 JOIN blueprints b ON b.id = w.blueprint_id
 WHERE w.id = $1 AND w.deleted_at IS NULL;
 
--- name: UpdateWatchAfterRun :exec
+-- name: RecordWatchSuccess :exec
+-- Resets failure tracking after a successful run. A watch in error recovers
+-- to active. adaptive_interval_seconds is the interval adaptive scheduling
+-- settled on, or NULL. Like the other Record queries, it only applies while
+-- owner still holds the watch's lease.
 UPDATE watches
-SET next_run_at = $2,
+SET next_run_at = sqlc.arg(next_run_at),
+    adaptive_interval_seconds = sqlc.arg(adaptive_interval_seconds),
+    last_run_at = now(),
+    last_error = NULL,
+    consecutive_failures = 0,
+    status = CASE WHEN status = 'error' THEN 'active' ELSE status END,
+    lease_owner = NULL,
+    lease_expires_at = NULL,
     updated_at = now()
-WHERE id = $1;
+WHERE id = sqlc.arg(id) AND lease_owner = sqlc.arg(owner)::text;
+
+-- name: RecordWatchCancelled :exec
+-- Schedules the next run after a cancelled or interrupted run without
+-- touching failure tracking.
+UPDATE watches
+SET next_run_at = sqlc.arg(next_run_at),
+    last_run_at = now(),
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND lease_owner = sqlc.arg(owner)::text;
+
+-- name: RecordWatchFailure :one
+-- Counts a failed run. An active watch moves to error once it reaches
+-- max_failures consecutive failures. Returns no rows when owner no longer
+-- holds the watch's lease.
+UPDATE watches
+SET next_run_at = sqlc.arg(next_run_at),
+    last_run_at = now(),
+    last_error = sqlc.arg(last_error),
+    consecutive_failures = consecutive_failures + 1,
+    status = CASE
+      WHEN status = 'active' AND consecutive_failures + 1 >= sqlc.arg(max_failures)::int THEN 'error'
+      ELSE status
+    END,
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND lease_owner = sqlc.arg(owner)::text
+RETURNING consecutive_failures, status;
+
+-- name: ReleaseWatchLease :exec
+-- Drops a worker's lease on one watch without recording a run.
+UPDATE watches
+SET lease_owner = NULL,
+    lease_expires_at = NULL
+WHERE id = sqlc.arg(id) AND lease_owner = sqlc.arg(owner)::text;
+
+-- name: ReleaseWatchLeases :execrows
+-- Drops the leases still held under a worker ID by a previous process.
+UPDATE watches
+SET lease_owner = NULL,
+    lease_expires_at = NULL
+WHERE lease_owner = sqlc.arg(owner)::text;
@@ -15,16 +15,51 @@ CREATE TABLE blueprints (
     deleted_at      timestamptz
 );
 
+CREATE TABLE blueprint_revisions (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id          text NOT NULL,
+    blueprint_id    uuid NOT NULL REFERENCES blueprints(id),
+    version         integer NOT NULL,
+    schema_type     text NOT NULL,
+    extraction_rules jsonb NOT NULL,
+    created_at      timestamptz NOT NULL DEFAULT now(),
+
+    UNIQUE (blueprint_id, version)
+);
+
 CREATE TABLE watches (
     id                    uuid PRIMARY KEY DEFAULT gen_random_uuid(),
     org_id                text NOT NULL,
     blueprint_id          uuid NOT NULL REFERENCES blueprints(id),
     name                  text NOT NULL,
     url                   text NOT NULL,
+    urls                  text[] NOT NULL DEFAULT ARRAY[]::text[],
+    url_params            text[] NOT NULL DEFAULT ARRAY[]::text[],
     schedule              text NOT NULL,
+    timezone              text NOT NULL DEFAULT 'UTC',
+    jitter_seconds        integer NOT NULL DEFAULT 0,
+    adaptive_schedule     boolean NOT NULL DEFAULT false,
+    adaptive_min_seconds  integer NOT NULL DEFAULT 300,
+    adaptive_max_seconds  integer NOT NULL DEFAULT 86400,
+    adaptive_interval_seconds integer,
     identity_fields       text[] NOT NULL DEFAULT ARRAY['name']::text[],
+    identity_fallbacks    jsonb NOT NULL DEFAULT '[]',
+    identity_normalize    text[] NOT NULL DEFAULT ARRAY[]::text[],
+    track_positions       boolean NOT NULL DEFAULT false,
+    rename_threshold      double precision NOT NULL DEFAULT 0,
+    diff_policy           jsonb NOT NULL DEFAULT '{}',
     status                text NOT NULL DEFAULT 'active',
     next_run_at           timestamptz,
+    last_run_at           timestamptz,
+    last_error            text,
+    consecutive_failures  integer NOT NULL DEFAULT 0,
+    min_entity_count      integer NOT NULL DEFAULT 1,
+    max_disappearance_ratio double precision NOT NULL DEFAULT 0.5,
+    accept_next_run       boolean NOT NULL DEFAULT false,
+    grace_missed_runs     integer NOT NULL DEFAULT 1,
+    grace_period_seconds  integer NOT NULL DEFAULT 0,
+    lease_owner           text,
+    lease_expires_at      timestamptz,
     created_at            timestamptz NOT NULL DEFAULT now(),
     updated_at            timestamptz NOT NULL DEFAULT now(),
     deleted_at            timestamptz
@@ -38,9 +73,14 @@ CREATE TABLE entities (
     external_id     text NOT NULL,
     content         jsonb NOT NULL,
     url             text,
+    source_url      text,
+    source_param    text,
     status          text NOT NULL DEFAULT 'active',
+    schema_version  integer NOT NULL DEFAULT 1,
+    blueprint_version integer NOT NULL DEFAULT 1,
     first_seen_at   timestamptz NOT NULL DEFAULT now(),
     last_seen_at    timestamptz NOT NULL DEFAULT now(),
+    missed_runs     integer NOT NULL DEFAULT 0,
     created_at      timestamptz NOT NULL DEFAULT now(),
     updated_at      timestamptz NOT NULL DEFAULT now(),
 
@@ -51,6 +91,7 @@ CREATE TABLE watch_runs (
     id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
     org_id          text NOT NULL,
     watch_id        uuid NOT NULL REFERENCES watches(id),
+    blueprint_revision_id uuid REFERENCES blueprint_revisions(id),
     status          text NOT NULL DEFAULT 'running',
     started_at      timestamptz NOT NULL DEFAULT now(),
     completed_at    timestamptz,
@@ -59,7 +100,37 @@ CREATE TABLE watch_runs (
     entities_changed integer,
     entities_removed integer,
     events_emitted  integer,
-    error_message   text
+    error_message   text,
+    cancel_requested_at timestamptz,
+    phase           text,
+    identity_collisions integer,
+    identity_missing integer,
+    page_errors     jsonb NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE watch_run_snapshots (
+    id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id           text NOT NULL,
+    watch_id         uuid NOT NULL REFERENCES watches(id),
+    watch_run_id     uuid NOT NULL REFERENCES watch_runs(id),
+    url              text NOT NULL,
+    raw_html_key     text NOT NULL,
+    cleaned_html_key text NOT NULL,
+    raw_size         integer NOT NULL,
+    cleaned_size     integer NOT NULL,
+    created_at       timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE entity_versions (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id          text NOT NULL,
+    watch_id        uuid NOT NULL REFERENCES watches(id),
+    entity_id       uuid NOT NULL REFERENCES entities(id),
+    watch_run_id    uuid NOT NULL REFERENCES watch_runs(id),
+    external_id     text NOT NULL,
+    change_type     text NOT NULL,
+    content         jsonb,
+    created_at      timestamptz NOT NULL DEFAULT now()
 );
 
 CREATE TABLE events (
@@ -97,7 +168,22 @@ CREATE TABLE deliveries (
     attempts        integer NOT NULL DEFAULT 0,
     max_attempts    integer NOT NULL DEFAULT 5,
     next_retry_at   timestamptz NOT NULL DEFAULT now(),
+    claimed_by      text,
     last_error      text,
     delivered_at    timestamptz,
     created_at      timestamptz NOT NULL DEFAULT now()
 );
+
+CREATE TABLE entity_schemas (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id          text NOT NULL,
+    schema_type     text NOT NULL,
+    version         integer NOT NULL,
+    description     text NOT NULL DEFAULT '',
+    fields          jsonb NOT NULL,
+    identity_fields text[] NOT NULL DEFAULT ARRAY[]::text[],
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    deleted_at      timestamptz,
+
+    UNIQUE (org_id, schema_type, version)
+);
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"sort"
 	"strings"
+	"time"
 )
 
 var changedTmpl = template.Must(template.New("changed").Parse(`<!DOCTYPE html>
@@ -15,6 +17,7 @@ var changedTmpl = template.Must(template.New("changed").Parse(`<!DOCTYPE html>
   <h2 style="color: #1a1a1a; margin-bottom: 4px;">Entity Changed</h2>
   <p style="color: #666; margin-top: 0;">Subscription: {{.SubscriptionName}}</p>
   {{if .EntityName}}<p style="color: #333;"><strong>{{.EntityName}}</strong></p>{{end}}
+  {{if .IdentityChanged}}<p style="color: #666;">Its identifying fields changed; it was matched to the previous entry by similarity.</p>{{end}}
   <table style="width: 100%; border-collapse: collapse; margin: 16px 0;">
     <thead>
       <tr style="background: #f5f5f5;">
@@ -70,7 +73,52 @@ var disappearedTmpl = template.Must(template.New("disappeared").Parse(`<!DOCTYPE
 <body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
   <h2 style="color: #1a1a1a; margin-bottom: 4px;">Entity Disappeared</h2>
   <p style="color: #666; margin-top: 0;">Subscription: {{.SubscriptionName}}</p>
-  <p style="color: #333;">The entity <strong>{{.EntityID}}</strong> is no longer present on the monitored page.</p>
+  <p style="color: #333;">The entity <strong>{{.EntityID}}</strong> is no longer present on the monitored page{{if .PageURL}} ({{if .WatchName}}{{.WatchName}}, {{end}}{{.PageURL}}){{end}}.</p>
+  {{if or .FirstSeenAt .LastSeenAt}}<p style="color: #666;">{{if .FirstSeenAt}}First seen {{.FirstSeenAt}}. {{end}}{{if .LastSeenAt}}Last seen {{.LastSeenAt}}.{{end}}</p>{{end}}
+  {{if .Fields}}
+  <p style="color: #333;">Last known state:</p>
+  <table style="width: 100%; border-collapse: collapse; margin: 16px 0;">
+    <thead>
+      <tr style="background: #f5f5f5;">
+        <th style="text-align: left; padding: 8px; border: 1px solid #ddd;">Field</th>
+        <th style="text-align: left; padding: 8px; border: 1px solid #ddd;">Value</th>
+      </tr>
+    </thead>
+    <tbody>
+      {{range .Fields}}
+      <tr>
+        <td style="padding: 8px; border: 1px solid #ddd;">{{.Key}}</td>
+        <td style="padding: 8px; border: 1px solid #ddd; color: #999;">{{.Value}}</td>
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+  {{end}}
+  <p style="color: #999; font-size: 12px;">Sent by Blueprinter</p>
+</body>
+</html>`))
+
+var watchErrorTmpl = template.Must(template.New("watch_error").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+  <h2 style="color: #1a1a1a; margin-bottom: 4px;">Watch Failing</h2>
+  <p style="color: #666; margin-top: 0;">Subscription: {{.SubscriptionName}}</p>
+  <p style="color: #333;">The watch <strong>{{.WatchName}}</strong> ({{.WatchURL}}) failed {{.ConsecutiveFailures}} times in a row and has been moved to the error state. It will not run again until it is triggered manually or resumed.</p>
+  <pre style="background: #f5f5f5; padding: 12px; border-radius: 4px; white-space: pre-wrap; color: #c00;">{{.Error}}</pre>
+  <p style="color: #999; font-size: 12px;">Sent by Blueprinter</p>
+</body>
+</html>`))
+
+var watchSuspectTmpl = template.Must(template.New("watch_suspect").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+  <h2 style="color: #1a1a1a; margin-bottom: 4px;">Suspicious Watch Run</h2>
+  <p style="color: #666; margin-top: 0;">Subscription: {{.SubscriptionName}}</p>
+  <p style="color: #333;">A run of the watch <strong>{{.WatchName}}</strong> ({{.WatchURL}}) found {{.EntitiesFound}} entities where {{.EntitiesPrevious}} were active before, so its results were discarded. The page layout may have changed, or the site may be blocking requests.</p>
+  <p style="color: #333;">Reason: {{.Reason}}</p>
+  <p style="color: #333;">Further runs are discarded the same way until the page recovers. If it really changed that much, accept the changes from the watch page so the next run applies them.</p>
   <p style="color: #999; font-size: 12px;">Sent by Blueprinter</p>
 </body>
 </html>`))
@@ -101,7 +149,11 @@ func BuildEmailContent(eventType string, payload []byte, subscriptionName string
 	case "entity_appeared":
 		return buildAppearedEmail(parsed, entityName, subscriptionName)
 	case "entity_disappeared":
-		return buildDisappearedEmail(parsed, entityName, subscriptionName)
+		return buildDisappearedEmail(payload, entityName, subscriptionName)
+	case "watch_error":
+		return buildWatchErrorEmail(payload, subscriptionName)
+	case "watch_suspect":
+		return buildWatchSuspectEmail(payload, subscriptionName)
 	default:
 		return fmt.Sprintf("[Blueprinter] Event: %s", eventType), "<p>Unknown event type</p>", nil
 	}
@@ -142,12 +194,20 @@ func buildChangedEmail(parsed map[string]json.RawMessage, entityName, subscripti
 		subject += " (" + strings.Join(fields, ", ") + ")"
 	}
 
+	var identityChanged bool
+	if raw, ok := parsed["identity_changed"]; ok {
+		if err := json.Unmarshal(raw, &identityChanged); err != nil {
+			return "", "", fmt.Errorf("parsing identity_changed: %w", err)
+		}
+	}
+
 	var buf bytes.Buffer
 	if err := changedTmpl.Execute(&buf, struct {
 		SubscriptionName string
 		EntityName       string
+		IdentityChanged  bool
 		Changes          []changeRow
-	}{subscriptionName, entityName, rows}); err != nil {
+	}{subscriptionName, entityName, identityChanged, rows}); err != nil {
 		return "", "", fmt.Errorf("executing template: %w", err)
 	}
 
@@ -166,10 +226,22 @@ func buildAppearedEmail(parsed map[string]json.RawMessage, entityName, subscript
 			return "", "", fmt.Errorf("parsing entity: %w", err)
 		}
 	}
+	var fieldOrder []string
+	if raw, ok := parsed["field_order"]; ok {
+		if err := json.Unmarshal(raw, &fieldOrder); err != nil {
+			return "", "", fmt.Errorf("parsing field_order: %w", err)
+		}
+	}
+
+	keys := make([]string, 0, len(entity))
+	for k := range entity {
+		keys = append(keys, k)
+	}
+	sortFields(keys, fieldOrder)
 
-	var fields []fieldRow
-	for k, v := range entity {
-		fields = append(fields, fieldRow{Key: k, Value: fmt.Sprintf("%v", v)})
+	fields := make([]fieldRow, len(keys))
+	for i, k := range keys {
+		fields[i] = fieldRow{Key: k, Value: fmt.Sprintf("%v", entity[k])}
 	}
 
 	var buf bytes.Buffer
@@ -184,17 +256,35 @@ func buildAppearedEmail(parsed map[string]json.RawMessage, entityName, subscript
 	return subject, buf.String(), nil
 }
 
-func buildDisappearedEmail(parsed map[string]json.RawMessage, entityName, subscriptionName string) (string, string, error) {
+func buildDisappearedEmail(payload []byte, entityName, subscriptionName string) (string, string, error) {
+	var p struct {
+		ExternalID  string         `json:"external_id"`
+		Entity      map[string]any `json:"entity"`
+		FieldOrder  []string       `json:"field_order"`
+		SourceURL   string         `json:"source_url"`
+		FirstSeenAt *time.Time     `json:"first_seen_at"`
+		LastSeenAt  *time.Time     `json:"last_seen_at"`
+		Watch       struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"watch"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", "", fmt.Errorf("parsing disappeared entity: %w", err)
+	}
+
+	// Older payloads only carry the external ID in the entity.
+	externalID := p.ExternalID
+	if externalID == "" {
+		externalID, _ = p.Entity["external_id"].(string)
+	}
 	entityID := entityName
 	if entityID == "" {
-		var entity map[string]any
-		if raw, ok := parsed["entity"]; ok {
-			if err := json.Unmarshal(raw, &entity); err == nil {
-				if eid, ok := entity["external_id"].(string); ok {
-					entityID = eid
-				}
-			}
-		}
+		entityID = externalID
+	}
+	pageURL := p.SourceURL
+	if pageURL == "" {
+		pageURL = p.Watch.URL
 	}
 
 	subject := "[Blueprinter] Entity disappeared"
@@ -202,17 +292,134 @@ func buildDisappearedEmail(parsed map[string]json.RawMessage, entityName, subscr
 		subject = fmt.Sprintf("[Blueprinter] %s disappeared", entityID)
 	}
 
+	// The external ID is already in the text, unless it's a content field of
+	// the same name with another value; older payloads carry nothing else.
+	keys := make([]string, 0, len(p.Entity))
+	for k, v := range p.Entity {
+		if k != "external_id" || v != externalID {
+			keys = append(keys, k)
+		}
+	}
+	sortFields(keys, p.FieldOrder)
+
+	fields := make([]fieldRow, len(keys))
+	for i, k := range keys {
+		fields[i] = fieldRow{Key: k, Value: fmt.Sprintf("%v", p.Entity[k])}
+	}
+
 	var buf bytes.Buffer
 	if err := disappearedTmpl.Execute(&buf, struct {
 		SubscriptionName string
 		EntityID         string
-	}{subscriptionName, entityID}); err != nil {
+		WatchName        string
+		PageURL          string
+		FirstSeenAt      string
+		LastSeenAt       string
+		Fields           []fieldRow
+	}{subscriptionName, entityID, p.Watch.Name, pageURL, formatTime(p.FirstSeenAt), formatTime(p.LastSeenAt), fields}); err != nil {
+		return "", "", fmt.Errorf("executing template: %w", err)
+	}
+
+	return subject, buf.String(), nil
+}
+
+// sortFields puts entity field names in the schema's field order, with fields
+// not listed there after them by name. Payloads without an order are sorted
+// by name.
+func sortFields(keys, order []string) {
+	rank := make(map[string]int, len(order))
+	for i, f := range order {
+		if _, ok := rank[f]; !ok {
+			rank[f] = i
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, iKnown := rank[keys[i]]
+		rj, jKnown := rank[keys[j]]
+		switch {
+		case iKnown && jKnown:
+			return ri < rj
+		case iKnown != jKnown:
+			return iKnown
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+func buildWatchErrorEmail(payload []byte, subscriptionName string) (string, string, error) {
+	var p struct {
+		Watch struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"watch"`
+		Error               string `json:"error"`
+		ConsecutiveFailures int    `json:"consecutive_failures"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", "", fmt.Errorf("parsing watch error: %w", err)
+	}
+
+	subject := "[Blueprinter] Watch is failing"
+	if p.Watch.Name != "" {
+		subject = fmt.Sprintf("[Blueprinter] Watch %s is failing", p.Watch.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := watchErrorTmpl.Execute(&buf, struct {
+		SubscriptionName    string
+		WatchName           string
+		WatchURL            string
+		Error               string
+		ConsecutiveFailures int
+	}{subscriptionName, p.Watch.Name, p.Watch.URL, p.Error, p.ConsecutiveFailures}); err != nil {
 		return "", "", fmt.Errorf("executing template: %w", err)
 	}
 
 	return subject, buf.String(), nil
 }
 
+func buildWatchSuspectEmail(payload []byte, subscriptionName string) (string, string, error) {
+	var p struct {
+		Watch struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"watch"`
+		Reason           string `json:"reason"`
+		EntitiesFound    int    `json:"entities_found"`
+		EntitiesPrevious int    `json:"entities_previous"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", "", fmt.Errorf("parsing watch suspect: %w", err)
+	}
+
+	subject := "[Blueprinter] Suspicious watch run"
+	if p.Watch.Name != "" {
+		subject = fmt.Sprintf("[Blueprinter] Suspicious run of %s", p.Watch.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := watchSuspectTmpl.Execute(&buf, struct {
+		SubscriptionName string
+		WatchName        string
+		WatchURL         string
+		Reason           string
+		EntitiesFound    int
+		EntitiesPrevious int
+	}{subscriptionName, p.Watch.Name, p.Watch.URL, p.Reason, p.EntitiesFound, p.EntitiesPrevious}); err != nil {
+		return "", "", fmt.Errorf("executing template: %w", err)
+	}
+
+	return subject, buf.String(), nil
+}
+
+// formatTime renders an event timestamp for an email, or "" if it is unset.
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
 func extractEntityName(parsed map[string]json.RawMessage) string {
 	entityRaw, ok := parsed["entity"]
 	if !ok {
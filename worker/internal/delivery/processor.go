@@ -3,6 +3,7 @@ package delivery
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -16,6 +17,15 @@ import (
 const (
 	pollInterval  = 10 * time.Second
 	maxConcurrent = 3
+
+	// claimLease is how long a claimed delivery is hidden from other
+	// workers without a heartbeat. A crashed worker's deliveries are due
+	// again after it.
+	claimLease = 5 * time.Minute
+
+	// claimHeartbeat is how often the claims of a batch being sent are
+	// renewed.
+	claimHeartbeat = time.Minute
 )
 
 // Retry backoff durations: 1m, 5m, 30m, 2h.
@@ -26,46 +36,105 @@ var retryBackoffs = []time.Duration{
 	2 * time.Hour,
 }
 
+// errShutdown is the cause of a send interrupted because the worker is
+// shutting down.
+var errShutdown = errors.New("worker shutting down")
+
 // Processor polls for pending deliveries and sends them.
 type Processor struct {
-	queries *dbgen.Queries
-	sender  Sender
-	logger  *slog.Logger
+	queries  *dbgen.Queries
+	sender   Sender
+	workerID string // owner recorded on delivery claims
+	logger   *slog.Logger
+
+	stopping      chan struct{} // closed by Shutdown to stop claiming
+	interrupting  chan struct{} // closed by Shutdown when the grace period ends
+	stopped       chan struct{} // closed when Run returns
+	stopOnce      sync.Once
+	interruptOnce sync.Once
 }
 
-// NewProcessor creates a new delivery Processor.
-func NewProcessor(queries *dbgen.Queries, sender Sender, logger *slog.Logger) *Processor {
+// NewProcessor creates a new delivery Processor. workerID must be unique
+// among running workers.
+func NewProcessor(queries *dbgen.Queries, sender Sender, workerID string, logger *slog.Logger) *Processor {
 	return &Processor{
-		queries: queries,
-		sender:  sender,
-		logger:  logger,
+		queries:      queries,
+		sender:       sender,
+		workerID:     workerID,
+		logger:       logger,
+		stopping:     make(chan struct{}),
+		interrupting: make(chan struct{}),
+		stopped:      make(chan struct{}),
 	}
 }
 
-// Run starts the polling loop. It blocks until the context is cancelled.
+// Run starts the polling loop. It returns once Shutdown has drained the sends
+// in progress, or when the context is cancelled, in which case they are
+// interrupted.
 func (p *Processor) Run(ctx context.Context) {
+	defer close(p.stopped)
 	p.logger.Info("delivery processor started", "poll_interval", pollInterval, "max_concurrent", maxConcurrent)
 	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
+	// Sends outlive ctx so that a shutdown can let them finish.
+	sendCtx, interrupt := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer interrupt(nil)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-p.interrupting:
+			p.logger.Warn("shutdown grace period over, interrupting deliveries")
+		case <-sendCtx.Done():
+			return
+		}
+		interrupt(errShutdown)
+	}()
+
 	// Run once immediately on startup
-	p.poll(ctx)
+	p.poll(ctx, sendCtx)
 
 	for {
 		select {
 		case <-ctx.Done():
-			p.logger.Info("delivery processor stopping")
-			return
+		case <-p.stopping:
 		case <-ticker.C:
-			p.poll(ctx)
+			p.poll(ctx, sendCtx)
+			continue
 		}
+		p.logger.Info("delivery processor stopped")
+		return
+	}
+}
+
+// Shutdown stops claiming deliveries and waits for the sends in progress to
+// finish. If ctx ends first, the remaining sends are interrupted and left for
+// a retry. Run must have been started.
+func (p *Processor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stopping) })
+
+	select {
+	case <-p.stopped:
+		return nil
+	case <-ctx.Done():
+		p.interruptOnce.Do(func() { close(p.interrupting) })
+		<-p.stopped
+		return ctx.Err()
 	}
 }
 
-func (p *Processor) poll(ctx context.Context) {
-	deliveries, err := p.queries.GetPendingDeliveries(ctx)
+// poll claims due deliveries and sends them under sendCtx, holding their
+// claims until all are sent. Once the processor is stopping, the deliveries
+// not yet started are released to other workers.
+func (p *Processor) poll(ctx, sendCtx context.Context) {
+	deliveries, err := p.queries.ClaimPendingDeliveries(ctx, dbgen.ClaimPendingDeliveriesParams{
+		LeaseSeconds: int32(claimLease.Seconds()),
+		Owner:        p.workerID,
+	})
 	if err != nil {
-		p.logger.Error("failed to get pending deliveries", "error", err)
+		if ctx.Err() == nil {
+			p.logger.Error("failed to claim pending deliveries", "error", err)
+		}
 		return
 	}
 
@@ -73,25 +142,102 @@ func (p *Processor) poll(ctx context.Context) {
 		return
 	}
 
-	p.logger.Info("found pending deliveries", "count", len(deliveries))
+	p.logger.Info("claimed pending deliveries", "count", len(deliveries))
+
+	holdCtx, stopHolding := context.WithCancel(sendCtx)
+	defer stopHolding()
+	go p.holdClaims(holdCtx, deliveryIDs(deliveries))
 
 	sem := make(chan struct{}, maxConcurrent)
 	var wg sync.WaitGroup
 
 	for i := range deliveries {
+		if !p.acquire(ctx, sem) {
+			p.release(ctx, deliveries[i:])
+			break
+		}
 		wg.Add(1)
-		sem <- struct{}{}
-		go func(d *dbgen.GetPendingDeliveriesRow) {
+		go func(d *dbgen.ClaimPendingDeliveriesRow) {
 			defer wg.Done()
 			defer func() { <-sem }()
-			p.processDelivery(ctx, d)
+			p.processDelivery(sendCtx, d)
 		}(&deliveries[i])
 	}
 
 	wg.Wait()
 }
 
-func (p *Processor) processDelivery(ctx context.Context, d *dbgen.GetPendingDeliveriesRow) {
+// acquire waits for a free send slot. It reports false once the processor is
+// stopping.
+func (p *Processor) acquire(ctx context.Context, sem chan<- struct{}) bool {
+	select {
+	case <-p.stopping:
+		return false
+	case <-ctx.Done():
+		return false
+	default:
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-p.stopping:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// holdClaims renews this worker's claims on deliveries every claimHeartbeat
+// until ctx is done, so that another worker doesn't send them again while
+// they wait for a slot or are being sent. Settled deliveries are skipped.
+func (p *Processor) holdClaims(ctx context.Context, ids []pgtype.UUID) {
+	ticker := time.NewTicker(claimHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := p.queries.ExtendDeliveryClaims(ctx, dbgen.ExtendDeliveryClaimsParams{
+				LeaseSeconds: int32(claimLease.Seconds()),
+				Ids:          ids,
+				Owner:        p.workerID,
+			})
+			if err != nil && ctx.Err() == nil {
+				p.logger.Warn("failed to extend delivery claims", "count", len(ids), "error", err)
+			}
+		}
+	}
+}
+
+// release hands claimed deliveries back without counting an attempt, so
+// another worker picks them up without waiting for the claim lease to lapse.
+func (p *Processor) release(ctx context.Context, deliveries []dbgen.ClaimPendingDeliveriesRow) {
+	ids := deliveryIDs(deliveries)
+	if err := p.queries.ReleaseDeliveries(context.WithoutCancel(ctx), dbgen.ReleaseDeliveriesParams{
+		Ids:   ids,
+		Owner: p.workerID,
+	}); err != nil {
+		p.logger.Error("failed to release deliveries", "count", len(ids), "error", err)
+		return
+	}
+	p.logger.Info("released unsent deliveries", "count", len(ids))
+}
+
+func deliveryIDs(deliveries []dbgen.ClaimPendingDeliveriesRow) []pgtype.UUID {
+	ids := make([]pgtype.UUID, len(deliveries))
+	for i := range deliveries {
+		ids[i] = deliveries[i].ID
+	}
+	return ids
+}
+
+func (p *Processor) processDelivery(sendCtx context.Context, d *dbgen.ClaimPendingDeliveriesRow) {
+	// The outcome is recorded even if the send was interrupted.
+	ctx := context.WithoutCancel(sendCtx)
+
 	// Parse channel config to get recipients
 	var config struct {
 		To []string `json:"to"`
@@ -114,24 +260,38 @@ func (p *Processor) processDelivery(ctx context.Context, d *dbgen.GetPendingDeli
 	}
 
 	// Send email
-	err = p.sender.Send(ctx, SendRequest{
+	err = p.sender.Send(sendCtx, SendRequest{
 		To:       config.To,
 		Subject:  subject,
 		HTMLBody: htmlBody,
 	})
+	if err != nil && sendCtx.Err() != nil {
+		// Interrupted by a shutdown: whether it went out is unknown, so it is
+		// retried without counting the attempt.
+		p.release(ctx, []dbgen.ClaimPendingDeliveriesRow{*d})
+		return
+	}
 	if err != nil {
 		p.handleSendError(ctx, d, err)
 		return
 	}
 
 	// Success
-	if err := p.queries.MarkDeliveryDelivered(ctx, d.ID); err != nil {
+	n, err := p.queries.MarkDeliveryDelivered(ctx, dbgen.MarkDeliveryDeliveredParams{
+		ID:    d.ID,
+		Owner: p.workerID,
+	})
+	if err != nil {
 		p.logger.Error("failed to mark delivery as delivered",
 			"delivery_id", d.ID,
 			"error", err,
 		)
 		return
 	}
+	if n == 0 {
+		p.claimLost(d)
+		return
+	}
 
 	p.logger.Info("delivery sent",
 		"delivery_id", d.ID,
@@ -141,7 +301,7 @@ func (p *Processor) processDelivery(ctx context.Context, d *dbgen.GetPendingDeli
 	)
 }
 
-func (p *Processor) handleSendError(ctx context.Context, d *dbgen.GetPendingDeliveriesRow, sendErr error) {
+func (p *Processor) handleSendError(ctx context.Context, d *dbgen.ClaimPendingDeliveriesRow, sendErr error) {
 	nextAttempt := d.Attempts + 1 // attempts will be incremented by the query
 
 	// If we've exhausted retries, mark as failed
@@ -157,8 +317,7 @@ func (p *Processor) handleSendError(ctx context.Context, d *dbgen.GetPendingDeli
 	}
 	nextRetry := time.Now().Add(retryBackoffs[backoffIdx])
 
-	if err := p.queries.MarkDeliveryRetry(ctx, dbgen.MarkDeliveryRetryParams{
-		ID: d.ID,
+	n, err := p.queries.MarkDeliveryRetry(ctx, dbgen.MarkDeliveryRetryParams{
 		NextRetryAt: pgtype.Timestamptz{
 			Time:  nextRetry,
 			Valid: true,
@@ -167,13 +326,20 @@ func (p *Processor) handleSendError(ctx context.Context, d *dbgen.GetPendingDeli
 			String: sendErr.Error(),
 			Valid:  true,
 		},
-	}); err != nil {
+		ID:    d.ID,
+		Owner: p.workerID,
+	})
+	if err != nil {
 		p.logger.Error("failed to mark delivery for retry",
 			"delivery_id", d.ID,
 			"error", err,
 		)
 		return
 	}
+	if n == 0 {
+		p.claimLost(d)
+		return
+	}
 
 	p.logger.Warn("delivery failed, scheduled retry",
 		"delivery_id", d.ID,
@@ -183,20 +349,26 @@ func (p *Processor) handleSendError(ctx context.Context, d *dbgen.GetPendingDeli
 	)
 }
 
-func (p *Processor) markFailed(ctx context.Context, d *dbgen.GetPendingDeliveriesRow, err error) {
-	if markErr := p.queries.MarkDeliveryFailed(ctx, dbgen.MarkDeliveryFailedParams{
-		ID: d.ID,
+func (p *Processor) markFailed(ctx context.Context, d *dbgen.ClaimPendingDeliveriesRow, err error) {
+	n, markErr := p.queries.MarkDeliveryFailed(ctx, dbgen.MarkDeliveryFailedParams{
 		LastError: pgtype.Text{
 			String: err.Error(),
 			Valid:  true,
 		},
-	}); markErr != nil {
+		ID:    d.ID,
+		Owner: p.workerID,
+	})
+	if markErr != nil {
 		p.logger.Error("failed to mark delivery as failed",
 			"delivery_id", d.ID,
 			"error", markErr,
 		)
 		return
 	}
+	if n == 0 {
+		p.claimLost(d)
+		return
+	}
 
 	p.logger.Error("delivery permanently failed",
 		"delivery_id", d.ID,
@@ -204,3 +376,12 @@ func (p *Processor) markFailed(ctx context.Context, d *dbgen.GetPendingDeliverie
 		"error", err,
 	)
 }
+
+// claimLost reports a delivery whose outcome wasn't recorded because its
+// claim lapsed and another worker may have taken it over.
+func (p *Processor) claimLost(d *dbgen.ClaimPendingDeliveriesRow) {
+	p.logger.Warn("delivery claim lost, outcome not recorded",
+		"delivery_id", d.ID,
+		"subscription", d.SubscriptionName,
+	)
+}
@@ -0,0 +1,74 @@
+package differ
+
+import "sort"
+
+// Migration reconciles stored entity content with the current field set of a
+// schema/blueprint, so that adding, renaming or removing a field does not show
+// up as a change on every entity.
+type Migration struct {
+	Fields  []string          // field set entities should carry after migration
+	Renames map[string]string // new field name -> previous field name
+}
+
+// ContentMigration reports what a migration did (or would do) to one entity.
+type ContentMigration struct {
+	ExternalID string            `json:"external_id"`
+	Renamed    map[string]string `json:"renamed,omitempty"` // old -> new
+	Dropped    []string          `json:"dropped,omitempty"`
+	Adopted    []string          `json:"adopted,omitempty"` // new fields filled from the current extraction
+	Pending    []string          `json:"pending,omitempty"` // new fields that will be filled on the next extraction
+}
+
+// Empty reports whether the migration leaves the content untouched.
+func (c ContentMigration) Empty() bool {
+	return len(c.Renamed) == 0 && len(c.Dropped) == 0 && len(c.Adopted) == 0 && len(c.Pending) == 0
+}
+
+// Apply migrates stored content to the migration's field set.
+//
+// Fields present in stored content are kept as-is. Renamed fields carry over
+// the old value. New fields take their value from extracted (the entity as
+// extracted in the current run) so they don't register as nil -> value; when
+// extracted is nil (dry run) they are reported as pending instead. Fields
+// outside the field set are dropped.
+func (m Migration) Apply(externalID string, stored, extracted map[string]any) (map[string]any, ContentMigration) {
+	report := ContentMigration{ExternalID: externalID}
+	out := make(map[string]any, len(m.Fields))
+	consumed := make(map[string]bool)
+
+	for _, field := range m.Fields {
+		if v, ok := stored[field]; ok {
+			out[field] = v
+			consumed[field] = true
+			continue
+		}
+		if old, ok := m.Renames[field]; ok {
+			if v, ok := stored[old]; ok {
+				out[field] = v
+				consumed[old] = true
+				if report.Renamed == nil {
+					report.Renamed = make(map[string]string)
+				}
+				report.Renamed[old] = field
+				continue
+			}
+		}
+		if extracted == nil {
+			report.Pending = append(report.Pending, field)
+			continue
+		}
+		if v, ok := extracted[field]; ok {
+			out[field] = v
+			report.Adopted = append(report.Adopted, field)
+		}
+	}
+
+	for field := range stored {
+		if !consumed[field] {
+			report.Dropped = append(report.Dropped, field)
+		}
+	}
+	sort.Strings(report.Dropped)
+
+	return out, report
+}
@@ -0,0 +1,49 @@
+package differ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationApply(t *testing.T) {
+	m := Migration{
+		Fields:  []string{"title", "price", "rating"},
+		Renames: map[string]string{"title": "name"},
+	}
+
+	stored := map[string]any{"name": "Product A", "price": float64(100), "color": "red"}
+	extracted := map[string]any{"title": "Product A", "price": float64(120), "rating": 4.5}
+
+	out, report := m.Apply("a", stored, extracted)
+
+	assert.Equal(t, map[string]any{"title": "Product A", "price": float64(100), "rating": 4.5}, out)
+	assert.Equal(t, map[string]string{"name": "title"}, report.Renamed)
+	assert.Equal(t, []string{"color"}, report.Dropped)
+	assert.Equal(t, []string{"rating"}, report.Adopted)
+	assert.Empty(t, report.Pending)
+
+	// After migration only the real price change remains.
+	result := Diff(map[string]map[string]any{"a": extracted}, map[string]map[string]any{"a": out})
+	assert.Len(t, result.Changed, 1)
+	assert.Len(t, result.Changed[0].Changes, 1)
+	assert.Equal(t, "price", result.Changed[0].Changes[0].Field)
+}
+
+func TestMigrationApply_DryRun(t *testing.T) {
+	m := Migration{Fields: []string{"name", "rating"}}
+
+	out, report := m.Apply("a", map[string]any{"name": "Product A"}, nil)
+
+	assert.Equal(t, map[string]any{"name": "Product A"}, out)
+	assert.Equal(t, []string{"rating"}, report.Pending)
+	assert.False(t, report.Empty())
+}
+
+func TestMigrationApply_NoOp(t *testing.T) {
+	m := Migration{Fields: []string{"name", "price"}}
+
+	_, report := m.Apply("a", map[string]any{"name": "Product A", "price": float64(1)}, map[string]any{"name": "Product A"})
+
+	assert.True(t, report.Empty())
+}
@@ -0,0 +1,71 @@
+package differ
+
+import (
+	"cmp"
+	"slices"
+)
+
+// positionField is the listing position system field entities are ordered
+// by when positions are tracked. It is blueprint.PositionField, which can't
+// be imported here since blueprint imports this package.
+const positionField = "_position"
+
+// Ordering is the order a DiffResult is in, recorded on the result so callers
+// can order other lists the same way. Each entity's Changes follow Fields,
+// with fields not listed there after them by name. Appeared, Changed and
+// Disappeared are each ordered by the Position field of their content,
+// entities without it after those with it, then by external ID.
+// Disappeared entities carry no content, so they are ordered by external ID.
+type Ordering struct {
+	Fields   []string `json:"fields,omitempty"`
+	Position string   `json:"position,omitempty"`
+}
+
+// Apply puts a DiffResult in this order.
+func (o Ordering) Apply(result *DiffResult) {
+	rank := make(map[string]int, len(o.Fields))
+	for i, f := range o.Fields {
+		if _, ok := rank[f]; !ok {
+			rank[f] = i
+		}
+	}
+	compareFields := func(a, b FieldChange) int {
+		ra, aKnown := rank[a.Field]
+		rb, bKnown := rank[b.Field]
+		switch {
+		case aKnown && bKnown:
+			return cmp.Compare(ra, rb)
+		case aKnown:
+			return -1
+		case bKnown:
+			return 1
+		}
+		return cmp.Compare(a.Field, b.Field)
+	}
+
+	for _, list := range [][]EntityDiff{result.Appeared, result.Changed, result.Disappeared} {
+		for _, d := range list {
+			slices.SortFunc(d.Changes, compareFields)
+		}
+		slices.SortStableFunc(list, o.compareEntities)
+	}
+	result.Ordering = o
+}
+
+func (o Ordering) compareEntities(a, b EntityDiff) int {
+	if o.Position != "" {
+		pa, aOK := toFloat64(a.Content[o.Position])
+		pb, bOK := toFloat64(b.Content[o.Position])
+		switch {
+		case aOK && bOK:
+			if c := cmp.Compare(pa, pb); c != 0 {
+				return c
+			}
+		case aOK:
+			return -1
+		case bOK:
+			return 1
+		}
+	}
+	return cmp.Compare(a.ExternalID, b.ExternalID)
+}
@@ -0,0 +1,87 @@
+package differ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func externalIDs(diffs []EntityDiff) []string {
+	ids := make([]string, len(diffs))
+	for i, d := range diffs {
+		ids[i] = d.ExternalID
+	}
+	return ids
+}
+
+func changedFields(d EntityDiff) []string {
+	fields := make([]string, len(d.Changes))
+	for i, c := range d.Changes {
+		fields[i] = c.Field
+	}
+	return fields
+}
+
+func TestDiff_Ordering(t *testing.T) {
+	stored := map[string]map[string]any{
+		"c": {"name": "C", "price": float64(1), "color": "red", "size": "S"},
+		"a": {"name": "A", "price": float64(1)},
+		"x": {"name": "X"},
+		"m": {"name": "M"},
+	}
+	extracted := map[string]map[string]any{
+		"c": {"name": "C2", "price": float64(2), "color": "blue", "weight": float64(3)},
+		"a": {"name": "A2", "price": float64(1)},
+		"e": {"name": "E"},
+		"b": {"name": "B"},
+	}
+
+	p := Policy{FieldOrder: []string{"price", "name"}}
+	for range 20 {
+		result := p.Diff(extracted, stored)
+
+		assert.Equal(t, []string{"b", "e"}, externalIDs(result.Appeared))
+		assert.Equal(t, []string{"a", "c"}, externalIDs(result.Changed))
+		assert.Equal(t, []string{"m", "x"}, externalIDs(result.Disappeared))
+		// Schema fields first, in schema order, then the rest by name.
+		assert.Equal(t, []string{"price", "name", "color", "size", "weight"}, changedFields(result.Changed[1]))
+		assert.Equal(t, Ordering{Fields: []string{"price", "name"}, Position: "_position"}, result.Ordering)
+	}
+}
+
+func TestDiff_OrderingByPosition(t *testing.T) {
+	stored := map[string]map[string]any{
+		"a": {"name": "A", "_position": float64(1)},
+		"b": {"name": "B", "_position": float64(2)},
+	}
+	extracted := map[string]map[string]any{
+		"a": {"name": "A", "_position": 3},
+		"b": {"name": "B", "_position": 1},
+		"c": {"name": "C", "_position": 2},
+		"d": {"name": "D"},
+		"z": {"name": "Z", "_position": 4},
+	}
+
+	result := Diff(extracted, stored)
+
+	assert.Equal(t, []string{"b", "a"}, externalIDs(result.Changed))
+	assert.Equal(t, []string{"c", "z", "d"}, externalIDs(result.Appeared))
+}
+
+func TestMatchRenames_KeepsOrdering(t *testing.T) {
+	stored := map[string]map[string]any{
+		"old": {"name": "Widget Pro 2", "price": float64(10)},
+		"m":   {"name": "Monitor", "price": float64(100)},
+	}
+	extracted := map[string]map[string]any{
+		"new": {"name": "Widget Pro II", "price": float64(10)},
+		"m":   {"name": "Monitor", "price": float64(90)},
+	}
+
+	p := Policy{FieldOrder: []string{"price", "name"}}
+	result := p.Diff(extracted, stored)
+	p.MatchRenames(&result, stored, 0.7)
+
+	assert.Equal(t, []string{"m", "new"}, externalIDs(result.Changed))
+	assert.Equal(t, []string{"name"}, changedFields(result.Changed[1]))
+}
@@ -0,0 +1,218 @@
+package differ
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Policy sets how a watch's entities are compared. The zero Policy compares
+// every field, numbers exactly and strings trimmed and case-sensitive.
+type Policy struct {
+	Ignore      []string             `json:"ignore,omitempty"`      // fields never reported as changed
+	Significant []string             `json:"significant,omitempty"` // if set, only changes to these make an entity changed
+	Default     FieldRule            `json:"default"`               // rule for fields without their own
+	Fields      map[string]FieldRule `json:"fields,omitempty"`      // per-field rules, replacing Default
+
+	// FieldOrder is the order changes are listed in, normally the schema's
+	// field order. It is set by the caller rather than configured.
+	FieldOrder []string `json:"-"`
+}
+
+// FieldRule sets how the values of a field are compared. Numbers are equal
+// when they are within either tolerance of each other.
+type FieldRule struct {
+	AbsTolerance       float64 `json:"abs_tolerance,omitempty"`       // largest difference ignored
+	RelTolerance       float64 `json:"rel_tolerance,omitempty"`       // largest difference ignored, as a fraction of the larger value
+	IgnoreCase         bool    `json:"ignore_case,omitempty"`         // compare strings case-insensitively
+	CollapseWhitespace bool    `json:"collapse_whitespace,omitempty"` // compare runs of whitespace as one space
+	NormalizeUnicode   bool    `json:"normalize_unicode,omitempty"`   // compare NFKC forms, so e.g. full-width and ligature characters match
+}
+
+// ParsePolicy decodes a watch's diff policy. Empty input is the zero Policy;
+// unknown keys are rejected so a misspelled setting isn't silently ignored.
+func ParsePolicy(raw []byte) (Policy, error) {
+	var p Policy
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return p, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return Policy{}, err
+	}
+	if err := p.Default.validate(); err != nil {
+		return Policy{}, fmt.Errorf("default: %w", err)
+	}
+	for field, r := range p.Fields {
+		if err := r.validate(); err != nil {
+			return Policy{}, fmt.Errorf("field %q: %w", field, err)
+		}
+	}
+	for _, field := range p.Significant {
+		if slices.Contains(p.Ignore, field) {
+			return Policy{}, fmt.Errorf("field %q is both ignored and significant", field)
+		}
+	}
+	return p, nil
+}
+
+func (r FieldRule) validate() error {
+	if r.AbsTolerance < 0 || r.RelTolerance < 0 {
+		return fmt.Errorf("tolerances must not be negative")
+	}
+	return nil
+}
+
+// Diff compares extracted entities against stored entities, keyed by
+// externalID, under the policy. The result is in the policy's Ordering.
+func (p Policy) Diff(extracted, stored map[string]map[string]any) DiffResult {
+	var result DiffResult
+
+	// Check for appeared and changed entities
+	for eid, extractedContent := range extracted {
+		storedContent, exists := stored[eid]
+		if !exists {
+			result.Appeared = append(result.Appeared, EntityDiff{
+				ExternalID: eid,
+				Type:       "appeared",
+				Content:    extractedContent,
+			})
+			continue
+		}
+
+		changes := p.diffFields(storedContent, extractedContent)
+		if p.isChange(changes) {
+			result.Changed = append(result.Changed, EntityDiff{
+				ExternalID: eid,
+				Type:       "changed",
+				Changes:    changes,
+				Content:    extractedContent,
+			})
+		} else {
+			result.Unchanged++
+		}
+	}
+
+	// Check for disappeared entities
+	for eid := range stored {
+		if _, exists := extracted[eid]; !exists {
+			result.Disappeared = append(result.Disappeared, EntityDiff{
+				ExternalID: eid,
+				Type:       "disappeared",
+			})
+		}
+	}
+
+	p.Ordering().Apply(&result)
+	return result
+}
+
+// Ordering is the order Diff and MatchRenames put their results in: changes
+// by FieldOrder, entities by listing position, then external ID.
+func (p Policy) Ordering() Ordering {
+	return Ordering{Fields: p.FieldOrder, Position: positionField}
+}
+
+// isChange reports whether field changes make an entity changed: any change
+// does, unless significant fields are set and none of them changed.
+func (p Policy) isChange(changes []FieldChange) bool {
+	if len(p.Significant) == 0 {
+		return len(changes) > 0
+	}
+	for _, c := range changes {
+		if slices.Contains(p.Significant, c.Field) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffFields compares two entity maps field by field and returns the changes
+// of the fields that aren't ignored.
+func (p Policy) diffFields(old, new map[string]any) []FieldChange {
+	var changes []FieldChange
+
+	// Check all fields in new entity
+	for field, newVal := range new {
+		if slices.Contains(p.Ignore, field) {
+			continue
+		}
+		oldVal, exists := old[field]
+		if !exists {
+			changes = append(changes, FieldChange{Field: field, Old: nil, New: newVal})
+			continue
+		}
+		if !p.rule(field).equal(oldVal, newVal) {
+			changes = append(changes, FieldChange{Field: field, Old: oldVal, New: newVal})
+		}
+	}
+
+	// Check for removed fields (in old but not in new)
+	for field, oldVal := range old {
+		if slices.Contains(p.Ignore, field) {
+			continue
+		}
+		if _, exists := new[field]; !exists {
+			changes = append(changes, FieldChange{Field: field, Old: oldVal, New: nil})
+		}
+	}
+
+	return changes
+}
+
+func (p Policy) rule(field string) FieldRule {
+	if r, ok := p.Fields[field]; ok {
+		return r
+	}
+	return p.Default
+}
+
+// equal compares two values under the rule.
+// Nil handling: nil == nil is true, nil != non-nil.
+func (r FieldRule) equal(a, b any) bool {
+	if a == nil && b == nil {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+
+	// Normalize both to comparable types
+	aStr, aIsStr := toString(a)
+	bStr, bIsStr := toString(b)
+	if aIsStr && bIsStr {
+		return r.normalize(aStr) == r.normalize(bStr)
+	}
+
+	aNum, aIsNum := toFloat64(a)
+	bNum, bIsNum := toFloat64(b)
+	if aIsNum && bIsNum {
+		diff := math.Abs(aNum - bNum)
+		return diff <= r.AbsTolerance || diff <= r.RelTolerance*math.Max(math.Abs(aNum), math.Abs(bNum))
+	}
+
+	// Fall back to string comparison
+	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
+}
+
+// normalize returns the form of a string value that is compared.
+func (r FieldRule) normalize(s string) string {
+	if r.NormalizeUnicode {
+		s = norm.NFKC.String(s)
+	}
+	if r.CollapseWhitespace {
+		s = strings.Join(strings.Fields(s), " ")
+	} else {
+		s = strings.TrimSpace(s)
+	}
+	if r.IgnoreCase {
+		s = strings.ToLower(s)
+	}
+	return s
+}
@@ -0,0 +1,123 @@
+package differ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(`{
+		"ignore": ["review_count"],
+		"significant": ["price", "availability"],
+		"default": {"ignore_case": true},
+		"fields": {"rating": {"abs_tolerance": 0.05}}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, Policy{
+		Ignore:      []string{"review_count"},
+		Significant: []string{"price", "availability"},
+		Default:     FieldRule{IgnoreCase: true},
+		Fields:      map[string]FieldRule{"rating": {AbsTolerance: 0.05}},
+	}, p)
+
+	p, err = ParsePolicy(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Policy{}, p)
+}
+
+func TestParsePolicy_Invalid(t *testing.T) {
+	_, err := ParsePolicy([]byte(`{"ignored": ["a"]}`))
+	assert.ErrorContains(t, err, `unknown field "ignored"`)
+
+	_, err = ParsePolicy([]byte(`{"fields": {"price": {"rel_tolerance": -0.1}}}`))
+	assert.EqualError(t, err, `field "price": tolerances must not be negative`)
+
+	_, err = ParsePolicy([]byte(`{"ignore": ["price"], "significant": ["price"]}`))
+	assert.EqualError(t, err, `field "price" is both ignored and significant`)
+}
+
+func TestFieldRuleEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		rule FieldRule
+		a, b any
+		want bool
+	}{
+		{"exact by default", FieldRule{}, 4.49, 4.5, false},
+		{"within absolute tolerance", FieldRule{AbsTolerance: 0.05}, 4.49, 4.5, true},
+		{"outside absolute tolerance", FieldRule{AbsTolerance: 0.05}, 4.4, 4.5, false},
+		{"within relative tolerance", FieldRule{RelTolerance: 0.01}, float64(1000), 1009, true},
+		{"outside relative tolerance", FieldRule{RelTolerance: 0.01}, float64(1000), 1011, false},
+		{"case sensitive by default", FieldRule{}, "In Stock", "in stock", false},
+		{"ignore case", FieldRule{IgnoreCase: true}, "In Stock", "in stock", true},
+		{"inner whitespace kept by default", FieldRule{}, "in  stock", "in stock", false},
+		{"collapse whitespace", FieldRule{CollapseWhitespace: true}, "in \n stock", "in stock", true},
+		{"unicode kept by default", FieldRule{}, "ＡＢＣ", "ABC", false},
+		{"normalize unicode", FieldRule{NormalizeUnicode: true}, "ＡＢＣ ﬁt", "ABC fit", true},
+		{"normalize non-breaking space", FieldRule{NormalizeUnicode: true}, "10 kg", "10 kg", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.equal(tt.a, tt.b))
+		})
+	}
+}
+
+func TestPolicyDiff_Ignore(t *testing.T) {
+	p := Policy{Ignore: []string{"review_count"}}
+	stored := map[string]map[string]any{
+		"a": {"name": "A", "price": float64(100), "review_count": float64(10)},
+		"b": {"name": "B", "price": float64(200), "review_count": float64(20)},
+	}
+	extracted := map[string]map[string]any{
+		"a": {"name": "A", "price": float64(100), "review_count": float64(11)},
+		"b": {"name": "B", "price": float64(180)},
+	}
+
+	result := p.Diff(extracted, stored)
+
+	assert.Equal(t, 1, result.Unchanged)
+	require.Len(t, result.Changed, 1)
+	assert.Equal(t, "b", result.Changed[0].ExternalID)
+	assert.Equal(t, []FieldChange{{Field: "price", Old: float64(200), New: float64(180)}}, result.Changed[0].Changes)
+}
+
+func TestPolicyDiff_Significant(t *testing.T) {
+	p := Policy{Significant: []string{"price"}}
+	stored := map[string]map[string]any{
+		"a": {"price": float64(100), "rating": 4.4},
+		"b": {"price": float64(200), "rating": 4.1},
+	}
+	extracted := map[string]map[string]any{
+		"a": {"price": float64(100), "rating": 4.5},
+		"b": {"price": float64(190), "rating": 4.2},
+	}
+
+	result := p.Diff(extracted, stored)
+
+	// a only changed an insignificant field; b's change lists every field.
+	assert.Equal(t, 1, result.Unchanged)
+	require.Len(t, result.Changed, 1)
+	assert.Equal(t, "b", result.Changed[0].ExternalID)
+	assert.ElementsMatch(t, []FieldChange{
+		{Field: "price", Old: float64(200), New: float64(190)},
+		{Field: "rating", Old: 4.1, New: 4.2},
+	}, result.Changed[0].Changes)
+}
+
+func TestPolicyDiff_FieldRuleReplacesDefault(t *testing.T) {
+	p := Policy{
+		Default: FieldRule{RelTolerance: 0.1},
+		Fields:  map[string]FieldRule{"price": {}},
+	}
+	stored := map[string]map[string]any{"a": {"price": float64(100), "stock": float64(100)}}
+	extracted := map[string]map[string]any{"a": {"price": float64(99), "stock": float64(95)}}
+
+	result := p.Diff(extracted, stored)
+
+	require.Len(t, result.Changed, 1)
+	assert.Equal(t, []FieldChange{{Field: "price", Old: float64(100), New: float64(99)}}, result.Changed[0].Changes)
+}
@@ -0,0 +1,229 @@
+package differ
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxRenamePairs bounds the number of disappeared/appeared pairs MatchRenames
+// considers. Runs with more pairs than this are left as they are: matching
+// runs inside the persist transaction, and that many renames at once is more
+// likely a re-identified page than edited entities.
+const maxRenamePairs = 20_000
+
+// maxCompareRunes bounds the length of the strings compared when scoring, so
+// long descriptions don't dominate the cost of a run.
+const maxCompareRunes = 100
+
+// MatchRenames pairs disappeared entities with appeared ones whose content is
+// at least threshold similar (0 to 1) and reports each pair as a single
+// changed entity under its new external ID, with PreviousExternalID set to
+// the old one. stored holds the content of the disappeared entities. Pairs
+// are taken best first, each entity at most once. A threshold of 0 or less
+// turns matching off. The changes of a pair are listed under the policy, and
+// the result is put back in the policy's Ordering. It returns the number of
+// pairs matched.
+func (p Policy) MatchRenames(result *DiffResult, stored map[string]map[string]any, threshold float64) int {
+	if threshold <= 0 || len(result.Appeared) == 0 || len(result.Disappeared) == 0 {
+		return 0
+	}
+	if len(result.Appeared)*len(result.Disappeared) > maxRenamePairs {
+		return 0
+	}
+
+	type candidate struct {
+		gone, added int
+		score       float64
+	}
+	added := make([]profile, len(result.Appeared))
+	for j, a := range result.Appeared {
+		added[j] = newProfile(a.Content)
+	}
+	var candidates []candidate
+	for i, d := range result.Disappeared {
+		old, ok := stored[d.ExternalID]
+		if !ok {
+			continue
+		}
+		gone := newProfile(old)
+		for j := range added {
+			// The bound is cheap and rules out most pairs before any edit
+			// distance is computed.
+			if gone.bound(added[j]) < threshold {
+				continue
+			}
+			if score := gone.similarity(added[j]); score >= threshold {
+				candidates = append(candidates, candidate{gone: i, added: j, score: score})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return 0
+	}
+	sort.Slice(candidates, func(x, y int) bool {
+		cx, cy := candidates[x], candidates[y]
+		if cx.score != cy.score {
+			return cx.score > cy.score
+		}
+		if gx, gy := result.Disappeared[cx.gone].ExternalID, result.Disappeared[cy.gone].ExternalID; gx != gy {
+			return gx < gy
+		}
+		return result.Appeared[cx.added].ExternalID < result.Appeared[cy.added].ExternalID
+	})
+
+	goneUsed := make([]bool, len(result.Disappeared))
+	addedUsed := make([]bool, len(result.Appeared))
+	matched := 0
+	for _, c := range candidates {
+		if goneUsed[c.gone] || addedUsed[c.added] {
+			continue
+		}
+		goneUsed[c.gone] = true
+		addedUsed[c.added] = true
+		matched++
+
+		oldID := result.Disappeared[c.gone].ExternalID
+		a := result.Appeared[c.added]
+		result.Changed = append(result.Changed, EntityDiff{
+			ExternalID:         a.ExternalID,
+			PreviousExternalID: oldID,
+			Type:               "changed",
+			Changes:            p.diffFields(stored[oldID], a.Content),
+			Content:            a.Content,
+		})
+	}
+
+	result.Appeared = keepUnmatched(result.Appeared, addedUsed)
+	result.Disappeared = keepUnmatched(result.Disappeared, goneUsed)
+	p.Ordering().Apply(result)
+	return matched
+}
+
+func keepUnmatched(diffs []EntityDiff, used []bool) []EntityDiff {
+	kept := diffs[:0]
+	for i, d := range diffs {
+		if !used[i] {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// Similarity scores how alike two entity contents are, from 0 to 1, as the
+// mean similarity of the fields either of them has. System fields (starting
+// with an underscore) are ignored. Strings are compared case-insensitively by
+// edit distance, numbers by their relative difference; a field only one side
+// has scores 0.
+func Similarity(a, b map[string]any) float64 {
+	return newProfile(a).similarity(newProfile(b))
+}
+
+// profile is an entity's content prepared for scoring, so strings are
+// normalized once per entity rather than once per pair.
+type profile map[string]profileValue
+
+type profileValue struct {
+	raw   any
+	runes []rune // normalized, if raw is a string
+	isStr bool
+	num   float64
+	isNum bool
+}
+
+func newProfile(content map[string]any) profile {
+	p := make(profile, len(content))
+	for f, v := range content {
+		if v == nil || strings.HasPrefix(f, "_") {
+			continue
+		}
+		pv := profileValue{raw: v}
+		if s, ok := toString(v); ok {
+			pv.runes = []rune(strings.ToLower(strings.Join(strings.Fields(s), " ")))
+			pv.runes = pv.runes[:min(len(pv.runes), maxCompareRunes)]
+			pv.isStr = true
+		}
+		pv.num, pv.isNum = toFloat64(v)
+		p[f] = pv
+	}
+	return p
+}
+
+// similarity is Similarity of two profiles.
+func (p profile) similarity(o profile) float64 {
+	return p.mean(o, func(a, b profileValue) float64 {
+		return 1 - float64(levenshtein(a.runes, b.runes))/float64(max(len(a.runes), len(b.runes)))
+	})
+}
+
+// bound is an upper bound of similarity that needs no edit distances: two
+// strings are at least as far apart as their lengths differ.
+func (p profile) bound(o profile) float64 {
+	return p.mean(o, func(a, b profileValue) float64 {
+		return float64(min(len(a.runes), len(b.runes))) / float64(max(len(a.runes), len(b.runes)))
+	})
+}
+
+// mean averages the similarity of the fields either profile has, scoring
+// pairs of non-empty strings with strSim.
+func (p profile) mean(o profile, strSim func(a, b profileValue) float64) float64 {
+	total, n := 0.0, 0
+	score := func(a, b profileValue, bothSet bool) {
+		n++
+		switch {
+		case !bothSet:
+		case a.isStr && b.isStr:
+			if len(a.runes) == 0 && len(b.runes) == 0 {
+				total++
+			} else {
+				total += strSim(a, b)
+			}
+		case a.isNum && b.isNum:
+			total += numberSimilarity(a.num, b.num)
+		case valuesEqual(a.raw, b.raw):
+			total++
+		}
+	}
+	for f, a := range p {
+		b, ok := o[f]
+		score(a, b, ok)
+	}
+	for f, b := range o {
+		if _, ok := p[f]; !ok {
+			score(profileValue{}, b, false)
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / float64(n)
+}
+
+func numberSimilarity(a, b float64) float64 {
+	if a == b {
+		return 1
+	}
+	largest := math.Max(math.Abs(a), math.Abs(b))
+	return math.Max(0, 1-math.Abs(a-b)/largest)
+}
+
+// levenshtein returns the edit distance between two rune slices.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,110 @@
+package differ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchRenames(t *testing.T) {
+	stored := map[string]map[string]any{
+		"old-tv":    {"name": "Samsung 55in QLED TV", "price": float64(799)},
+		"old-radio": {"name": "Pocket Radio", "price": float64(25)},
+	}
+	extracted := map[string]map[string]any{
+		"new-tv":  {"name": "Samsung 55\" QLED TV", "price": float64(749)},
+		"blender": {"name": "Kitchen Blender", "price": float64(60)},
+	}
+
+	result := Diff(extracted, stored)
+	matched := Policy{}.MatchRenames(&result, stored, 0.8)
+
+	assert.Equal(t, 1, matched)
+	require.Len(t, result.Changed, 1)
+	renamed := result.Changed[0]
+	assert.Equal(t, "new-tv", renamed.ExternalID)
+	assert.Equal(t, "old-tv", renamed.PreviousExternalID)
+	assert.Equal(t, extracted["new-tv"], renamed.Content)
+	assert.ElementsMatch(t, []FieldChange{
+		{Field: "name", Old: "Samsung 55in QLED TV", New: "Samsung 55\" QLED TV"},
+		{Field: "price", Old: float64(799), New: float64(749)},
+	}, renamed.Changes)
+
+	require.Len(t, result.Appeared, 1)
+	assert.Equal(t, "blender", result.Appeared[0].ExternalID)
+	require.Len(t, result.Disappeared, 1)
+	assert.Equal(t, "old-radio", result.Disappeared[0].ExternalID)
+}
+
+func TestMatchRenames_BestPairFirst(t *testing.T) {
+	stored := map[string]map[string]any{
+		"a": {"name": "Blue Widget Large"},
+	}
+	extracted := map[string]map[string]any{
+		"b": {"name": "Blue Gadget Large"},
+		"c": {"name": "Blue Widget Large!"},
+		"d": {"name": "Blue Widget Large."},
+	}
+
+	result := Diff(extracted, stored)
+	assert.Equal(t, 1, Policy{}.MatchRenames(&result, stored, 0.5))
+
+	// c and d score the same; ties go to the lower external ID.
+	require.Len(t, result.Changed, 1)
+	assert.Equal(t, "c", result.Changed[0].ExternalID)
+	assert.Len(t, result.Appeared, 2)
+	assert.Empty(t, result.Disappeared)
+}
+
+func TestMatchRenames_Off(t *testing.T) {
+	stored := map[string]map[string]any{"a": {"name": "Widget"}}
+	extracted := map[string]map[string]any{"b": {"name": "Widget"}}
+
+	result := Diff(extracted, stored)
+	assert.Equal(t, 0, Policy{}.MatchRenames(&result, stored, 0))
+	assert.Len(t, result.Appeared, 1)
+	assert.Len(t, result.Disappeared, 1)
+	assert.Empty(t, result.Changed)
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]any
+		want float64
+	}{
+		{"identical", map[string]any{"name": "Widget", "price": float64(10)}, map[string]any{"name": "widget ", "price": 10}, 1},
+		{"number halved", map[string]any{"price": float64(10)}, map[string]any{"price": float64(5)}, 0.5},
+		{"opposite signs", map[string]any{"delta": float64(1)}, map[string]any{"delta": float64(-1)}, 0},
+		{"one edit in four", map[string]any{"code": "abcd"}, map[string]any{"code": "abce"}, 0.75},
+		{"field missing", map[string]any{"name": "Widget", "color": "red"}, map[string]any{"name": "Widget"}, 0.5},
+		{"system fields ignored", map[string]any{"name": "Widget", "_position": 1}, map[string]any{"name": "Widget", "_position": 9}, 1},
+		{"nothing to compare", map[string]any{}, map[string]any{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, Similarity(tt.a, tt.b), 1e-9)
+		})
+	}
+}
+
+func TestProfileBound(t *testing.T) {
+	contents := []map[string]any{
+		{"name": "Samsung 55in QLED TV", "price": float64(799)},
+		{"name": "Samsung 55\" QLED TV", "price": float64(749)},
+		{"name": "TV", "price": float64(799), "color": "black"},
+		{"name": "", "sku": "X-1"},
+		{"name": "Pocket Radio", "price": "25"},
+	}
+
+	// The bound may only rule out pairs that really score lower.
+	for _, a := range contents {
+		for _, b := range contents {
+			pa, pb := newProfile(a), newProfile(b)
+			assert.GreaterOrEqual(t, pa.bound(pb)+1e-9, pa.similarity(pb), "%v / %v", a, b)
+		}
+	}
+	assert.InDelta(t, 0.5, newProfile(map[string]any{"code": "ab"}).bound(newProfile(map[string]any{"code": "abcd"})), 1e-9)
+}
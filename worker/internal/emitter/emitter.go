@@ -5,16 +5,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/blueprinter/worker/internal/db/dbgen"
 	"github.com/blueprinter/worker/internal/differ"
 )
 
-// Matcher creates delivery rows for matching subscriptions.
+// eventBatchSize caps the number of events inserted per statement.
+const eventBatchSize = 1000
+
+// Matcher creates delivery rows for matching subscriptions, using q so that
+// deliveries are written in the same transaction as the events.
 type Matcher interface {
-	CreateDeliveries(ctx context.Context, event dbgen.Event) error
+	CreateDeliveries(ctx context.Context, q *dbgen.Queries, events []dbgen.Event) (int, error)
 }
 
 // Emitter persists change events detected by the differ.
@@ -32,6 +39,15 @@ func New(queries *dbgen.Queries, logger *slog.Logger) *Emitter {
 	}
 }
 
+// WithTx returns a copy of the emitter that writes events and deliveries in tx.
+func (e *Emitter) WithTx(tx pgx.Tx) *Emitter {
+	return &Emitter{
+		queries: e.queries.WithTx(tx),
+		matcher: e.matcher,
+		logger:  e.logger,
+	}
+}
+
 // SetMatcher sets the matcher for subscription matching after event emission.
 func (e *Emitter) SetMatcher(m Matcher) {
 	e.matcher = m
@@ -44,135 +60,281 @@ type EmitContext struct {
 	WatchRunID pgtype.UUID
 }
 
-// EmitDiffEvents converts a DiffResult into event rows and persists them.
+// DiffSource describes the watch a diff was computed for and what was stored
+// about the entities it reports as disappeared, which the diff itself lacks.
+type DiffSource struct {
+	WatchID   string
+	Name      string
+	URL       string
+	LastKnown map[string]LastKnown // by external ID
+}
+
+// LastKnown is the stored state of an entity before it disappeared.
+// SourceURL and SourceParam are the page it was last listed on, which for a
+// multi-URL watch isn't the watch's URL.
+type LastKnown struct {
+	Content     map[string]any
+	SourceURL   string
+	SourceParam string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+// PreviewEvent is an event that would be emitted for a diff, without being persisted.
+type PreviewEvent struct {
+	EventType  string          `json:"event_type"`
+	ExternalID string          `json:"external_id"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// EmitDiffEvents converts a DiffResult into event rows and persists them in
+// batches, then matches all of them against subscriptions at once.
 // entityIDs maps external_id -> entity UUID (from upsert results and stored entities).
 // Returns the count of events emitted.
-func (e *Emitter) EmitDiffEvents(ctx context.Context, ec EmitContext, diff *differ.DiffResult, entityIDs map[string]pgtype.UUID) (int, error) {
-	count := 0
+func (e *Emitter) EmitDiffEvents(ctx context.Context, ec EmitContext, diff *differ.DiffResult, src DiffSource, entityIDs map[string]pgtype.UUID) (int, error) {
+	return e.emit(ctx, ec, e.Preview(diff, src), entityIDs)
+}
 
-	for _, d := range diff.Appeared {
-		payload, err := buildAppearedPayload(d)
-		if err != nil {
-			e.logger.Warn("failed to build appeared payload", "external_id", d.ExternalID, "error", err)
-			continue
-		}
+// WatchError describes a watch that has been disabled after repeated failures.
+type WatchError struct {
+	WatchID             string
+	Name                string
+	URL                 string
+	Error               string
+	ConsecutiveFailures int
+}
+
+// EmitWatchError persists a watch_error event and its deliveries.
+func (e *Emitter) EmitWatchError(ctx context.Context, ec EmitContext, we WatchError) error {
+	payload, err := buildWatchErrorPayload(we)
+	if err != nil {
+		return fmt.Errorf("building watch_error payload: %w", err)
+	}
+
+	_, err = e.emit(ctx, ec, []PreviewEvent{{EventType: "watch_error", Payload: payload}}, nil)
+	return err
+}
 
-		entityID := entityIDs[d.ExternalID]
-		event, err := e.queries.InsertEvent(ctx, dbgen.InsertEventParams{
+// WatchSuspect describes a run whose results were discarded because they
+// tripped one of the watch's safety guards.
+type WatchSuspect struct {
+	WatchID          string
+	Name             string
+	URL              string
+	Reason           string
+	EntitiesFound    int
+	EntitiesPrevious int
+	EntitiesMissing  int
+}
+
+// EmitWatchSuspect persists a watch_suspect event and its deliveries.
+func (e *Emitter) EmitWatchSuspect(ctx context.Context, ec EmitContext, ws WatchSuspect) error {
+	payload, err := buildWatchSuspectPayload(ws)
+	if err != nil {
+		return fmt.Errorf("building watch_suspect payload: %w", err)
+	}
+
+	_, err = e.emit(ctx, ec, []PreviewEvent{{EventType: "watch_suspect", Payload: payload}}, nil)
+	return err
+}
+
+// emit inserts events in batches and creates their deliveries.
+func (e *Emitter) emit(ctx context.Context, ec EmitContext, previews []PreviewEvent, entityIDs map[string]pgtype.UUID) (int, error) {
+	events := make([]dbgen.Event, 0, len(previews))
+
+	for start := 0; start < len(previews); start += eventBatchSize {
+		batch := previews[start:min(start+eventBatchSize, len(previews))]
+
+		params := dbgen.InsertEventsParams{
 			OrgID:      ec.OrgID,
-			EventType:  "entity_appeared",
 			WatchID:    ec.WatchID,
 			WatchRunID: ec.WatchRunID,
-			EntityID:   entityID,
-			Payload:    payload,
-		})
+			EventTypes: make([]string, len(batch)),
+			EntityIds:  make([]pgtype.UUID, len(batch)),
+			Payloads:   make([]string, len(batch)),
+		}
+		for i, pe := range batch {
+			params.EventTypes[i] = pe.EventType
+			params.EntityIds[i] = entityIDs[pe.ExternalID]
+			params.Payloads[i] = string(pe.Payload)
+		}
+
+		inserted, err := e.queries.InsertEvents(ctx, params)
 		if err != nil {
-			return count, fmt.Errorf("inserting entity_appeared event: %w", err)
+			return 0, fmt.Errorf("inserting events: %w", err)
 		}
-		e.matchEvent(ctx, event)
-		count++
+		events = append(events, inserted...)
 	}
 
-	for _, d := range diff.Changed {
-		payload, err := buildChangedPayload(d)
+	if err := e.matchEvents(ctx, events); err != nil {
+		return 0, err
+	}
+
+	return len(events), nil
+}
+
+// Preview builds the events EmitDiffEvents would persist for a diff, in the
+// same order, without touching the database.
+func (e *Emitter) Preview(diff *differ.DiffResult, src DiffSource) []PreviewEvent {
+	events := make([]PreviewEvent, 0, len(diff.Appeared)+len(diff.Changed)+len(diff.Disappeared))
+
+	add := func(eventType string, d differ.EntityDiff, build func(differ.EntityDiff) ([]byte, error)) {
+		payload, err := build(d)
 		if err != nil {
-			e.logger.Warn("failed to build changed payload", "external_id", d.ExternalID, "error", err)
-			continue
+			e.logger.Warn("failed to build "+eventType+" payload", "external_id", d.ExternalID, "error", err)
+			return
 		}
-
-		entityID := entityIDs[d.ExternalID]
-		event, err := e.queries.InsertEvent(ctx, dbgen.InsertEventParams{
-			OrgID:      ec.OrgID,
-			EventType:  "entity_changed",
-			WatchID:    ec.WatchID,
-			WatchRunID: ec.WatchRunID,
-			EntityID:   entityID,
+		events = append(events, PreviewEvent{
+			EventType:  eventType,
+			ExternalID: d.ExternalID,
 			Payload:    payload,
 		})
-		if err != nil {
-			return count, fmt.Errorf("inserting entity_changed event: %w", err)
-		}
-		e.matchEvent(ctx, event)
-		count++
 	}
 
+	for _, d := range diff.Appeared {
+		add("entity_appeared", d, func(d differ.EntityDiff) ([]byte, error) {
+			return buildAppearedPayload(d, diff.Ordering.Fields)
+		})
+	}
+	for _, d := range diff.Changed {
+		add("entity_changed", d, buildChangedPayload)
+	}
 	for _, d := range diff.Disappeared {
-		payload, err := buildDisappearedPayload(d)
-		if err != nil {
-			e.logger.Warn("failed to build disappeared payload", "external_id", d.ExternalID, "error", err)
-			continue
-		}
-
-		entityID := entityIDs[d.ExternalID]
-		event, err := e.queries.InsertEvent(ctx, dbgen.InsertEventParams{
-			OrgID:      ec.OrgID,
-			EventType:  "entity_disappeared",
-			WatchID:    ec.WatchID,
-			WatchRunID: ec.WatchRunID,
-			EntityID:   entityID,
-			Payload:    payload,
+		add("entity_disappeared", d, func(d differ.EntityDiff) ([]byte, error) {
+			return buildDisappearedPayload(d, src, diff.Ordering.Fields)
 		})
-		if err != nil {
-			return count, fmt.Errorf("inserting entity_disappeared event: %w", err)
-		}
-		e.matchEvent(ctx, event)
-		count++
 	}
 
-	return count, nil
+	return events
 }
 
-// matchEvent calls the matcher to create deliveries for the given event.
-// Errors are logged but do not fail event emission.
-func (e *Emitter) matchEvent(ctx context.Context, event dbgen.Event) {
-	if e.matcher == nil {
-		return
+// matchEvents calls the matcher to create deliveries for the given events.
+// An event must never be persisted without its deliveries, so errors fail
+// the emission.
+func (e *Emitter) matchEvents(ctx context.Context, events []dbgen.Event) error {
+	if e.matcher == nil || len(events) == 0 {
+		return nil
 	}
-	if err := e.matcher.CreateDeliveries(ctx, event); err != nil {
-		e.logger.Warn("failed to create deliveries for event",
-			"event_id", event.ID,
-			"event_type", event.EventType,
-			"error", err,
-		)
+	if _, err := e.matcher.CreateDeliveries(ctx, e.queries, events); err != nil {
+		return fmt.Errorf("creating deliveries: %w", err)
 	}
+	return nil
 }
 
 // appearedPayload is the JSON structure for entity_appeared events.
+// FieldOrder is the schema's field order, for rendering the entity's fields.
 type appearedPayload struct {
-	Entity map[string]any `json:"entity"`
+	Entity     map[string]any `json:"entity"`
+	FieldOrder []string       `json:"field_order,omitempty"`
 }
 
 // changedPayload is the JSON structure for entity_changed events.
+// An entity matched across an identity change carries identity_changed and
+// the external ID it had before.
 type changedPayload struct {
-	Changes []differ.FieldChange `json:"changes"`
-	Entity  map[string]any       `json:"entity"`
+	Changes            []differ.FieldChange `json:"changes"`
+	Entity             map[string]any       `json:"entity"`
+	IdentityChanged    bool                 `json:"identity_changed,omitempty"`
+	PreviousExternalID string               `json:"previous_external_id,omitempty"`
 }
 
 // disappearedPayload is the JSON structure for entity_disappeared events.
+// The entity is its last stored content, plus its external_id unless the
+// content has a field of that name. SourceURL and SourceParam are the page
+// the entity was last listed on.
 type disappearedPayload struct {
-	Entity map[string]any `json:"entity"`
+	ExternalID  string         `json:"external_id"`
+	Entity      map[string]any `json:"entity"`
+	FieldOrder  []string       `json:"field_order,omitempty"`
+	SourceURL   string         `json:"source_url,omitempty"`
+	SourceParam string         `json:"source_param,omitempty"`
+	FirstSeenAt *time.Time     `json:"first_seen_at,omitempty"`
+	LastSeenAt  *time.Time     `json:"last_seen_at,omitempty"`
+	Watch       watchRef       `json:"watch"`
+}
+
+// watchRef identifies a watch in event payloads.
+type watchRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// watchErrorPayload is the JSON structure for watch_error events.
+type watchErrorPayload struct {
+	Watch               watchRef `json:"watch"`
+	Error               string   `json:"error"`
+	ConsecutiveFailures int      `json:"consecutive_failures"`
 }
 
-func buildAppearedPayload(d differ.EntityDiff) ([]byte, error) {
+// watchSuspectPayload is the JSON structure for watch_suspect events.
+type watchSuspectPayload struct {
+	Watch            watchRef `json:"watch"`
+	Reason           string   `json:"reason"`
+	EntitiesFound    int      `json:"entities_found"`
+	EntitiesPrevious int      `json:"entities_previous"`
+	EntitiesMissing  int      `json:"entities_missing"`
+}
+
+func buildAppearedPayload(d differ.EntityDiff, fieldOrder []string) ([]byte, error) {
 	p := appearedPayload{
-		Entity: d.Content,
+		Entity:     d.Content,
+		FieldOrder: fieldOrder,
 	}
 	return json.Marshal(p)
 }
 
 func buildChangedPayload(d differ.EntityDiff) ([]byte, error) {
 	p := changedPayload{
-		Changes: d.Changes,
-		Entity:  d.Content,
+		Changes:            d.Changes,
+		Entity:             d.Content,
+		IdentityChanged:    d.PreviousExternalID != "",
+		PreviousExternalID: d.PreviousExternalID,
 	}
 	return json.Marshal(p)
 }
 
-func buildDisappearedPayload(d differ.EntityDiff) ([]byte, error) {
+func buildDisappearedPayload(d differ.EntityDiff, src DiffSource, fieldOrder []string) ([]byte, error) {
+	last := src.LastKnown[d.ExternalID]
+	entity := make(map[string]any, len(last.Content)+1)
+	maps.Copy(entity, last.Content)
+	if _, ok := entity["external_id"]; !ok {
+		entity["external_id"] = d.ExternalID
+	}
+
 	p := disappearedPayload{
-		Entity: map[string]any{
-			"external_id": d.ExternalID,
-		},
+		ExternalID:  d.ExternalID,
+		Entity:      entity,
+		FieldOrder:  fieldOrder,
+		SourceURL:   last.SourceURL,
+		SourceParam: last.SourceParam,
+		Watch:       watchRef{ID: src.WatchID, Name: src.Name, URL: src.URL},
+	}
+	if !last.FirstSeenAt.IsZero() {
+		p.FirstSeenAt = &last.FirstSeenAt
+	}
+	if !last.LastSeenAt.IsZero() {
+		p.LastSeenAt = &last.LastSeenAt
+	}
+	return json.Marshal(p)
+}
+
+func buildWatchErrorPayload(we WatchError) ([]byte, error) {
+	p := watchErrorPayload{
+		Watch:               watchRef{ID: we.WatchID, Name: we.Name, URL: we.URL},
+		Error:               we.Error,
+		ConsecutiveFailures: we.ConsecutiveFailures,
+	}
+	return json.Marshal(p)
+}
+
+func buildWatchSuspectPayload(ws WatchSuspect) ([]byte, error) {
+	p := watchSuspectPayload{
+		Watch:            watchRef{ID: ws.WatchID, Name: ws.Name, URL: ws.URL},
+		Reason:           ws.Reason,
+		EntitiesFound:    ws.EntitiesFound,
+		EntitiesPrevious: ws.EntitiesPrevious,
+		EntitiesMissing:  ws.EntitiesMissing,
 	}
 	return json.Marshal(p)
 }
@@ -2,7 +2,10 @@ package emitter
 
 import (
 	"encoding/json"
+	"io"
+	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,7 +25,7 @@ func TestBuildAppearedPayload(t *testing.T) {
 		},
 	}
 
-	payload, err := buildAppearedPayload(d)
+	payload, err := buildAppearedPayload(d, nil)
 	require.NoError(t, err)
 
 	var result appearedPayload
@@ -75,15 +78,80 @@ func TestBuildDisappearedPayload(t *testing.T) {
 		ExternalID: "abc123",
 		Type:       "disappeared",
 	}
+	src := DiffSource{
+		WatchID: "w-1",
+		Name:    "Headphones",
+		URL:     "https://shop.example.com/headphones",
+		LastKnown: map[string]LastKnown{
+			"abc123": {
+				Content:     map[string]any{"name": "Sony WH-1000XM5", "price": float64(24999)},
+				SourceURL:   "https://shop.example.com/headphones?page=2",
+				SourceParam: "2",
+				FirstSeenAt: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+				LastSeenAt:  time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	payload, err := buildDisappearedPayload(d, src, nil)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"external_id": "abc123",
+		"entity": {"external_id": "abc123", "name": "Sony WH-1000XM5", "price": 24999},
+		"source_url": "https://shop.example.com/headphones?page=2",
+		"source_param": "2",
+		"first_seen_at": "2026-03-01T09:00:00Z",
+		"last_seen_at": "2026-03-08T09:00:00Z",
+		"watch": {"id": "w-1", "name": "Headphones", "url": "https://shop.example.com/headphones"}
+	}`, string(payload))
+	// The stored content is copied, not modified.
+	assert.NotContains(t, src.LastKnown["abc123"].Content, "external_id")
+}
+
+func TestBuildDisappearedPayload_NothingKnown(t *testing.T) {
+	d := differ.EntityDiff{
+		ExternalID: "abc123",
+		Type:       "disappeared",
+	}
+
+	payload, err := buildDisappearedPayload(d, DiffSource{WatchID: "w-1", Name: "Headphones"}, nil)
+	require.NoError(t, err)
+
+	var result disappearedPayload
+	err = json.Unmarshal(payload, &result)
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc123", result.ExternalID)
+	assert.Equal(t, map[string]any{"external_id": "abc123"}, result.Entity)
+	assert.Empty(t, result.SourceURL)
+	assert.Nil(t, result.FirstSeenAt)
+	assert.Nil(t, result.LastSeenAt)
+	assert.Equal(t, "Headphones", result.Watch.Name)
+}
+
+func TestBuildDisappearedPayload_ExternalIDField(t *testing.T) {
+	d := differ.EntityDiff{
+		ExternalID: "abc123",
+		Type:       "disappeared",
+	}
+	src := DiffSource{
+		WatchID: "w-1",
+		LastKnown: map[string]LastKnown{
+			"abc123": {Content: map[string]any{"external_id": "SKU-9"}},
+		},
+	}
 
-	payload, err := buildDisappearedPayload(d)
+	payload, err := buildDisappearedPayload(d, src, nil)
 	require.NoError(t, err)
 
 	var result disappearedPayload
 	err = json.Unmarshal(payload, &result)
 	require.NoError(t, err)
 
-	assert.Equal(t, "abc123", result.Entity["external_id"])
+	// A content field of that name is kept; the key is at the top level.
+	assert.Equal(t, "abc123", result.ExternalID)
+	assert.Equal(t, map[string]any{"external_id": "SKU-9"}, result.Entity)
 }
 
 func TestBuildAppearedPayload_EmptyContent(t *testing.T) {
@@ -93,7 +161,7 @@ func TestBuildAppearedPayload_EmptyContent(t *testing.T) {
 		Content:    map[string]any{},
 	}
 
-	payload, err := buildAppearedPayload(d)
+	payload, err := buildAppearedPayload(d, nil)
 	require.NoError(t, err)
 
 	var result appearedPayload
@@ -126,4 +194,119 @@ func TestBuildChangedPayload_SingleChange(t *testing.T) {
 	assert.Len(t, result.Changes, 1)
 	assert.Equal(t, "price", result.Changes[0].Field)
 	assert.Equal(t, "Product X", result.Entity["name"])
+	assert.False(t, result.IdentityChanged)
+	assert.NotContains(t, string(payload), "previous_external_id")
+}
+
+func TestBuildChangedPayload_IdentityChanged(t *testing.T) {
+	d := differ.EntityDiff{
+		ExternalID:         "new-id",
+		PreviousExternalID: "old-id",
+		Type:               "changed",
+		Changes: []differ.FieldChange{
+			{Field: "name", Old: "Product X", New: "Product X2"},
+		},
+		Content: map[string]any{"name": "Product X2"},
+	}
+
+	payload, err := buildChangedPayload(d)
+	require.NoError(t, err)
+
+	var result changedPayload
+	err = json.Unmarshal(payload, &result)
+	require.NoError(t, err)
+
+	assert.True(t, result.IdentityChanged)
+	assert.Equal(t, "old-id", result.PreviousExternalID)
+	assert.Equal(t, "Product X2", result.Entity["name"])
+}
+
+func TestPreview_OrderAndTypes(t *testing.T) {
+	e := New(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	diff := &differ.DiffResult{
+		Appeared:    []differ.EntityDiff{{ExternalID: "a", Type: "appeared", Content: map[string]any{"name": "A"}}},
+		Changed:     []differ.EntityDiff{{ExternalID: "b", Type: "changed", Changes: []differ.FieldChange{{Field: "price", Old: 1, New: 2}}}},
+		Disappeared: []differ.EntityDiff{{ExternalID: "c", Type: "disappeared"}},
+	}
+
+	src := DiffSource{
+		WatchID:   "w-1",
+		Name:      "Headphones",
+		URL:       "https://shop.example.com/headphones",
+		LastKnown: map[string]LastKnown{"c": {Content: map[string]any{"name": "C"}}},
+	}
+
+	events := e.Preview(diff, src)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, "entity_appeared", events[0].EventType)
+	assert.Equal(t, "a", events[0].ExternalID)
+	assert.Equal(t, "entity_changed", events[1].EventType)
+	assert.Equal(t, "b", events[1].ExternalID)
+	assert.Equal(t, "entity_disappeared", events[2].EventType)
+	assert.JSONEq(t, `{
+		"external_id": "c",
+		"entity": {"external_id": "c", "name": "C"},
+		"watch": {"id": "w-1", "name": "Headphones", "url": "https://shop.example.com/headphones"}
+	}`, string(events[2].Payload))
+}
+
+func TestPreview_FieldOrder(t *testing.T) {
+	e := New(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	diff := &differ.DiffResult{
+		Appeared:    []differ.EntityDiff{{ExternalID: "a", Type: "appeared", Content: map[string]any{"name": "A"}}},
+		Disappeared: []differ.EntityDiff{{ExternalID: "c", Type: "disappeared"}},
+		Ordering:    differ.Ordering{Fields: []string{"name", "price"}},
+	}
+
+	events := e.Preview(diff, DiffSource{WatchID: "w-1"})
+	require.Len(t, events, 2)
+
+	for _, ev := range events {
+		var result struct {
+			FieldOrder []string `json:"field_order"`
+		}
+		require.NoError(t, json.Unmarshal(ev.Payload, &result))
+		assert.Equal(t, []string{"name", "price"}, result.FieldOrder, ev.EventType)
+	}
+}
+
+func TestBuildWatchErrorPayload(t *testing.T) {
+	payload, err := buildWatchErrorPayload(WatchError{
+		WatchID:             "w-1",
+		Name:                "Headphones",
+		URL:                 "https://shop.example.com/headphones",
+		Error:               "fetching HTML: timeout",
+		ConsecutiveFailures: 3,
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"watch": {"id": "w-1", "name": "Headphones", "url": "https://shop.example.com/headphones"},
+		"error": "fetching HTML: timeout",
+		"consecutive_failures": 3
+	}`, string(payload))
+}
+
+func TestBuildWatchSuspectPayload(t *testing.T) {
+	payload, err := buildWatchSuspectPayload(WatchSuspect{
+		WatchID:          "w-1",
+		Name:             "Headphones",
+		URL:              "https://shop.example.com/headphones",
+		Reason:           "0 entities extracted, minimum is 1",
+		EntitiesFound:    0,
+		EntitiesPrevious: 42,
+		EntitiesMissing:  42,
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"watch": {"id": "w-1", "name": "Headphones", "url": "https://shop.example.com/headphones"},
+		"reason": "0 entities extracted, minimum is 1",
+		"entities_found": 0,
+		"entities_previous": 42,
+		"entities_missing": 42
+	}`, string(payload))
 }
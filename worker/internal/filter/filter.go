@@ -101,19 +101,41 @@ func evaluateChangedCondition(parsed map[string]json.RawMessage, c Condition) (b
 			return compareNumeric(change.Old, change.New, func(o, n float64) bool { return n < o })
 		case "eq":
 			return valueEquals(change.New, c.Value), nil
+		case "moved_up", "moved_down":
+			by, err := rankThreshold(c, 1)
+			if err != nil {
+				return false, err
+			}
+			if c.Operator == "moved_up" {
+				return compareNumeric(change.Old, change.New, func(o, n float64) bool { return o-n >= by })
+			}
+			return compareNumeric(change.Old, change.New, func(o, n float64) bool { return n-o >= by })
+		case "entered_top":
+			k, err := rankThreshold(c, 0)
+			if err != nil {
+				return false, err
+			}
+			newF, ok := toFloat64(change.New)
+			if !ok || newF > k {
+				return false, nil
+			}
+			oldF, ok := toFloat64(change.Old)
+			return !ok || oldF > k, nil
 		}
 	}
 	return false, nil
 }
 
 // evaluateAppearedCondition checks against payload.entity[field].
-// Direction operators (increased, decreased, changed) pass automatically for appeared entities.
+// Direction operators (increased, decreased, changed, moved_up, moved_down)
+// pass automatically for appeared entities; entered_top checks the rank the
+// entity appeared at.
 func evaluateAppearedCondition(parsed map[string]json.RawMessage, c Condition) (bool, error) {
 	switch c.Operator {
-	case "changed", "increased", "decreased":
+	case "changed", "increased", "decreased", "moved_up", "moved_down":
 		// Direction operators pass automatically for new entities
 		return true, nil
-	case "eq":
+	case "eq", "entered_top":
 		entityRaw, ok := parsed["entity"]
 		if !ok {
 			return false, nil
@@ -126,12 +148,34 @@ func evaluateAppearedCondition(parsed map[string]json.RawMessage, c Condition) (
 		if !exists {
 			return false, nil
 		}
-		return valueEquals(fieldVal, c.Value), nil
+		if c.Operator == "eq" {
+			return valueEquals(fieldVal, c.Value), nil
+		}
+		k, err := rankThreshold(c, 0)
+		if err != nil {
+			return false, err
+		}
+		rank, ok := toFloat64(fieldVal)
+		return ok && rank <= k, nil
 	default:
 		return false, nil
 	}
 }
 
+// rankThreshold reads the numeric value of a rank operator. Ranks count from
+// 1 at the top, so moving up means the value decreases. def is used when the
+// condition has no value; 0 means a value is required.
+func rankThreshold(c Condition, def float64) (float64, error) {
+	if c.Value == nil && def > 0 {
+		return def, nil
+	}
+	v, ok := toFloat64(c.Value)
+	if !ok || v <= 0 {
+		return 0, fmt.Errorf("operator %s needs a positive numeric value, got %v", c.Operator, c.Value)
+	}
+	return v, nil
+}
+
 // compareNumeric attempts to compare old and new values numerically.
 func compareNumeric(old, new any, cmp func(float64, float64) bool) (bool, error) {
 	oldF, okOld := toFloat64(old)
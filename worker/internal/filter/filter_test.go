@@ -174,6 +174,64 @@ func TestMatch(t *testing.T) {
 			filters:   Filters{Conditions: []Condition{{Field: "name", Operator: "decreased"}}},
 			want:      false,
 		},
+
+		// --- Rank operators ---
+		{
+			name:      "moved_up matches when rank improved by at least N",
+			eventType: "entity_changed",
+			payload:   `{"changes":[{"field":"_position","old":12,"new":4}],"entity":{"name":"Foo"}}`,
+			filters:   Filters{Conditions: []Condition{{Field: "_position", Operator: "moved_up", Value: float64(5)}}},
+			want:      true,
+		},
+		{
+			name:      "moved_up no match when rank improved by less than N",
+			eventType: "entity_changed",
+			payload:   `{"changes":[{"field":"_position","old":6,"new":4}],"entity":{"name":"Foo"}}`,
+			filters:   Filters{Conditions: []Condition{{Field: "_position", Operator: "moved_up", Value: "5"}}},
+			want:      false,
+		},
+		{
+			name:      "moved_up defaults to one place",
+			eventType: "entity_changed",
+			payload:   `{"changes":[{"field":"_position","old":5,"new":4}],"entity":{"name":"Foo"}}`,
+			filters:   Filters{Conditions: []Condition{{Field: "_position", Operator: "moved_up"}}},
+			want:      true,
+		},
+		{
+			name:      "moved_down matches when rank dropped",
+			eventType: "entity_changed",
+			payload:   `{"changes":[{"field":"_position","old":2,"new":9}],"entity":{"name":"Foo"}}`,
+			filters:   Filters{Conditions: []Condition{{Field: "_position", Operator: "moved_down", Value: float64(3)}}},
+			want:      true,
+		},
+		{
+			name:      "entered_top matches when rank crosses into top K",
+			eventType: "entity_changed",
+			payload:   `{"changes":[{"field":"_position","old":14,"new":3}],"entity":{"name":"Foo"}}`,
+			filters:   Filters{Conditions: []Condition{{Field: "_position", Operator: "entered_top", Value: float64(10)}}},
+			want:      true,
+		},
+		{
+			name:      "entered_top no match when already in top K",
+			eventType: "entity_changed",
+			payload:   `{"changes":[{"field":"_position","old":8,"new":3}],"entity":{"name":"Foo"}}`,
+			filters:   Filters{Conditions: []Condition{{Field: "_position", Operator: "entered_top", Value: float64(10)}}},
+			want:      false,
+		},
+		{
+			name:      "entered_top requires a value",
+			eventType: "entity_changed",
+			payload:   `{"changes":[{"field":"_position","old":14,"new":3}],"entity":{"name":"Foo"}}`,
+			filters:   Filters{Conditions: []Condition{{Field: "_position", Operator: "entered_top"}}},
+			wantErr:   true,
+		},
+		{
+			name:      "appeared: entered_top checks the rank it appeared at",
+			eventType: "entity_appeared",
+			payload:   `{"entity":{"name":"New Product","_position":7}}`,
+			filters:   Filters{Conditions: []Condition{{Field: "_position", Operator: "entered_top", Value: float64(5)}}},
+			want:      false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,182 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Normalizations that may be applied to identity values before hashing.
+const (
+	NormalizeLowercase      = "lowercase"             // compare values case-insensitively
+	NormalizeTrackingParams = "strip_tracking_params" // drop utm_* and click IDs from URLs
+)
+
+// pathModifier, appended to a field name, identifies by the path of the
+// field's URL value alone, e.g. "url:path".
+const pathModifier = ":path"
+
+// trackingParams are query parameters that identify a visit rather than a page.
+var trackingParams = map[string]bool{
+	"gclid": true, "dclid": true, "gbraid": true, "wbraid": true,
+	"fbclid": true, "msclkid": true, "yclid": true, "igshid": true,
+	"mc_cid": true, "mc_eid": true, "_ga": true, "_gl": true,
+	"ref": true, "ref_src": true, "srsltid": true,
+}
+
+// Spec describes how entities are identified: by the primary identity
+// fields, or, for entities missing one of them, by the first fallback field
+// set they have all values for.
+type Spec struct {
+	Fields    []string
+	Fallbacks [][]string
+
+	lowercase     bool
+	stripTracking bool
+}
+
+// NewSpec builds a Spec from a watch's identity configuration.
+func NewSpec(fields []string, fallbacks [][]string, normalize []string) (Spec, error) {
+	s := Spec{Fields: fields, Fallbacks: fallbacks}
+	for _, n := range normalize {
+		switch n {
+		case NormalizeLowercase:
+			s.lowercase = true
+		case NormalizeTrackingParams:
+			s.stripTracking = true
+		default:
+			return Spec{}, fmt.Errorf("unknown identity normalization %q", n)
+		}
+	}
+	for _, set := range fallbacks {
+		if len(set) == 0 {
+			return Spec{}, errors.New("empty identity fallback")
+		}
+	}
+	return s, nil
+}
+
+// Key returns the external ID of an entity. The primary fields are used when
+// all of them have a value, otherwise the first complete fallback; failing
+// both, the primary fields are used as long as one of them has a value. ok
+// is false when the entity has no identity at all.
+//
+// An entity identified by its primary fields without normalization keeps the
+// ID ExternalID gives it.
+func (s Spec) Key(entity map[string]any) (id string, ok bool) {
+	primary := s.values(entity, s.Fields)
+	if complete(primary) {
+		return hash(primary), true
+	}
+	for _, set := range s.Fallbacks {
+		if values := s.values(entity, set); complete(values) {
+			return hash(labelled(set, values)), true
+		}
+	}
+	for _, v := range primary {
+		if v != "" {
+			return hash(primary), true
+		}
+	}
+	return "", false
+}
+
+// ExternalID creates a SHA-256 hash from the identity field values.
+func ExternalID(entity map[string]any, identityFields []string) string {
+	return hash(Spec{Fields: identityFields}.values(entity, identityFields))
+}
+
+// values returns the normalized values of fields, sorted by field name for
+// deterministic hashing. Missing fields have an empty value.
+func (s Spec) values(entity map[string]any, fields []string) []string {
+	sorted := make([]string, len(fields))
+	copy(sorted, fields)
+	sort.Strings(sorted)
+
+	parts := make([]string, len(sorted))
+	for i, field := range sorted {
+		name, pathOnly := strings.CutSuffix(field, pathModifier)
+		val, ok := entity[name]
+		if !ok || val == nil {
+			continue
+		}
+		v := strings.TrimSpace(fmt.Sprintf("%v", val))
+		if pathOnly {
+			v = urlPath(v)
+		} else if s.stripTracking {
+			v = stripTrackingParams(v)
+		}
+		if s.lowercase {
+			v = strings.ToLower(v)
+		}
+		parts[i] = v
+	}
+	return parts
+}
+
+func complete(values []string) bool {
+	for _, v := range values {
+		if v == "" {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+// labelled prefixes fallback values with their field names, so an ID derived
+// from a fallback can't equal one derived from other fields with the same
+// values.
+func labelled(fields, values []string) []string {
+	sorted := make([]string, len(fields))
+	copy(sorted, fields)
+	sort.Strings(sorted)
+
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = sorted[i] + "=" + v
+	}
+	return out
+}
+
+func hash(parts []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return fmt.Sprintf("%x", sum[:16]) // 32 hex chars
+}
+
+// urlPath returns the path of a URL, without trailing slash, or the value
+// unchanged if it isn't one.
+func urlPath(v string) string {
+	u, err := url.Parse(v)
+	if err != nil || (u.Host == "" && !strings.HasPrefix(v, "/")) {
+		return v
+	}
+	if p := strings.TrimSuffix(u.Path, "/"); p != "" {
+		return p
+	}
+	return "/"
+}
+
+// stripTrackingParams removes tracking query parameters from a URL, leaving
+// other values unchanged.
+func stripTrackingParams(v string) string {
+	u, err := url.Parse(v)
+	if err != nil || u.RawQuery == "" || (u.Host == "" && !strings.HasPrefix(v, "/")) {
+		return v
+	}
+	q := u.Query()
+	stripped := false
+	for key := range q {
+		if k := strings.ToLower(key); trackingParams[k] || strings.HasPrefix(k, "utm_") {
+			q.Del(key)
+			stripped = true
+		}
+	}
+	if !stripped {
+		return v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,73 @@
+package identity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey_PrimaryMatchesExternalID(t *testing.T) {
+	entity := map[string]any{"name": " Widget ", "seller": "Acme"}
+	spec, err := NewSpec([]string{"seller", "name"}, [][]string{{"url:path"}}, nil)
+	require.NoError(t, err)
+
+	id, ok := spec.Key(entity)
+	assert.True(t, ok)
+	assert.Equal(t, ExternalID(entity, []string{"name", "seller"}), id)
+}
+
+func TestKey_Fallback(t *testing.T) {
+	spec, err := NewSpec([]string{"name"}, [][]string{{"url:path"}, {"seller", "sku"}}, nil)
+	require.NoError(t, err)
+
+	a, ok := spec.Key(map[string]any{"url": "https://shop.example/p/123?utm_source=x"})
+	require.True(t, ok)
+	b, _ := spec.Key(map[string]any{"url": "https://m.shop.example/p/123/"})
+	assert.Equal(t, a, b, "the path alone identifies")
+
+	c, ok := spec.Key(map[string]any{"seller": "Acme", "sku": "123"})
+	require.True(t, ok)
+	assert.NotEqual(t, a, c)
+
+	// A fallback ID never equals a primary ID with the same value.
+	d, _ := spec.Key(map[string]any{"name": "/p/123"})
+	assert.NotEqual(t, a, d)
+}
+
+func TestKey_PartialPrimaryWithoutFallback(t *testing.T) {
+	spec, err := NewSpec([]string{"name", "seller"}, nil, nil)
+	require.NoError(t, err)
+
+	entity := map[string]any{"name": "Widget"}
+	id, ok := spec.Key(entity)
+	assert.True(t, ok)
+	assert.Equal(t, ExternalID(entity, []string{"name", "seller"}), id)
+}
+
+func TestKey_Missing(t *testing.T) {
+	spec, err := NewSpec([]string{"name"}, [][]string{{"url"}}, nil)
+	require.NoError(t, err)
+
+	_, ok := spec.Key(map[string]any{"price": 100, "name": "  "})
+	assert.False(t, ok)
+}
+
+func TestKey_Normalization(t *testing.T) {
+	spec, err := NewSpec([]string{"url"}, nil, []string{NormalizeLowercase, NormalizeTrackingParams})
+	require.NoError(t, err)
+
+	a, _ := spec.Key(map[string]any{"url": "https://Shop.example/P/1?color=red&utm_campaign=spring&gclid=abc"})
+	b, _ := spec.Key(map[string]any{"url": "https://shop.example/p/1?color=red"})
+	c, _ := spec.Key(map[string]any{"url": "https://shop.example/p/1?color=blue"})
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestNewSpec_Invalid(t *testing.T) {
+	_, err := NewSpec([]string{"name"}, nil, []string{"uppercase"})
+	assert.EqualError(t, err, `unknown identity normalization "uppercase"`)
+
+	_, err = NewSpec([]string{"name"}, [][]string{{}}, nil)
+	assert.EqualError(t, err, "empty identity fallback")
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/blueprinter/worker/internal/db/dbgen"
 	"github.com/blueprinter/worker/internal/filter"
@@ -11,74 +14,117 @@ import (
 
 // Matcher matches events to subscriptions and creates delivery rows.
 type Matcher struct {
-	queries *dbgen.Queries
-	logger  *slog.Logger
+	logger *slog.Logger
 }
 
 // New creates a new Matcher.
-func New(queries *dbgen.Queries, logger *slog.Logger) *Matcher {
+func New(logger *slog.Logger) *Matcher {
 	return &Matcher{
-		queries: queries,
-		logger:  logger,
+		logger: logger,
 	}
 }
 
-// CreateDeliveries finds subscriptions that match the given event and creates delivery rows.
-func (m *Matcher) CreateDeliveries(ctx context.Context, event dbgen.Event) error {
-	subs, err := m.queries.MatchSubscriptions(ctx, dbgen.MatchSubscriptionsParams{
-		OrgID:   event.OrgID,
-		Column2: event.EventType,
-		WatchID: event.WatchID,
-	})
-	if err != nil {
-		return fmt.Errorf("matching subscriptions: %w", err)
-	}
+// watchKey identifies the subscription set that applies to a watch.
+type watchKey struct {
+	orgID   string
+	watchID pgtype.UUID
+}
 
-	if len(subs) == 0 {
-		return nil
-	}
+// candidate is a subscription with its filters parsed once per batch.
+type candidate struct {
+	sub     dbgen.Subscription
+	filters filter.Filters
+}
 
-	matched := 0
-	for _, sub := range subs {
-		filters, err := filter.ParseFilters(sub.Filters)
-		if err != nil {
-			m.logger.Warn("failed to parse subscription filters",
-				"subscription_id", sub.ID,
-				"error", err,
-			)
-			continue
-		}
+// CreateDeliveries finds subscriptions that match the given events and
+// creates their delivery rows. Subscriptions are loaded and their filters
+// parsed once per watch rather than once per event, and deliveries are
+// inserted in one statement. Queries run on q, so deliveries can share the
+// transaction that inserted the events. Returns the number of deliveries created.
+func (m *Matcher) CreateDeliveries(ctx context.Context, q *dbgen.Queries, events []dbgen.Event) (int, error) {
+	candidates := make(map[watchKey][]candidate)
+	byOrg := make(map[string]*dbgen.InsertDeliveriesParams)
 
-		ok, err := filter.Match(event.EventType, event.Payload, filters)
-		if err != nil {
-			m.logger.Warn("failed to evaluate filters",
-				"subscription_id", sub.ID,
-				"error", err,
-			)
-			continue
-		}
+	for i := range events {
+		event := &events[i]
+		key := watchKey{orgID: event.OrgID, watchID: event.WatchID}
 
+		cands, ok := candidates[key]
 		if !ok {
-			continue
+			var err error
+			cands, err = m.loadCandidates(ctx, q, key)
+			if err != nil {
+				return 0, err
+			}
+			candidates[key] = cands
 		}
 
-		if _, err := m.queries.InsertDelivery(ctx, dbgen.InsertDeliveryParams{
-			OrgID:          event.OrgID,
-			EventID:        event.ID,
-			SubscriptionID: sub.ID,
-		}); err != nil {
-			return fmt.Errorf("inserting delivery for subscription %v: %w", sub.ID, err)
+		for _, c := range cands {
+			if !slices.Contains(c.sub.EventTypes, event.EventType) {
+				continue
+			}
+
+			ok, err := filter.Match(event.EventType, event.Payload, c.filters)
+			if err != nil {
+				m.logger.Warn("failed to evaluate filters",
+					"subscription_id", c.sub.ID,
+					"error", err,
+				)
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			params := byOrg[event.OrgID]
+			if params == nil {
+				params = &dbgen.InsertDeliveriesParams{OrgID: event.OrgID}
+				byOrg[event.OrgID] = params
+			}
+			params.EventIds = append(params.EventIds, event.ID)
+			params.SubscriptionIds = append(params.SubscriptionIds, c.sub.ID)
+		}
+	}
+
+	created := 0
+	for _, params := range byOrg {
+		n, err := q.InsertDeliveries(ctx, *params)
+		if err != nil {
+			return created, fmt.Errorf("inserting deliveries: %w", err)
 		}
-		matched++
+		created += int(n)
 	}
 
-	if matched > 0 {
+	if created > 0 {
 		m.logger.Info("created deliveries",
-			"event_id", event.ID,
-			"event_type", event.EventType,
-			"subscriptions_matched", matched,
+			"events", len(events),
+			"deliveries", created,
 		)
 	}
 
-	return nil
+	return created, nil
+}
+
+func (m *Matcher) loadCandidates(ctx context.Context, q *dbgen.Queries, key watchKey) ([]candidate, error) {
+	subs, err := q.ListWatchSubscriptions(ctx, dbgen.ListWatchSubscriptionsParams{
+		OrgID:   key.orgID,
+		WatchID: key.watchID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing subscriptions: %w", err)
+	}
+
+	cands := make([]candidate, 0, len(subs))
+	for _, sub := range subs {
+		filters, err := filter.ParseFilters(sub.Filters)
+		if err != nil {
+			m.logger.Warn("failed to parse subscription filters",
+				"subscription_id", sub.ID,
+				"error", err,
+			)
+			continue
+		}
+		cands = append(cands, candidate{sub: sub, filters: filters})
+	}
+	return cands, nil
 }
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+const (
+	// adaptiveWindow is the number of recent completed runs adaptive
+	// scheduling looks at.
+	adaptiveWindow = 5
+
+	// minAdaptiveInterval is the shortest interval adaptive scheduling uses,
+	// whatever the watch's bounds say.
+	minAdaptiveInterval = time.Minute
+)
+
+// adaptiveBounds returns the watch's adaptive interval bounds, sanitised.
+func adaptiveBounds(watch *dbgen.ClaimDueWatchesRow) (lo, hi time.Duration) {
+	lo = max(time.Duration(watch.AdaptiveMinSeconds)*time.Second, minAdaptiveInterval)
+	hi = max(time.Duration(watch.AdaptiveMaxSeconds)*time.Second, lo)
+	return lo, hi
+}
+
+// adaptInterval tightens or relaxes a watch's interval from the number of
+// entity changes in its recent completed runs, newest first. The interval
+// halves when the latest run saw changes and so did at least half the window,
+// doubles once a full window saw none, and otherwise stays put. The result is
+// kept within [lo, hi].
+func adaptInterval(current, lo, hi time.Duration, changes []int32) time.Duration {
+	changed := 0
+	for _, n := range changes {
+		if n > 0 {
+			changed++
+		}
+	}
+
+	switch {
+	case len(changes) > 0 && changes[0] > 0 && changed*2 >= len(changes):
+		current /= 2
+	case len(changes) >= adaptiveWindow && changed == 0:
+		current *= 2
+	}
+	return min(max(current, lo), hi)
+}
+
+// currentInterval returns the interval an adaptive watch is running at. A
+// watch that hasn't adapted yet starts from its schedule's interval.
+func currentInterval(watch *dbgen.ClaimDueWatchesRow, sched *Schedule, now time.Time) time.Duration {
+	lo, hi := adaptiveBounds(watch)
+	if watch.AdaptiveIntervalSeconds.Valid {
+		return min(max(time.Duration(watch.AdaptiveIntervalSeconds.Int32)*time.Second, lo), hi)
+	}
+	return min(max(sched.Interval(now), lo), hi)
+}
+
+// regularNextRun returns when a watch is next due in the normal course of
+// things: by its schedule, or after its current adaptive interval.
+func regularNextRun(watch *dbgen.ClaimDueWatchesRow, sched *Schedule, now time.Time) time.Time {
+	if watch.AdaptiveSchedule {
+		return sched.After(now, currentInterval(watch, sched, now))
+	}
+	return sched.NextRun(now)
+}
+
+// nextAdaptiveInterval recomputes an adaptive watch's interval after a
+// successful run. If the run history can't be read, the interval is kept.
+func (e *Executor) nextAdaptiveInterval(ctx context.Context, watch *dbgen.ClaimDueWatchesRow, sched *Schedule, now time.Time, logger *slog.Logger) time.Duration {
+	current := currentInterval(watch, sched, now)
+
+	changes, err := e.queries.GetRecentRunChanges(ctx, dbgen.GetRecentRunChangesParams{
+		WatchID: watch.ID,
+		Limit:   adaptiveWindow,
+	})
+	if err != nil {
+		logger.Warn("failed to load run history for adaptive scheduling", "error", err)
+		return current
+	}
+
+	lo, hi := adaptiveBounds(watch)
+	next := adaptInterval(current, lo, hi, changes)
+	if next != current {
+		logger.Info("adaptive interval changed", "from", current, "to", next)
+	}
+	return next
+}
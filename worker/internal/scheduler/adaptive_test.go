@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+func TestAdaptInterval_Tightens(t *testing.T) {
+	lo, hi := 5*time.Minute, 24*time.Hour
+
+	assert.Equal(t, 30*time.Minute, adaptInterval(time.Hour, lo, hi, []int32{3, 0, 1, 0}))
+	// Never below the lower bound.
+	assert.Equal(t, lo, adaptInterval(6*time.Minute, lo, hi, []int32{1, 1}))
+}
+
+func TestAdaptInterval_Relaxes(t *testing.T) {
+	lo, hi := 5*time.Minute, 3*time.Hour
+
+	assert.Equal(t, 2*time.Hour, adaptInterval(time.Hour, lo, hi, []int32{0, 0, 0, 0, 0}))
+	// Never above the upper bound.
+	assert.Equal(t, hi, adaptInterval(2*time.Hour, lo, hi, []int32{0, 0, 0, 0, 0}))
+}
+
+func TestAdaptInterval_Holds(t *testing.T) {
+	lo, hi := 5*time.Minute, 24*time.Hour
+
+	// Quiet, but not for a full window yet.
+	assert.Equal(t, time.Hour, adaptInterval(time.Hour, lo, hi, []int32{0, 0}))
+	// The latest run changed, but most of the window didn't.
+	assert.Equal(t, time.Hour, adaptInterval(time.Hour, lo, hi, []int32{2, 0, 0, 0, 0}))
+	// The latest run was quiet after a busy stretch.
+	assert.Equal(t, time.Hour, adaptInterval(time.Hour, lo, hi, []int32{0, 4, 2, 1, 0}))
+	// No history.
+	assert.Equal(t, time.Hour, adaptInterval(time.Hour, lo, hi, nil))
+}
+
+func TestCurrentInterval(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 10, 0, 0, time.UTC)
+	sched := mustParseSchedule(t, "*/30 * * * *", "")
+	watch := &dbgen.ClaimDueWatchesRow{AdaptiveMinSeconds: 600, AdaptiveMaxSeconds: 7200}
+
+	// Not adapted yet: the schedule's interval.
+	assert.Equal(t, 30*time.Minute, currentInterval(watch, sched, now))
+
+	watch.AdaptiveIntervalSeconds = pgtype.Int4{Int32: 3600, Valid: true}
+	assert.Equal(t, time.Hour, currentInterval(watch, sched, now))
+
+	// Bounds tightened since the interval was stored.
+	watch.AdaptiveMaxSeconds = 1200
+	assert.Equal(t, 20*time.Minute, currentInterval(watch, sched, now))
+}
+
+func TestAdaptiveBounds(t *testing.T) {
+	lo, hi := adaptiveBounds(&dbgen.ClaimDueWatchesRow{AdaptiveMinSeconds: 10, AdaptiveMaxSeconds: 5})
+	assert.Equal(t, minAdaptiveInterval, lo)
+	assert.Equal(t, minAdaptiveInterval, hi)
+}
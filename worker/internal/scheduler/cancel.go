@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/blueprinter/worker/internal/db"
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+var (
+	// ErrRunCancelled is the cause of a run cancelled through the API. Such
+	// runs complete as cancelled and don't count as watch failures.
+	ErrRunCancelled = errors.New("run cancelled")
+
+	// ErrRunNotFound is returned when a run doesn't exist or belongs to another org.
+	ErrRunNotFound = errors.New("run not found")
+
+	// ErrRunNotRunning is returned when cancelling a run that already finished.
+	ErrRunNotRunning = errors.New("run is not running")
+
+	errLeaseLost = errors.New("watch lease lost")
+)
+
+// runRegistry tracks the runs in progress on this worker so they can be
+// cancelled without waiting for a heartbeat.
+type runRegistry struct {
+	mu   sync.Mutex
+	runs map[string]context.CancelCauseFunc
+}
+
+func (r *runRegistry) add(runID string, cancel context.CancelCauseFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.runs == nil {
+		r.runs = make(map[string]context.CancelCauseFunc)
+	}
+	r.runs[runID] = cancel
+}
+
+func (r *runRegistry) remove(runID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, runID)
+}
+
+func (r *runRegistry) cancel(runID string, cause error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.runs[runID]
+	if ok {
+		cancel(cause)
+	}
+	return ok
+}
+
+// CancelRun requests cancellation of a running run. A run on this worker is
+// cancelled at once; one on another worker stops at that worker's next lease
+// heartbeat.
+func (e *Executor) CancelRun(ctx context.Context, orgID, runID string) error {
+	var id pgtype.UUID
+	if err := id.Scan(runID); err != nil {
+		return ErrRunNotFound
+	}
+
+	n, err := e.queries.RequestWatchRunCancel(ctx, dbgen.RequestWatchRunCancelParams{ID: id, OrgID: orgID})
+	if err != nil {
+		return fmt.Errorf("requesting cancellation: %w", err)
+	}
+	if n == 0 {
+		_, err := e.queries.GetWatchRun(ctx, dbgen.GetWatchRunParams{ID: id, OrgID: orgID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRunNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("getting run: %w", err)
+		}
+		return ErrRunNotRunning
+	}
+
+	if e.runs.cancel(db.UUIDToString(id), ErrRunCancelled) {
+		e.logger.Info("run cancelled", "run_id", runID)
+	} else {
+		e.logger.Info("run cancellation requested", "run_id", runID)
+	}
+	return nil
+}
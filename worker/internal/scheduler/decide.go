@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"slices"
+
+	"github.com/blueprinter/worker/internal/differ"
+)
+
+// runSettings are the watch settings that decide what a run does with what
+// it extracted.
+type runSettings struct {
+	trackPositions        bool
+	renameThreshold       float64
+	minEntityCount        int32
+	maxDisappearanceRatio float64
+	acceptNextRun         bool // skip the safety guards
+}
+
+// runDecision is what a run does with what it extracted.
+type runDecision struct {
+	diff    differ.DiffResult
+	missed  []string   // external IDs missing within their grace window
+	renamed int        // changed entities matched across an identity change
+	trip    *guardTrip // the safety guard the run tripped, if any
+}
+
+// decideRun compares a run's extraction with the entities active before it.
+// Entities last found on a page that failed in this run (unchecked) are
+// neither missing nor gone; entities whose identity fields changed are paired
+// with their previous selves and reported as changed; of the other missing
+// ones, those within their grace window (inGrace) stay active and are not
+// reported. Unless the run was accepted in advance, the safety guards then
+// judge whether its results can be applied. Live runs and replays both decide
+// through it, so a replay reports what a live run would have done.
+//
+// stored may be modified to agree with the extraction about position fields.
+func decideRun(
+	policy differ.Policy,
+	settings runSettings,
+	extracted, stored map[string]map[string]any,
+	unchecked map[string]bool,
+	inGrace func(externalID string) bool,
+) runDecision {
+	// Turning position tracking on or off is not a change of every entity
+	alignPositionFields(stored, extracted, settings.trackPositions)
+
+	var d runDecision
+	d.diff = policy.Diff(extracted, stored)
+	d.diff.Disappeared = slices.DeleteFunc(d.diff.Disappeared, func(e differ.EntityDiff) bool {
+		return unchecked[e.ExternalID]
+	})
+
+	// Renames are matched before the grace window is applied: a renamed
+	// entity's old ID must not wait out its grace and disappear later.
+	d.renamed = policy.MatchRenames(&d.diff, stored, settings.renameThreshold)
+	d.diff.Disappeared, d.missed = splitDisappeared(d.diff.Disappeared, inGrace)
+
+	// A broken page would otherwise look like mass removals. Renamed entities
+	// aren't missing, and unchecked ones weren't looked for.
+	if !settings.acceptNextRun {
+		checked := stored
+		if len(unchecked) > 0 {
+			checked = make(map[string]map[string]any, len(stored))
+			for eid, content := range stored {
+				if !unchecked[eid] {
+					checked[eid] = content
+				}
+			}
+		}
+		d.trip = checkGuards(settings.minEntityCount, settings.maxDisappearanceRatio, extracted, checked, d.renamed)
+	}
+	return d
+}
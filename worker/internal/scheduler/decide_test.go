@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blueprinter/worker/internal/differ"
+)
+
+func noGrace(string) bool { return false }
+
+func TestDecideRun_GraceAndUncheckedPages(t *testing.T) {
+	settings := runSettings{minEntityCount: 1, maxDisappearanceRatio: 1}
+	stored := entitySet("a", "b", "c", "d")
+	unchecked := map[string]bool{"c": true}
+
+	d := decideRun(differ.Policy{}, settings, entitySet("a"), stored, unchecked, func(eid string) bool {
+		return eid == "b"
+	})
+	require.Nil(t, d.trip)
+	assert.Equal(t, []string{"b"}, d.missed)
+	require.Len(t, d.diff.Disappeared, 1)
+	assert.Equal(t, "d", d.diff.Disappeared[0].ExternalID)
+}
+
+func TestDecideRun_Guards(t *testing.T) {
+	settings := runSettings{minEntityCount: 1, maxDisappearanceRatio: 0.5}
+	stored := entitySet("a", "b", "c", "d")
+
+	d := decideRun(differ.Policy{}, settings, entitySet("a"), stored, nil, noGrace)
+	require.NotNil(t, d.trip)
+	assert.Equal(t, 3, d.trip.Missing)
+
+	// Entities on a failed page weren't looked for, so they aren't missing.
+	d = decideRun(differ.Policy{}, settings, entitySet("a"), stored, map[string]bool{"b": true, "c": true}, noGrace)
+	assert.Nil(t, d.trip)
+
+	// An accepted run applies whatever it finds.
+	settings.acceptNextRun = true
+	d = decideRun(differ.Policy{}, settings, entitySet("a"), stored, nil, noGrace)
+	assert.Nil(t, d.trip)
+	assert.Len(t, d.diff.Disappeared, 3)
+}
+
+func TestDecideRun_Renames(t *testing.T) {
+	settings := runSettings{renameThreshold: 0.5, minEntityCount: 1, maxDisappearanceRatio: 0.5}
+	stored := map[string]map[string]any{
+		"a": {"name": "Widget", "price": 10.0},
+		"b": {"name": "Gadget", "price": 20.0},
+	}
+	extracted := map[string]map[string]any{
+		"a":  {"name": "Widget", "price": 10.0},
+		"b2": {"name": "Gadget", "price": 20.0},
+	}
+
+	d := decideRun(differ.Policy{}, settings, extracted, stored, nil, noGrace)
+	require.Nil(t, d.trip)
+	assert.Equal(t, 1, d.renamed)
+	assert.Empty(t, d.diff.Appeared)
+	assert.Empty(t, d.diff.Disappeared)
+	require.Len(t, d.diff.Changed, 1)
+	assert.Equal(t, "b", d.diff.Changed[0].PreviousExternalID)
+}
+
+func TestDecideRun_RenameWithinGrace(t *testing.T) {
+	settings := runSettings{renameThreshold: 0.5, minEntityCount: 1, maxDisappearanceRatio: 0.5}
+	stored := map[string]map[string]any{
+		"a": {"name": "Widget", "price": 10.0},
+		"b": {"name": "Gadget", "price": 20.0},
+	}
+	extracted := map[string]map[string]any{
+		"a":  {"name": "Widget", "price": 10.0},
+		"b2": {"name": "Gadget", "price": 20.0},
+	}
+	allInGrace := func(string) bool { return true }
+
+	// The old ID is matched, not held back as missed to disappear later.
+	d := decideRun(differ.Policy{}, settings, extracted, stored, nil, allInGrace)
+	assert.Equal(t, 1, d.renamed)
+	assert.Empty(t, d.missed)
+	assert.Empty(t, d.diff.Appeared)
+	require.Len(t, d.diff.Changed, 1)
+	assert.Equal(t, "b", d.diff.Changed[0].PreviousExternalID)
+}
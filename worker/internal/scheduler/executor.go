@@ -2,140 +2,225 @@ package scheduler
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"sort"
-	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/robfig/cron/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/blueprinter/worker/internal/archive"
 	"github.com/blueprinter/worker/internal/blueprint"
+	"github.com/blueprinter/worker/internal/db"
 	"github.com/blueprinter/worker/internal/db/dbgen"
 	"github.com/blueprinter/worker/internal/differ"
 	"github.com/blueprinter/worker/internal/emitter"
 	"github.com/blueprinter/worker/internal/fetcher"
 )
 
+// upsertBatchSize caps the number of entities written per statement.
+const upsertBatchSize = 1000
+
 // Executor handles single watch run execution.
 type Executor struct {
-	queries *dbgen.Queries
-	fetcher *fetcher.Client
-	emitter *emitter.Emitter
-	logger  *slog.Logger
+	pool     *pgxpool.Pool
+	queries  *dbgen.Queries
+	fetcher  *fetcher.Client
+	emitter  *emitter.Emitter
+	schemas  *blueprint.SchemaRegistry
+	archive  *archive.Archive // nil when snapshot archiving is disabled
+	workerID string           // owner recorded on watch leases
+	timeouts RunTimeouts
+	runs     runRegistry
+	logger   *slog.Logger
 }
 
-// NewExecutor creates a new Executor.
-func NewExecutor(queries *dbgen.Queries, fetcher *fetcher.Client, emitter *emitter.Emitter, logger *slog.Logger) *Executor {
+// NewExecutor creates a new Executor. archive may be nil. workerID must be
+// unique among the workers sharing the database. Zero timeouts use the
+// defaults.
+func NewExecutor(
+	pool *pgxpool.Pool,
+	queries *dbgen.Queries,
+	fetcher *fetcher.Client,
+	emitter *emitter.Emitter,
+	schemas *blueprint.SchemaRegistry,
+	archive *archive.Archive,
+	workerID string,
+	timeouts RunTimeouts,
+	logger *slog.Logger,
+) *Executor {
 	return &Executor{
-		queries: queries,
-		fetcher: fetcher,
-		emitter: emitter,
-		logger:  logger,
+		pool:     pool,
+		queries:  queries,
+		fetcher:  fetcher,
+		emitter:  emitter,
+		schemas:  schemas,
+		archive:  archive,
+		workerID: workerID,
+		timeouts: timeouts.withDefaults(),
+		logger:   logger,
 	}
 }
 
-// Execute runs a single watch from a GetDueWatchesRow.
-func (e *Executor) Execute(ctx context.Context, watch *dbgen.GetDueWatchesRow) {
-	logger := e.logger.With("watch_id", uuidToString(watch.ID), "watch_name", watch.Name)
+// Execute runs a single watch this worker holds the lease for. The lease is
+// renewed while the run is in progress and released when the watch is
+// updated afterwards; if the run is abandoned, the watch is retried once the
+// lease expires.
+func (e *Executor) Execute(ctx context.Context, watch *dbgen.ClaimDueWatchesRow) {
+	logger := e.logger.With("watch_id", db.UUIDToString(watch.ID), "watch_name", watch.Name)
 	logger.Info("executing watch run")
 
 	// 1. Create watch_run record
-	run, err := e.queries.CreateWatchRun(ctx, dbgen.CreateWatchRunParams{
-		OrgID:   watch.OrgID,
-		WatchID: watch.ID,
-	})
+	run, err := e.createRun(ctx, watch, logger)
 	if err != nil {
 		logger.Error("failed to create watch run", "error", err)
 		return
 	}
 
-	// Execute and handle result
-	stats, execErr := e.executeRun(ctx, watch, run.ID, logger)
-
-	// 2. Complete the watch run
-	completeStatus := "completed"
-	var errorMsg pgtype.Text
-	if execErr != nil {
-		completeStatus = "failed"
-		errorMsg = pgtype.Text{String: execErr.Error(), Valid: true}
-	}
-
-	if err := e.queries.CompleteWatchRun(ctx, dbgen.CompleteWatchRunParams{
-		ID:              run.ID,
-		Status:          completeStatus,
-		EntitiesFound:   pgInt4(stats.found),
-		EntitiesNew:     pgInt4(stats.newCount),
-		EntitiesChanged: pgInt4(stats.changed),
-		EntitiesRemoved: pgInt4(stats.removed),
-		EventsEmitted:   pgInt4(stats.eventsEmitted),
-		ErrorMessage:    errorMsg,
-	}); err != nil {
-		logger.Error("failed to complete watch run", "error", err)
-	}
-
-	// 3. Update watch metadata
-	e.updateWatchAfterRun(ctx, watch, logger)
+	// 2. Execute it and record the outcome
+	_ = e.runAndRecord(ctx, watch, run.ID, logger)
 }
 
-// ExecuteByID runs a watch by its UUID string (for manual trigger).
-func (e *Executor) ExecuteByID(ctx context.Context, watchID string) (string, error) {
+// claimByID claims an org's watch for a manual run and creates the run. It
+// fails with ErrWatchRunning if the watch is already running anywhere.
+func (e *Executor) claimByID(ctx context.Context, orgID, watchID string) (*dbgen.ClaimDueWatchesRow, pgtype.UUID, error) {
 	var id pgtype.UUID
 	if err := id.Scan(watchID); err != nil {
-		return "", fmt.Errorf("invalid watch ID: %w", err)
+		return nil, pgtype.UUID{}, fmt.Errorf("invalid watch ID: %w", err)
 	}
 
-	watch, err := e.queries.GetWatchByID(ctx, id)
+	watch, err := e.queries.ClaimWatch(ctx, dbgen.ClaimWatchParams{
+		Owner:        e.workerID,
+		LeaseSeconds: int32(watchLease.Seconds()),
+		ID:           id,
+		OrgID:        orgID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either the org has no such watch or another run holds it.
+		if w, getErr := e.queries.GetWatchByID(ctx, id); getErr == nil && w.OrgID == orgID {
+			return nil, pgtype.UUID{}, ErrWatchRunning
+		}
+		return nil, pgtype.UUID{}, ErrWatchNotFound
+	}
 	if err != nil {
-		return "", fmt.Errorf("getting watch: %w", err)
+		return nil, pgtype.UUID{}, fmt.Errorf("claiming watch: %w", err)
 	}
 
-	// Create watch_run
-	run, err := e.queries.CreateWatchRun(ctx, dbgen.CreateWatchRunParams{
-		OrgID:   watch.OrgID,
-		WatchID: watch.ID,
-	})
+	// Convert to ClaimDueWatchesRow for shared execution logic
+	dueRow := dbgen.ClaimDueWatchesRow(watch)
+
+	run, err := e.createRun(ctx, &dueRow, e.logger.With("watch_id", watchID))
 	if err != nil {
-		return "", fmt.Errorf("creating watch run: %w", err)
+		// Nothing will run, so let the next trigger claim the watch at once
+		// rather than wait out the lease.
+		if relErr := e.queries.ReleaseWatchLease(context.WithoutCancel(ctx), dbgen.ReleaseWatchLeaseParams{
+			ID:    watch.ID,
+			Owner: e.workerID,
+		}); relErr != nil {
+			e.logger.Error("failed to release watch lease", "watch_id", watchID, "error", relErr)
+		}
+		return nil, pgtype.UUID{}, fmt.Errorf("creating watch run: %w", err)
 	}
+	return &dueRow, run.ID, nil
+}
 
-	// Convert to DueWatchesRow for shared execution logic
-	dueRow := dbgen.GetDueWatchesRow(watch)
+// runAndRecord executes a created run and records its outcome on the run and
+// the watch. While it executes, the run can be cancelled and the watch's
+// lease is renewed.
+func (e *Executor) runAndRecord(ctx context.Context, watch *dbgen.ClaimDueWatchesRow, runID pgtype.UUID, logger *slog.Logger) error {
+	logger = logger.With("run_id", db.UUIDToString(runID))
 
-	logger := e.logger.With("watch_id", watchID, "watch_name", watch.Name)
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	e.runs.add(db.UUIDToString(runID), cancel)
+	defer e.runs.remove(db.UUIDToString(runID))
+	go e.holdLease(runCtx, cancel, watch.ID, runID, logger)
 
-	stats, execErr := e.executeRun(ctx, &dueRow, run.ID, logger)
+	// A schedule that can't be parsed fails the run before anything is
+	// fetched; updateWatchAfterRun then moves the watch to error.
+	var stats runStats
+	_, execErr := ParseSchedule(watch.Schedule, watch.Timezone, watch.JitterSeconds)
+	if execErr == nil {
+		stats, execErr = e.executeRun(runCtx, watch, runID, logger)
+		if execErr != nil && runCtx.Err() != nil {
+			// Report why the run was stopped rather than where it noticed.
+			execErr = context.Cause(runCtx)
+		}
+	}
+
+	// The outcome is recorded even if the run was stopped by a shutdown.
+	recordCtx := context.WithoutCancel(ctx)
+	e.completeRun(recordCtx, runID, stats, execErr, logger)
+	e.updateWatchAfterRun(recordCtx, watch, runID, execErr, logger)
+	return execErr
+}
+
+// createRun records a new watch_run, linked to the blueprint revision it executes.
+func (e *Executor) createRun(ctx context.Context, watch *dbgen.ClaimDueWatchesRow, logger *slog.Logger) (dbgen.WatchRun, error) {
+	revisionID, err := e.queries.EnsureBlueprintRevision(ctx, watch.BlueprintID)
+	if err != nil {
+		// Not fatal: the run proceeds without a revision link.
+		logger.Warn("failed to record blueprint revision", "error", err)
+	}
+
+	return e.queries.CreateWatchRun(ctx, dbgen.CreateWatchRunParams{
+		OrgID:               watch.OrgID,
+		WatchID:             watch.ID,
+		BlueprintRevisionID: revisionID,
+	})
+}
 
+// completeRun records the outcome of a run. A run that tripped a safety guard
+// completes as suspect, with the guard's reason as its error message; one
+// that exceeded a phase deadline as timed_out, one cancelled through the API
+// as cancelled, and one stopped by a shutdown or a lost lease as interrupted.
+func (e *Executor) completeRun(ctx context.Context, runID pgtype.UUID, stats runStats, execErr error, logger *slog.Logger) {
 	completeStatus := "completed"
 	var errorMsg pgtype.Text
-	if execErr != nil {
+	var timeout *PhaseTimeoutError
+	switch {
+	case errors.As(execErr, &timeout):
+		completeStatus = "timed_out"
+		errorMsg = pgtype.Text{String: execErr.Error(), Valid: true}
+	case errors.Is(execErr, ErrRunCancelled):
+		completeStatus = "cancelled"
+		errorMsg = pgtype.Text{String: execErr.Error(), Valid: true}
+	case errors.Is(execErr, ErrShutdown), errors.Is(execErr, errLeaseLost):
+		completeStatus = "interrupted"
+		errorMsg = pgtype.Text{String: execErr.Error(), Valid: true}
+	case execErr != nil:
 		completeStatus = "failed"
 		errorMsg = pgtype.Text{String: execErr.Error(), Valid: true}
+	case stats.suspect != "":
+		completeStatus = "suspect"
+		errorMsg = pgtype.Text{String: stats.suspect, Valid: true}
+	}
+
+	pageErrors := []byte("[]")
+	if len(stats.pageErrors) > 0 {
+		if b, err := json.Marshal(stats.pageErrors); err == nil {
+			pageErrors = b
+		}
 	}
 
 	if err := e.queries.CompleteWatchRun(ctx, dbgen.CompleteWatchRunParams{
-		ID:              run.ID,
-		Status:          completeStatus,
-		EntitiesFound:   pgInt4(stats.found),
-		EntitiesNew:     pgInt4(stats.newCount),
-		EntitiesChanged: pgInt4(stats.changed),
-		EntitiesRemoved: pgInt4(stats.removed),
-		EventsEmitted:   pgInt4(stats.eventsEmitted),
-		ErrorMessage:    errorMsg,
+		ID:                 runID,
+		Status:             completeStatus,
+		EntitiesFound:      pgInt4(stats.found),
+		EntitiesNew:        pgInt4(stats.newCount),
+		EntitiesChanged:    pgInt4(stats.changed),
+		EntitiesRemoved:    pgInt4(stats.removed),
+		EventsEmitted:      pgInt4(stats.eventsEmitted),
+		ErrorMessage:       errorMsg,
+		IdentityCollisions: pgInt4(stats.identity.collisions),
+		IdentityMissing:    pgInt4(stats.identity.missing),
+		PageErrors:         pageErrors,
 	}); err != nil {
 		logger.Error("failed to complete watch run", "error", err)
 	}
-
-	e.updateWatchAfterRun(ctx, &dueRow, logger)
-
-	runID := uuidToString(run.ID)
-	if execErr != nil {
-		return runID, execErr
-	}
-	return runID, nil
 }
 
 type runStats struct {
@@ -144,9 +229,12 @@ type runStats struct {
 	changed       int
 	removed       int
 	eventsEmitted int
+	suspect       string // guard reason when the run's results were discarded
+	identity      identityStats
+	pageErrors    []PageError
 }
 
-func (e *Executor) executeRun(ctx context.Context, watch *dbgen.GetDueWatchesRow, runID pgtype.UUID, logger *slog.Logger) (runStats, error) {
+func (e *Executor) executeRun(ctx context.Context, watch *dbgen.ClaimDueWatchesRow, runID pgtype.UUID, logger *slog.Logger) (runStats, error) {
 	var stats runStats
 
 	// 1. Parse extraction rules from JSON
@@ -155,52 +243,248 @@ func (e *Executor) executeRun(ctx context.Context, watch *dbgen.GetDueWatchesRow
 		return stats, fmt.Errorf("parsing extraction rules: %w", err)
 	}
 
-	// 2. Fetch HTML
-	rawHTML, err := e.fetcher.FetchHTML(ctx, watch.Url)
+	// Resolve the entity schema (built-in or org-defined)
+	schema, err := e.schemas.GetSchema(ctx, watch.OrgID, watch.SchemaType)
 	if err != nil {
-		return stats, fmt.Errorf("fetching HTML: %w", err)
+		return stats, fmt.Errorf("resolving schema: %w", err)
 	}
 
-	// 3. Clean HTML
-	cleanedHTML, err := blueprint.Clean(rawHTML)
+	identityFields := watch.IdentityFields
+	if len(identityFields) == 0 {
+		identityFields = schema.IdentityFields
+	}
+	idSpec, err := watchIdentity(identityFields, watch.IdentityFallbacks, watch.IdentityNormalize)
+	if err != nil {
+		return stats, fmt.Errorf("invalid identity configuration: %w", err)
+	}
+	policy, err := differ.ParsePolicy(watch.DiffPolicy)
 	if err != nil {
-		return stats, fmt.Errorf("cleaning HTML: %w", err)
+		return stats, fmt.Errorf("invalid diff policy: %w", err)
 	}
+	policy.FieldOrder = diffFieldOrder(schema)
 
-	// 4. Extract entities
-	extractedRaw, err := blueprint.Extract(cleanedHTML, &rules)
+	targets, err := watchTargets(watch.Url, watch.Urls, watch.UrlParams)
 	if err != nil {
-		return stats, fmt.Errorf("extracting entities: %w", err)
+		return stats, fmt.Errorf("expanding watch URLs: %w", err)
+	}
+
+	// 2-3. Fetch and clean HTML of every page, each under the fetch deadline.
+	// A page that fails is recorded and left out; the run only fails when
+	// every page does.
+	cleanedHTML := make([]string, len(targets))
+	fetched := make([]bool, len(targets))
+	var firstPageErr error
+	e.setPhase(ctx, runID, "fetch", logger)
+	for i, target := range targets {
+		err := withPhaseTimeout(ctx, "fetch", e.timeouts.Fetch, func(ctx context.Context) error {
+			rawHTML, err := e.fetcher.FetchHTML(ctx, target.URL)
+			if err != nil {
+				return fmt.Errorf("fetching %s: %w", target.URL, err)
+			}
+
+			cleanedHTML[i], err = blueprint.Clean(rawHTML)
+			if err != nil {
+				return fmt.Errorf("cleaning HTML of %s: %w", target.URL, err)
+			}
+
+			// Archive the page as fetched, so surprising results can be debugged later
+			if e.archive != nil {
+				if _, err := e.archive.Save(ctx, archive.SaveParams{
+					OrgID:       watch.OrgID,
+					WatchID:     watch.ID,
+					WatchRunID:  runID,
+					URL:         target.URL,
+					RawHTML:     rawHTML,
+					CleanedHTML: cleanedHTML[i],
+				}); err != nil {
+					logger.Warn("failed to archive snapshot", "url", target.URL, "error", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return stats, err
+			}
+			logger.Warn("page failed", "url", target.URL, "phase", "fetch", "error", err)
+			if firstPageErr == nil {
+				firstPageErr = err
+			}
+			stats.pageErrors = append(stats.pageErrors, PageError{URL: target.URL, Param: target.Param, Phase: "fetch", Error: err.Error()})
+			continue
+		}
+		fetched[i] = true
+	}
+
+	// 4-5. Extract entities and compute external IDs. The pages are diffed as
+	// one set, so an entity moving between them is neither gone nor new; each
+	// entity keeps the page it was last found on. Positions count on across
+	// the pages of one listing, i.e. those sharing a URL parameter.
+	extracted := make(map[string]map[string]any)
+	sources := make(map[string]fetchTarget)
+	found := 0
+	var ids identityStats
+	e.setPhase(ctx, runID, "extract", logger)
+	listed := make(map[fetchTarget]int)
+	for i, target := range targets {
+		if !fetched[i] {
+			continue
+		}
+		var entities []map[string]any
+		err := withPhaseTimeout(ctx, "extract", e.timeouts.Extract, func(ctx context.Context) error {
+			var err error
+			entities, err = extractEntities(ctx, cleanedHTML[i], &rules)
+			if err != nil {
+				return fmt.Errorf("extracting entities from %s: %w", target.URL, err)
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return stats, err
+			}
+			logger.Warn("page failed", "url", target.URL, "phase", "extract", "error", err)
+			if firstPageErr == nil {
+				firstPageErr = err
+			}
+			stats.pageErrors = append(stats.pageErrors, PageError{URL: target.URL, Param: target.Param, Phase: "extract", Error: err.Error()})
+			continue
+		}
+		found += len(entities)
+		if watch.TrackPositions {
+			blueprint.RecordPositions(entities, listed[target.listing()])
+			listed[target.listing()] += len(entities)
+		}
+		for _, eid := range keyEntities(entities, idSpec, extracted, &ids) {
+			sources[eid] = target
+		}
+	}
+	if len(stats.pageErrors) == len(targets) {
+		return stats, firstPageErr
+	}
+	stats.identity = ids
+	if ids.collisions > 0 || ids.missing > 0 {
+		logger.Warn("entities dropped for ambiguous identity",
+			"collisions", ids.collisions,
+			"missing", ids.missing,
+		)
+	}
+
+	stats.found = found
+	logger.Info("entities extracted", "count", stats.found, "pages", len(targets))
+
+	// 6-12. Persist the results atomically
+	e.setPhase(ctx, runID, "persist", logger)
+	if err := withPhaseTimeout(ctx, "persist", e.timeouts.Persist, func(ctx context.Context) error {
+		return e.persistRun(ctx, watch, schema, &rules, policy, runID, extracted, sources, &stats, logger)
+	}); err != nil {
+		return runStats{found: stats.found, identity: stats.identity, pageErrors: stats.pageErrors}, err
 	}
 
-	stats.found = len(extractedRaw)
-	logger.Info("entities extracted", "count", stats.found)
+	return stats, nil
+}
 
-	// 5. Compute external IDs and build extracted map
-	extracted := make(map[string]map[string]any, len(extractedRaw))
-	for _, entity := range extractedRaw {
-		eid := computeExternalID(entity, watch.IdentityFields)
-		extracted[eid] = entity
+// persistRun applies the results of a run in a single transaction: stored
+// entities are migrated, touched, upserted and marked stale, and events are
+// inserted together with their deliveries. Any error rolls everything back,
+// so entities never change without their events, nor events appear without
+// their deliveries.
+func (e *Executor) persistRun(
+	ctx context.Context,
+	watch *dbgen.ClaimDueWatchesRow,
+	schema *blueprint.EntitySchema,
+	rules *blueprint.ExtractionRules,
+	policy differ.Policy,
+	runID pgtype.UUID,
+	extracted map[string]map[string]any,
+	sources map[string]fetchTarget,
+	stats *runStats,
+	logger *slog.Logger,
+) error {
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := e.queries.WithTx(tx)
 
 	// 6. Load stored entities
-	storedEntities, err := e.queries.GetEntitiesByWatch(ctx, watch.ID)
+	storedEntities, err := q.GetEntitiesByWatch(ctx, watch.ID)
 	if err != nil {
-		return stats, fmt.Errorf("loading stored entities: %w", err)
+		return fmt.Errorf("loading stored entities: %w", err)
+	}
+
+	// Give entities stored before versions were kept a starting version, so
+	// their history begins with the content this run compares against.
+	if err := q.SeedEntityVersions(ctx, dbgen.SeedEntityVersionsParams{
+		WatchRunID: runID,
+		WatchID:    watch.ID,
+	}); err != nil {
+		return fmt.Errorf("seeding entity versions: %w", err)
 	}
 
 	stored := make(map[string]map[string]any, len(storedEntities))
 	for i := range storedEntities {
 		var content map[string]any
 		if err := json.Unmarshal(storedEntities[i].Content, &content); err != nil {
-			logger.Warn("failed to unmarshal stored entity", "entity_id", uuidToString(storedEntities[i].ID), "error", err)
+			logger.Warn("failed to unmarshal stored entity", "entity_id", db.UUIDToString(storedEntities[i].ID), "error", err)
 			continue
 		}
 		stored[storedEntities[i].ExternalID] = content
 	}
 
-	// 7. Run differ
-	diffResult := differ.Diff(extracted, stored)
+	// 7. Reconcile entities stored under an older schema/blueprint version
+	migrated, err := e.migrateStoredEntities(ctx, q, watch, schema, rules, storedEntities, stored, extracted, logger)
+	if err != nil {
+		return fmt.Errorf("migrating stored entities: %w", err)
+	}
+	if migrated > 0 {
+		logger.Info("stored entities migrated",
+			"count", migrated,
+			"schema_version", schema.Version,
+			"blueprint_version", watch.BlueprintVersion,
+		)
+	}
+
+	// 8. Decide what the run changes. Entities last found on a page that
+	// failed in this run weren't checked, so they are neither missing nor
+	// gone; entities missing within their grace window stay active and are
+	// not reported, and if they come back, it is not a reappearance.
+	now := time.Now()
+	storedByExternalID := make(map[string]*dbgen.Entity, len(storedEntities))
+	storedSources := make(map[string]string, len(storedEntities))
+	for i := range storedEntities {
+		storedByExternalID[storedEntities[i].ExternalID] = &storedEntities[i]
+		storedSources[storedEntities[i].ExternalID] = storedEntities[i].SourceUrl.String
+	}
+	decision := decideRun(policy, runSettings{
+		trackPositions:        watch.TrackPositions,
+		renameThreshold:       watch.RenameThreshold,
+		minEntityCount:        watch.MinEntityCount,
+		maxDisappearanceRatio: watch.MaxDisappearanceRatio,
+		acceptNextRun:         watch.AcceptNextRun,
+	}, extracted, stored, uncheckedEntities(storedSources, stats.pageErrors), func(eid string) bool {
+		entity := storedByExternalID[eid]
+		return withinGrace(watch.GraceMissedRuns, watch.GracePeriodSeconds, entity.MissedRuns, entity.LastSeenAt.Time, now)
+	})
+	diffResult, missed, renamed := decision.diff, decision.missed, decision.renamed
+
+	// Safety guards: if the extraction looks like a broken page, keep nothing
+	// of this run (migrations included) and raise a single alert instead of
+	// diff events. A run the user accepted in advance applies whatever it
+	// finds, so a real turnover can be taken on.
+	if watch.AcceptNextRun {
+		if err := q.ClearAcceptNextRun(ctx, watch.ID); err != nil {
+			return fmt.Errorf("clearing accept_next_run: %w", err)
+		}
+		logger.Info("safety guards skipped for accepted run")
+	} else if decision.trip != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return fmt.Errorf("rolling back suspect run: %w", err)
+		}
+		return e.discardSuspectRun(ctx, watch, runID, decision.trip, stats, logger)
+	}
+
 	stats.newCount = len(diffResult.Appeared)
 	stats.changed = len(diffResult.Changed)
 	stats.removed = len(diffResult.Disappeared)
@@ -209,150 +493,259 @@ func (e *Executor) executeRun(ctx context.Context, watch *dbgen.GetDueWatchesRow
 		"appeared", stats.newCount,
 		"changed", stats.changed,
 		"disappeared", stats.removed,
+		"missed", len(missed),
+		"renamed", renamed,
 		"unchanged", diffResult.Unchanged,
 	)
 
-	// 8. Touch last_seen_at for all extracted entities (appeared, changed, and unchanged)
+	// 9. Touch last_seen_at for all extracted entities (appeared, changed, and unchanged)
 	allExtractedIDs := make([]string, 0, len(extracted))
 	for eid := range extracted {
 		allExtractedIDs = append(allExtractedIDs, eid)
 	}
 	if len(allExtractedIDs) > 0 {
-		if err := e.queries.TouchEntitiesLastSeen(ctx, dbgen.TouchEntitiesLastSeenParams{
+		if err := q.TouchEntitiesLastSeen(ctx, dbgen.TouchEntitiesLastSeenParams{
 			WatchID: watch.ID,
 			Column2: allExtractedIDs,
 		}); err != nil {
-			logger.Warn("failed to touch entities last_seen_at", "error", err)
+			return fmt.Errorf("touching entities last_seen_at: %w", err)
 		}
 	}
 
-	// 9. Upsert appeared + changed entities, collecting entity IDs
-	entityIDs := make(map[string]pgtype.UUID)
-
-	for _, d := range diffResult.Appeared {
-		contentBytes, err := json.Marshal(d.Content)
-		if err != nil {
-			logger.Warn("failed to marshal entity content", "external_id", d.ExternalID, "error", err)
+	// Record the new page of unchanged entities that moved between pages;
+	// changed ones get theirs with the upsert below.
+	changedIDs := make(map[string]bool, len(diffResult.Changed))
+	for _, d := range diffResult.Changed {
+		changedIDs[d.ExternalID] = true
+	}
+	var moved dbgen.UpdateEntitySourcesParams
+	for eid, src := range sources {
+		entity, ok := storedByExternalID[eid]
+		if !ok || changedIDs[eid] || (entity.SourceUrl.String == src.URL && entity.SourceParam.String == src.Param) {
 			continue
 		}
-		entity, err := e.queries.UpsertEntity(ctx, dbgen.UpsertEntityParams{
-			OrgID:      watch.OrgID,
-			WatchID:    watch.ID,
-			SchemaType: watch.SchemaType,
-			ExternalID: d.ExternalID,
-			Content:    contentBytes,
-		})
-		if err != nil {
-			logger.Warn("failed to upsert appeared entity", "external_id", d.ExternalID, "error", err)
-			continue
+		moved.ExternalIds = append(moved.ExternalIds, eid)
+		moved.SourceUrls = append(moved.SourceUrls, src.URL)
+		moved.SourceParams = append(moved.SourceParams, src.Param)
+	}
+	if len(moved.ExternalIds) > 0 {
+		moved.WatchID = watch.ID
+		if err := q.UpdateEntitySources(ctx, moved); err != nil {
+			return fmt.Errorf("updating entity sources: %w", err)
 		}
-		entityIDs[d.ExternalID] = entity.ID
+		logger.Debug("entities moved between pages", "count", len(moved.ExternalIds))
 	}
 
+	// Move renamed entities to their new external ID. If an entity already
+	// holds that ID (a stale one coming back), it is updated instead and the
+	// renamed one goes stale.
+	var superseded []string
 	for _, d := range diffResult.Changed {
-		contentBytes, err := json.Marshal(d.Content)
-		if err != nil {
-			logger.Warn("failed to marshal entity content", "external_id", d.ExternalID, "error", err)
+		if d.PreviousExternalID == "" {
 			continue
 		}
-		entity, err := e.queries.UpsertEntity(ctx, dbgen.UpsertEntityParams{
-			OrgID:      watch.OrgID,
-			WatchID:    watch.ID,
-			SchemaType: watch.SchemaType,
-			ExternalID: d.ExternalID,
-			Content:    contentBytes,
+		n, err := q.RenameEntity(ctx, dbgen.RenameEntityParams{
+			NewExternalID: d.ExternalID,
+			WatchID:       watch.ID,
+			OldExternalID: d.PreviousExternalID,
 		})
 		if err != nil {
-			logger.Warn("failed to upsert changed entity", "external_id", d.ExternalID, "error", err)
-			continue
+			return fmt.Errorf("renaming entity %s: %w", d.PreviousExternalID, err)
+		}
+		if n == 0 {
+			superseded = append(superseded, d.PreviousExternalID)
 		}
-		entityIDs[d.ExternalID] = entity.ID
 	}
 
-	// 10. Mark disappeared entities as stale
+	// 10. Upsert appeared + changed entities in batches, collecting entity IDs
+	entityIDs := make(map[string]pgtype.UUID, len(storedEntities)+len(diffResult.Appeared))
+
+	upserts := make([]differ.EntityDiff, 0, len(diffResult.Appeared)+len(diffResult.Changed))
+	upserts = append(upserts, diffResult.Appeared...)
+	upserts = append(upserts, diffResult.Changed...)
+	if err := upsertEntities(ctx, q, watch, schema, upserts, sources, entityIDs); err != nil {
+		return err
+	}
+
+	// 11. Count a missed run for entities within their grace window, and mark
+	// disappeared entities as stale
 	// Also collect their IDs from stored entities for event emission
 	for i := range storedEntities {
 		entityIDs[storedEntities[i].ExternalID] = storedEntities[i].ID
 	}
 
-	if len(diffResult.Disappeared) > 0 {
-		staleIDs := make([]string, len(diffResult.Disappeared))
-		for i, d := range diffResult.Disappeared {
-			staleIDs[i] = d.ExternalID
+	if len(missed) > 0 {
+		if err := q.MarkEntitiesMissed(ctx, dbgen.MarkEntitiesMissedParams{
+			WatchID: watch.ID,
+			Column2: missed,
+		}); err != nil {
+			return fmt.Errorf("marking entities missed: %w", err)
+		}
+	}
+
+	if len(diffResult.Disappeared)+len(superseded) > 0 {
+		staleIDs := make([]string, 0, len(diffResult.Disappeared)+len(superseded))
+		for _, d := range diffResult.Disappeared {
+			staleIDs = append(staleIDs, d.ExternalID)
 		}
-		if err := e.queries.MarkEntitiesStale(ctx, dbgen.MarkEntitiesStaleParams{
+		staleIDs = append(staleIDs, superseded...)
+		if err := q.MarkEntitiesStale(ctx, dbgen.MarkEntitiesStaleParams{
 			WatchID: watch.ID,
 			Column2: staleIDs,
 		}); err != nil {
-			logger.Warn("failed to mark entities stale", "error", err)
+			return fmt.Errorf("marking entities stale: %w", err)
 		}
 	}
 
-	// 11. Emit events for diff results
-	eventsEmitted, err := e.emitter.EmitDiffEvents(ctx, emitter.EmitContext{
+	// Append the new versions to the entities' history
+	if err := recordVersions(ctx, q, watch, runID, &diffResult, superseded, entityIDs); err != nil {
+		return err
+	}
+
+	// 12. Emit events (and their deliveries) for diff results. Disappeared
+	// events report what was last stored about the entity.
+	src := emitter.DiffSource{
+		WatchID:   db.UUIDToString(watch.ID),
+		Name:      watch.Name,
+		URL:       watch.Url,
+		LastKnown: make(map[string]emitter.LastKnown, len(diffResult.Disappeared)),
+	}
+	for _, d := range diffResult.Disappeared {
+		entity := storedByExternalID[d.ExternalID]
+		src.LastKnown[d.ExternalID] = emitter.LastKnown{
+			Content:     stored[d.ExternalID],
+			SourceURL:   entity.SourceUrl.String,
+			SourceParam: entity.SourceParam.String,
+			FirstSeenAt: entity.FirstSeenAt.Time,
+			LastSeenAt:  entity.LastSeenAt.Time,
+		}
+	}
+	eventsEmitted, err := e.emitter.WithTx(tx).EmitDiffEvents(ctx, emitter.EmitContext{
 		OrgID:      watch.OrgID,
 		WatchID:    watch.ID,
 		WatchRunID: runID,
-	}, &diffResult, entityIDs)
+	}, &diffResult, src, entityIDs)
 	if err != nil {
-		logger.Warn("failed to emit events", "error", err)
+		return fmt.Errorf("emitting events: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing run results: %w", err)
 	}
 	stats.eventsEmitted = eventsEmitted
 
 	logger.Info("events emitted", "count", eventsEmitted)
 
-	return stats, nil
+	return nil
 }
 
-func (e *Executor) updateWatchAfterRun(ctx context.Context, watch *dbgen.GetDueWatchesRow, logger *slog.Logger) {
-	nextRun := computeNextRun(watch.Schedule, time.Now())
+// discardSuspectRun records a run that tripped a safety guard: nothing is
+// written to entities, and a watch_suspect event is emitted, in a transaction
+// of its own, unless the previous run was already suspect, so a lasting
+// breakage alerts once.
+func (e *Executor) discardSuspectRun(
+	ctx context.Context,
+	watch *dbgen.ClaimDueWatchesRow,
+	runID pgtype.UUID,
+	trip *guardTrip,
+	stats *runStats,
+	logger *slog.Logger,
+) error {
+	stats.suspect = trip.Reason
+	logger.Warn("run tripped safety guard, results discarded",
+		"reason", trip.Reason,
+		"found", trip.Found,
+		"previous", trip.Previous,
+		"missing", trip.Missing,
+	)
 
-	if err := e.queries.UpdateWatchAfterRun(ctx, dbgen.UpdateWatchAfterRunParams{
-		ID:        watch.ID,
-		NextRunAt: pgtype.Timestamptz{Time: nextRun, Valid: true},
-	}); err != nil {
-		logger.Error("failed to update watch after run", "error", err)
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
 	}
-}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := e.queries.WithTx(tx)
 
-// computeExternalID creates a SHA-256 hash from the identity field values.
-func computeExternalID(entity map[string]any, identityFields []string) string {
-	var parts []string
-	// Sort identity fields for deterministic hashing
-	fields := make([]string, len(identityFields))
-	copy(fields, identityFields)
-	sort.Strings(fields)
-
-	for _, field := range fields {
-		val, ok := entity[field]
-		if !ok || val == nil {
-			parts = append(parts, "")
-			continue
-		}
-		parts = append(parts, strings.TrimSpace(fmt.Sprintf("%v", val)))
+	prevStatus, err := q.GetPreviousWatchRunStatus(ctx, dbgen.GetPreviousWatchRunStatusParams{
+		WatchID: watch.ID,
+		ID:      runID,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("getting previous run status: %w", err)
+	}
+	if prevStatus == "suspect" {
+		return nil
 	}
 
-	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
-	return fmt.Sprintf("%x", hash[:16]) // 32 hex chars
-}
+	if err := e.emitter.WithTx(tx).EmitWatchSuspect(ctx, emitter.EmitContext{
+		OrgID:      watch.OrgID,
+		WatchID:    watch.ID,
+		WatchRunID: runID,
+	}, emitter.WatchSuspect{
+		WatchID:          db.UUIDToString(watch.ID),
+		Name:             watch.Name,
+		URL:              watch.Url,
+		Reason:           trip.Reason,
+		EntitiesFound:    trip.Found,
+		EntitiesPrevious: trip.Previous,
+		EntitiesMissing:  trip.Missing,
+	}); err != nil {
+		return fmt.Errorf("emitting watch_suspect event: %w", err)
+	}
 
-// computeNextRun calculates the next run time from a cron expression.
-func computeNextRun(schedule string, from time.Time) time.Time {
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	sched, err := parser.Parse(schedule)
-	if err != nil {
-		// Fall back to 1 hour if cron parse fails
-		return from.Add(time.Hour)
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing suspect run: %w", err)
 	}
-	return sched.Next(from)
+	stats.eventsEmitted = 1
+	return nil
 }
 
-func uuidToString(id pgtype.UUID) string {
-	if !id.Valid {
-		return ""
+// upsertEntities writes entity content, tagged with the page each entity was
+// found on, in batches of upsertBatchSize and records the resulting entity
+// IDs in entityIDs.
+func upsertEntities(
+	ctx context.Context,
+	q *dbgen.Queries,
+	watch *dbgen.ClaimDueWatchesRow,
+	schema *blueprint.EntitySchema,
+	diffs []differ.EntityDiff,
+	sources map[string]fetchTarget,
+	entityIDs map[string]pgtype.UUID,
+) error {
+	for start := 0; start < len(diffs); start += upsertBatchSize {
+		batch := diffs[start:min(start+upsertBatchSize, len(diffs))]
+
+		params := dbgen.UpsertEntitiesParams{
+			OrgID:            watch.OrgID,
+			WatchID:          watch.ID,
+			SchemaType:       watch.SchemaType,
+			SchemaVersion:    int32(schema.Version),
+			BlueprintVersion: watch.BlueprintVersion,
+			ExternalIds:      make([]string, len(batch)),
+			Contents:         make([]string, len(batch)),
+			SourceUrls:       make([]string, len(batch)),
+			SourceParams:     make([]string, len(batch)),
+		}
+		for i, d := range batch {
+			contentBytes, err := json.Marshal(d.Content)
+			if err != nil {
+				return fmt.Errorf("marshalling content of %s: %w", d.ExternalID, err)
+			}
+			params.ExternalIds[i] = d.ExternalID
+			params.Contents[i] = string(contentBytes)
+			params.SourceUrls[i] = sources[d.ExternalID].URL
+			params.SourceParams[i] = sources[d.ExternalID].Param
+		}
+
+		rows, err := q.UpsertEntities(ctx, params)
+		if err != nil {
+			return fmt.Errorf("upserting entities: %w", err)
+		}
+		for _, row := range rows {
+			entityIDs[row.ExternalID] = row.ID
+		}
 	}
-	b := id.Bytes
-	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	return nil
 }
 
 func pgInt4(v int) pgtype.Int4 {
@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/blueprinter/worker/internal/differ"
+)
+
+// withinGrace reports whether an entity missing from the current run should
+// stay active. missedRuns is the number of consecutive earlier runs it was
+// already missing from and lastSeen is when it was last extracted. The entity
+// is declared gone only once both the watch's missed-run count and grace
+// period are exhausted.
+func withinGrace(graceMissedRuns, gracePeriodSeconds, missedRuns int32, lastSeen, now time.Time) bool {
+	if missedRuns+1 < graceMissedRuns {
+		return true
+	}
+	return gracePeriodSeconds > 0 && now.Sub(lastSeen) < time.Duration(gracePeriodSeconds)*time.Second
+}
+
+// splitDisappeared separates the entities the differ reported as disappeared
+// into those that are gone and the external IDs of those still within their
+// grace window.
+func splitDisappeared(disappeared []differ.EntityDiff, inGrace func(externalID string) bool) (gone []differ.EntityDiff, pending []string) {
+	for _, d := range disappeared {
+		if inGrace(d.ExternalID) {
+			pending = append(pending, d.ExternalID)
+			continue
+		}
+		gone = append(gone, d)
+	}
+	return gone, pending
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/blueprinter/worker/internal/differ"
+)
+
+func TestWithinGrace_Default(t *testing.T) {
+	now := time.Now()
+	// One missed run, no grace period: gone immediately.
+	assert.False(t, withinGrace(1, 0, 0, now.Add(-time.Hour), now))
+}
+
+func TestWithinGrace_MissedRuns(t *testing.T) {
+	now := time.Now()
+	lastSeen := now.Add(-time.Hour)
+
+	assert.True(t, withinGrace(3, 0, 0, lastSeen, now))
+	assert.True(t, withinGrace(3, 0, 1, lastSeen, now))
+	assert.False(t, withinGrace(3, 0, 2, lastSeen, now))
+}
+
+func TestWithinGrace_Period(t *testing.T) {
+	now := time.Now()
+
+	assert.True(t, withinGrace(1, 7200, 5, now.Add(-time.Hour), now))
+	assert.False(t, withinGrace(1, 7200, 5, now.Add(-3*time.Hour), now))
+
+	// Both must be exhausted.
+	assert.True(t, withinGrace(3, 60, 0, now.Add(-time.Hour), now))
+}
+
+func TestSplitDisappeared(t *testing.T) {
+	disappeared := []differ.EntityDiff{
+		{ExternalID: "a", Type: "disappeared"},
+		{ExternalID: "b", Type: "disappeared"},
+		{ExternalID: "c", Type: "disappeared"},
+	}
+
+	gone, pending := splitDisappeared(disappeared, func(eid string) bool { return eid == "b" })
+
+	assert.Equal(t, []differ.EntityDiff{
+		{ExternalID: "a", Type: "disappeared"},
+		{ExternalID: "c", Type: "disappeared"},
+	}, gone)
+	assert.Equal(t, []string{"b"}, pending)
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"fmt"
+)
+
+// guardTrip describes why a run's extraction was judged untrustworthy.
+type guardTrip struct {
+	Reason   string
+	Found    int
+	Previous int
+	Missing  int
+}
+
+// checkGuards compares a run's extraction with the entities that were active
+// before it. A run that finds fewer than minCount entities, or in which more
+// than maxRatio of the previous entities are missing, is far more likely a
+// layout change or a block page than real removals, so it trips a guard.
+// Guards only apply once a watch has active entities. renamed is the number
+// of missing entities that were matched to an appeared one under a new
+// identity; they don't count as missing. It returns nil when the run can be
+// applied.
+func checkGuards(minCount int32, maxRatio float64, extracted, previous map[string]map[string]any, renamed int) *guardTrip {
+	if len(previous) == 0 {
+		return nil
+	}
+
+	missing := -renamed
+	for eid := range previous {
+		if _, ok := extracted[eid]; !ok {
+			missing++
+		}
+	}
+
+	trip := &guardTrip{
+		Found:    len(extracted),
+		Previous: len(previous),
+		Missing:  missing,
+	}
+
+	if len(extracted) < int(minCount) {
+		trip.Reason = fmt.Sprintf("%d entities extracted, minimum is %d", len(extracted), minCount)
+		return trip
+	}
+
+	if ratio := float64(missing) / float64(len(previous)); ratio > maxRatio {
+		trip.Reason = fmt.Sprintf("%d of %d entities disappeared (%.0f%%), maximum is %.0f%%",
+			missing, len(previous), ratio*100, maxRatio*100)
+		return trip
+	}
+
+	return nil
+}
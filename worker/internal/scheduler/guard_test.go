@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func entitySet(ids ...string) map[string]map[string]any {
+	m := make(map[string]map[string]any, len(ids))
+	for _, id := range ids {
+		m[id] = map[string]any{"name": id}
+	}
+	return m
+}
+
+func TestCheckGuards_NoPreviousEntities(t *testing.T) {
+	assert.Nil(t, checkGuards(1, 0.5, entitySet(), entitySet(), 0))
+}
+
+func TestCheckGuards_MinEntityCount(t *testing.T) {
+	trip := checkGuards(1, 1, entitySet(), entitySet("a", "b", "c"), 0)
+	require.NotNil(t, trip)
+	assert.Equal(t, "0 entities extracted, minimum is 1", trip.Reason)
+	assert.Equal(t, 0, trip.Found)
+	assert.Equal(t, 3, trip.Previous)
+	assert.Equal(t, 3, trip.Missing)
+}
+
+func TestCheckGuards_DisappearanceRatio(t *testing.T) {
+	previous := entitySet("a", "b", "c", "d")
+
+	// Half gone is still within a 0.5 ratio, and new entities don't offset missing ones.
+	assert.Nil(t, checkGuards(1, 0.5, entitySet("a", "b", "x"), previous, 0))
+
+	trip := checkGuards(1, 0.5, entitySet("a", "x", "y"), previous, 0)
+	require.NotNil(t, trip)
+	assert.Equal(t, "3 of 4 entities disappeared (75%), maximum is 50%", trip.Reason)
+	assert.Equal(t, 3, trip.Missing)
+}
+
+func TestCheckGuards_Disabled(t *testing.T) {
+	assert.Nil(t, checkGuards(0, 1, entitySet(), entitySet("a", "b"), 0))
+}
+
+func TestCheckGuards_RenamesNotMissing(t *testing.T) {
+	previous := entitySet("a", "b", "c", "d")
+
+	// Three of four IDs are gone, but two of them were renamed.
+	assert.Nil(t, checkGuards(1, 0.5, entitySet("a", "b2", "c2", "x"), previous, 2))
+
+	trip := checkGuards(1, 0.5, entitySet("a", "b2", "c2", "x"), previous, 0)
+	require.NotNil(t, trip)
+	assert.Equal(t, 3, trip.Missing)
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/blueprinter/worker/internal/db"
+	"github.com/blueprinter/worker/internal/db/dbgen"
+	"github.com/blueprinter/worker/internal/emitter"
+)
+
+const (
+	// maxConsecutiveFailures is the number of failed runs in a row after
+	// which an active watch is moved to the error status.
+	maxConsecutiveFailures = 3
+
+	failureBackoffBase = time.Minute
+	failureBackoffMax  = time.Hour
+)
+
+// failureBackoff returns how long to wait before retrying a watch that has
+// failed the given number of times in a row: 1m, 2m, 4m, ... up to 1h.
+func failureBackoff(failures int) time.Duration {
+	if failures < 1 {
+		return 0
+	}
+	backoff := failureBackoffBase
+	for i := 1; i < failures && backoff < failureBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, failureBackoffMax)
+}
+
+// nextRunAfterFailure returns the later of the regular next run and the
+// failure backoff, so a failing watch is never retried more often than its
+// schedule allows.
+func nextRunAfterFailure(next time.Time, failures int, now time.Time) time.Time {
+	if retry := now.Add(failureBackoff(failures)); retry.After(next) {
+		return retry
+	}
+	return next
+}
+
+// updateWatchAfterRun records the outcome of a run on the watch: it schedules
+// the next run, tracks consecutive failures, and moves the watch to error
+// (emitting a watch_error event) when the failure threshold is reached.
+func (e *Executor) updateWatchAfterRun(ctx context.Context, watch *dbgen.ClaimDueWatchesRow, runID pgtype.UUID, execErr error, logger *slog.Logger) {
+	now := time.Now()
+
+	// An invalid schedule is never guessed at: the watch moves to error at
+	// once so the problem is surfaced.
+	sched, err := ParseSchedule(watch.Schedule, watch.Timezone, watch.JitterSeconds)
+	if err != nil {
+		e.recordWatchFailure(ctx, watch, runID, err, now.Add(failureBackoffMax), 1, logger)
+		return
+	}
+
+	if execErr == nil {
+		next := sched.NextRun(now)
+		var interval pgtype.Int4
+		if watch.AdaptiveSchedule {
+			d := e.nextAdaptiveInterval(ctx, watch, sched, now, logger)
+			next = sched.After(now, d)
+			interval = pgInt4(int(d / time.Second))
+		}
+		if err := e.queries.RecordWatchSuccess(ctx, dbgen.RecordWatchSuccessParams{
+			NextRunAt:               pgtype.Timestamptz{Time: next, Valid: true},
+			AdaptiveIntervalSeconds: interval,
+			ID:                      watch.ID,
+			Owner:                   e.workerID,
+		}); err != nil {
+			logger.Error("failed to update watch after run", "error", err)
+			return
+		}
+		if watch.Status == "error" {
+			logger.Info("watch recovered from error")
+		}
+		return
+	}
+
+	// A run whose lease was lost no longer owns the watch: another worker may
+	// be running it, so its schedule and health are left to that run.
+	if errors.Is(execErr, errLeaseLost) {
+		return
+	}
+
+	// A cancelled or interrupted run says nothing about the watch's health.
+	// An interrupted one is due again at once, for another worker to pick up.
+	if errors.Is(execErr, ErrRunCancelled) || errors.Is(execErr, ErrShutdown) {
+		next := regularNextRun(watch, sched, now)
+		if errors.Is(execErr, ErrShutdown) {
+			next = now
+		}
+		if err := e.queries.RecordWatchCancelled(ctx, dbgen.RecordWatchCancelledParams{
+			NextRunAt: pgtype.Timestamptz{Time: next, Valid: true},
+			ID:        watch.ID,
+			Owner:     e.workerID,
+		}); err != nil {
+			logger.Error("failed to update watch after run", "error", err)
+		}
+		return
+	}
+
+	failures := int(watch.ConsecutiveFailures) + 1
+	e.recordWatchFailure(ctx, watch, runID, execErr, nextRunAfterFailure(regularNextRun(watch, sched, now), failures, now), maxConsecutiveFailures, logger)
+}
+
+// recordWatchFailure counts a failed run and schedules the retry. An active
+// watch that reaches maxFailures moves to error, with a single watch_error
+// event raised on the transition.
+func (e *Executor) recordWatchFailure(ctx context.Context, watch *dbgen.ClaimDueWatchesRow, runID pgtype.UUID, failErr error, next time.Time, maxFailures int32, logger *slog.Logger) {
+	res, err := e.queries.RecordWatchFailure(ctx, dbgen.RecordWatchFailureParams{
+		NextRunAt:   pgtype.Timestamptz{Time: next, Valid: true},
+		LastError:   pgtype.Text{String: failErr.Error(), Valid: true},
+		MaxFailures: maxFailures,
+		ID:          watch.ID,
+		Owner:       e.workerID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Warn("watch lease lost before the failure was recorded", "error", failErr)
+		return
+	}
+	if err != nil {
+		logger.Error("failed to update watch after run", "error", err)
+		return
+	}
+
+	logger.Warn("watch run failed",
+		"error", failErr,
+		"consecutive_failures", res.ConsecutiveFailures,
+		"status", res.Status,
+	)
+
+	// Alert once, on the transition into error.
+	if res.Status != "error" || watch.Status == "error" {
+		return
+	}
+	if err := e.emitWatchError(ctx, watch, runID, failErr, int(res.ConsecutiveFailures)); err != nil {
+		logger.Error("failed to emit watch_error event", "error", err)
+		return
+	}
+	logger.Warn("watch moved to error", "consecutive_failures", res.ConsecutiveFailures)
+}
+
+// emitWatchError persists a watch_error event together with its deliveries.
+func (e *Executor) emitWatchError(ctx context.Context, watch *dbgen.ClaimDueWatchesRow, runID pgtype.UUID, execErr error, failures int) error {
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := e.emitter.WithTx(tx).EmitWatchError(ctx, emitter.EmitContext{
+		OrgID:      watch.OrgID,
+		WatchID:    watch.ID,
+		WatchRunID: runID,
+	}, emitter.WatchError{
+		WatchID:             db.UUIDToString(watch.ID),
+		Name:                watch.Name,
+		URL:                 watch.Url,
+		Error:               execErr.Error(),
+		ConsecutiveFailures: failures,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
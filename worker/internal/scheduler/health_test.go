@@ -0,0 +1,27 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureBackoff(t *testing.T) {
+	assert.Equal(t, time.Duration(0), failureBackoff(0))
+	assert.Equal(t, time.Minute, failureBackoff(1))
+	assert.Equal(t, 2*time.Minute, failureBackoff(2))
+	assert.Equal(t, 32*time.Minute, failureBackoff(6))
+	assert.Equal(t, time.Hour, failureBackoff(7))
+	assert.Equal(t, time.Hour, failureBackoff(100))
+}
+
+func TestNextRunAfterFailure(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 30, 0, time.UTC)
+
+	// Every minute: the backoff wins once it exceeds the schedule.
+	assert.Equal(t, now.Add(4*time.Minute), nextRunAfterFailure(mustParseSchedule(t, "* * * * *", "").NextRun(now), 3, now))
+
+	// Daily: the schedule is already later than any backoff.
+	assert.Equal(t, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), nextRunAfterFailure(mustParseSchedule(t, "0 0 * * *", "").NextRun(now), 7, now))
+}
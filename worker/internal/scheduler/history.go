@@ -0,0 +1,271 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/blueprinter/worker/internal/db"
+	"github.com/blueprinter/worker/internal/db/dbgen"
+	"github.com/blueprinter/worker/internal/differ"
+)
+
+// ErrEntityNotFound is returned when an entity doesn't exist or belongs to another org.
+var ErrEntityNotFound = errors.New("entity not found")
+
+// EntityHistory is the recorded versions of an entity, oldest first, or with
+// a field given, the timeline of that field's value.
+type EntityHistory struct {
+	EntityID   string          `json:"entity_id"`
+	WatchID    string          `json:"watch_id"`
+	ExternalID string          `json:"external_id"`
+	Status     string          `json:"status"`
+	Versions   []EntityVersion `json:"versions,omitempty"`
+	Field      string          `json:"field,omitempty"`
+	Timeline   []FieldValue    `json:"timeline,omitempty"`
+}
+
+// EntityVersion is the content an entity had from a run on. Disappeared
+// versions have no content.
+type EntityVersion struct {
+	WatchRunID string         `json:"watch_run_id"`
+	ChangeType string         `json:"change_type"` // appeared, changed, disappeared
+	ExternalID string         `json:"external_id"`
+	RecordedAt time.Time      `json:"recorded_at"`
+	Content    map[string]any `json:"content,omitempty"`
+}
+
+// FieldValue is the value a field took from a run on.
+type FieldValue struct {
+	WatchRunID string    `json:"watch_run_id"`
+	ChangeType string    `json:"change_type"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Value      any       `json:"value"`
+}
+
+// EntitySet is the entities of a watch as they were at a point in time.
+type EntitySet struct {
+	WatchID  string       `json:"watch_id"`
+	AsOf     time.Time    `json:"as_of"`
+	Entities []EntityAsOf `json:"entities"`
+}
+
+// EntityAsOf is an entity's content at a point in time and when it was
+// recorded.
+type EntityAsOf struct {
+	EntityID   string         `json:"entity_id"`
+	ExternalID string         `json:"external_id"`
+	Content    map[string]any `json:"content"`
+	Since      time.Time      `json:"since"`
+}
+
+// entityVersionRow is one row of a run's entity versions.
+type entityVersionRow struct {
+	entityID   pgtype.UUID
+	externalID string
+	changeType string
+	content    map[string]any
+}
+
+// versionRows lists a version for every entity a run reported as appeared,
+// changed or disappeared, plus a disappeared version for each superseded
+// entity. It fails if entityIDs is missing any of them rather than leave a
+// version without its entity.
+func versionRows(diff *differ.DiffResult, superseded []string, entityIDs map[string]pgtype.UUID) ([]entityVersionRow, error) {
+	rows := make([]entityVersionRow, 0, len(diff.Appeared)+len(diff.Changed)+len(diff.Disappeared)+len(superseded))
+	add := func(externalID, changeType string, content map[string]any) error {
+		id, ok := entityIDs[externalID]
+		if !ok {
+			return fmt.Errorf("no entity ID for %s version of %s", changeType, externalID)
+		}
+		rows = append(rows, entityVersionRow{id, externalID, changeType, content})
+		return nil
+	}
+	for _, d := range diff.Appeared {
+		if err := add(d.ExternalID, "appeared", d.Content); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range diff.Changed {
+		if err := add(d.ExternalID, "changed", d.Content); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range diff.Disappeared {
+		if err := add(d.ExternalID, "disappeared", nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, eid := range superseded {
+		if err := add(eid, "disappeared", nil); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// recordVersions appends the versions from versionRows to the entities'
+// history. entityIDs must hold the IDs of all of them.
+func recordVersions(
+	ctx context.Context,
+	q *dbgen.Queries,
+	watch *dbgen.ClaimDueWatchesRow,
+	runID pgtype.UUID,
+	diff *differ.DiffResult,
+	superseded []string,
+	entityIDs map[string]pgtype.UUID,
+) error {
+	versions, err := versionRows(diff, superseded, entityIDs)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(versions); start += upsertBatchSize {
+		batch := versions[start:min(start+upsertBatchSize, len(versions))]
+
+		params := dbgen.InsertEntityVersionsParams{
+			OrgID:       watch.OrgID,
+			WatchID:     watch.ID,
+			WatchRunID:  runID,
+			EntityIds:   make([]pgtype.UUID, len(batch)),
+			ExternalIds: make([]string, len(batch)),
+			ChangeTypes: make([]string, len(batch)),
+			Contents:    make([]string, len(batch)),
+		}
+		for i, v := range batch {
+			params.EntityIds[i] = v.entityID
+			params.ExternalIds[i] = v.externalID
+			params.ChangeTypes[i] = v.changeType
+			if v.content != nil {
+				content, err := json.Marshal(v.content)
+				if err != nil {
+					return fmt.Errorf("marshalling content of %s: %w", v.externalID, err)
+				}
+				params.Contents[i] = string(content)
+			}
+		}
+		if err := q.InsertEntityVersions(ctx, params); err != nil {
+			return fmt.Errorf("inserting entity versions: %w", err)
+		}
+	}
+	return nil
+}
+
+// EntityHistory returns the recorded versions of an entity. With a field, it
+// returns the timeline of that field instead.
+func (e *Executor) EntityHistory(ctx context.Context, orgID, entityID, field string) (*EntityHistory, error) {
+	var id pgtype.UUID
+	if err := id.Scan(entityID); err != nil {
+		return nil, ErrEntityNotFound
+	}
+
+	entity, err := e.queries.GetEntity(ctx, dbgen.GetEntityParams{ID: id, OrgID: orgID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEntityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting entity: %w", err)
+	}
+
+	rows, err := e.queries.ListEntityVersions(ctx, dbgen.ListEntityVersionsParams{EntityID: id, OrgID: orgID})
+	if err != nil {
+		return nil, fmt.Errorf("listing entity versions: %w", err)
+	}
+	versions := make([]EntityVersion, len(rows))
+	for i := range rows {
+		versions[i] = EntityVersion{
+			WatchRunID: db.UUIDToString(rows[i].WatchRunID),
+			ChangeType: rows[i].ChangeType,
+			ExternalID: rows[i].ExternalID,
+			RecordedAt: rows[i].CreatedAt.Time,
+		}
+		if len(rows[i].Content) > 0 {
+			if err := json.Unmarshal(rows[i].Content, &versions[i].Content); err != nil {
+				return nil, fmt.Errorf("decoding version content: %w", err)
+			}
+		}
+	}
+
+	history := &EntityHistory{
+		EntityID:   db.UUIDToString(entity.ID),
+		WatchID:    db.UUIDToString(entity.WatchID),
+		ExternalID: entity.ExternalID,
+		Status:     entity.Status,
+	}
+	if field == "" {
+		history.Versions = versions
+	} else {
+		history.Field = field
+		history.Timeline = fieldTimeline(versions, field)
+	}
+	return history, nil
+}
+
+// fieldTimeline reduces versions to the points where a field's value changed,
+// along with every appearance and disappearance.
+func fieldTimeline(versions []EntityVersion, field string) []FieldValue {
+	timeline := []FieldValue{}
+	for i, v := range versions {
+		value := v.Content[field]
+		if i > 0 && v.ChangeType == "changed" && versions[i-1].ChangeType != "disappeared" &&
+			reflect.DeepEqual(value, timeline[len(timeline)-1].Value) {
+			continue
+		}
+		timeline = append(timeline, FieldValue{
+			WatchRunID: v.WatchRunID,
+			ChangeType: v.ChangeType,
+			RecordedAt: v.RecordedAt,
+			Value:      value,
+		})
+	}
+	return timeline
+}
+
+// WatchEntitiesAsOf returns the entities of a watch as they were at asOf,
+// from their recorded versions.
+func (e *Executor) WatchEntitiesAsOf(ctx context.Context, orgID, watchID string, asOf time.Time) (*EntitySet, error) {
+	var id pgtype.UUID
+	if err := id.Scan(watchID); err != nil {
+		return nil, ErrWatchNotFound
+	}
+
+	watch, err := e.queries.GetWatchByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && watch.OrgID != orgID) {
+		return nil, ErrWatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting watch: %w", err)
+	}
+
+	rows, err := e.queries.ListWatchEntitiesAsOf(ctx, dbgen.ListWatchEntitiesAsOfParams{
+		WatchID: id,
+		OrgID:   orgID,
+		AsOf:    pgtype.Timestamptz{Time: asOf, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing entity versions: %w", err)
+	}
+
+	set := &EntitySet{
+		WatchID:  db.UUIDToString(watch.ID),
+		AsOf:     asOf,
+		Entities: make([]EntityAsOf, len(rows)),
+	}
+	for i := range rows {
+		set.Entities[i] = EntityAsOf{
+			EntityID:   db.UUIDToString(rows[i].EntityID),
+			ExternalID: rows[i].ExternalID,
+			Since:      rows[i].CreatedAt.Time,
+		}
+		if err := json.Unmarshal(rows[i].Content, &set.Entities[i].Content); err != nil {
+			return nil, fmt.Errorf("decoding version content: %w", err)
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blueprinter/worker/internal/differ"
+)
+
+func TestFieldTimeline(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2026, 3, day, 12, 0, 0, 0, time.UTC) }
+	versions := []EntityVersion{
+		{WatchRunID: "r1", ChangeType: "appeared", RecordedAt: at(1), Content: map[string]any{"name": "A", "price": float64(100)}},
+		{WatchRunID: "r2", ChangeType: "changed", RecordedAt: at(2), Content: map[string]any{"name": "A+", "price": float64(100)}},
+		{WatchRunID: "r3", ChangeType: "changed", RecordedAt: at(3), Content: map[string]any{"name": "A+", "price": float64(90)}},
+		{WatchRunID: "r4", ChangeType: "disappeared", RecordedAt: at(4)},
+		{WatchRunID: "r5", ChangeType: "appeared", RecordedAt: at(5), Content: map[string]any{"name": "A+", "price": float64(90)}},
+		{WatchRunID: "r6", ChangeType: "changed", RecordedAt: at(6), Content: map[string]any{"name": "A+"}},
+	}
+
+	got := fieldTimeline(versions, "price")
+
+	assert.Equal(t, []FieldValue{
+		{WatchRunID: "r1", ChangeType: "appeared", RecordedAt: at(1), Value: float64(100)},
+		{WatchRunID: "r3", ChangeType: "changed", RecordedAt: at(3), Value: float64(90)},
+		{WatchRunID: "r4", ChangeType: "disappeared", RecordedAt: at(4), Value: nil},
+		{WatchRunID: "r5", ChangeType: "appeared", RecordedAt: at(5), Value: float64(90)},
+		{WatchRunID: "r6", ChangeType: "changed", RecordedAt: at(6), Value: nil},
+	}, got)
+}
+
+func TestFieldTimeline_Empty(t *testing.T) {
+	assert.Equal(t, []FieldValue{}, fieldTimeline(nil, "price"))
+}
+
+func TestVersionRows_RenamedAndSuperseded(t *testing.T) {
+	id := func(b byte) pgtype.UUID { return pgtype.UUID{Bytes: [16]byte{b}, Valid: true} }
+	diff := &differ.DiffResult{
+		Appeared: []differ.EntityDiff{{ExternalID: "new", Content: map[string]any{"name": "New"}}},
+		Changed: []differ.EntityDiff{
+			{ExternalID: "b-v2", PreviousExternalID: "b", Content: map[string]any{"name": "B v2"}},
+			{ExternalID: "c-v2", PreviousExternalID: "c", Content: map[string]any{"name": "C v2"}},
+		},
+		Disappeared: []differ.EntityDiff{{ExternalID: "gone"}},
+	}
+	// b was moved to b-v2 and kept its row; c-v2 already existed (a stale
+	// entity coming back), so c was superseded and keeps its old ID.
+	entityIDs := map[string]pgtype.UUID{
+		"new": id(1), "b-v2": id(2), "c-v2": id(3), "c": id(4), "gone": id(5),
+	}
+
+	rows, err := versionRows(diff, []string{"c"}, entityIDs)
+	require.NoError(t, err)
+
+	assert.Equal(t, []entityVersionRow{
+		{id(1), "new", "appeared", map[string]any{"name": "New"}},
+		{id(2), "b-v2", "changed", map[string]any{"name": "B v2"}},
+		{id(3), "c-v2", "changed", map[string]any{"name": "C v2"}},
+		{id(5), "gone", "disappeared", nil},
+		{id(4), "c", "disappeared", nil},
+	}, rows)
+}
+
+func TestVersionRows_MissingEntityID(t *testing.T) {
+	diff := &differ.DiffResult{
+		Changed: []differ.EntityDiff{{ExternalID: "b-v2", PreviousExternalID: "b"}},
+	}
+
+	_, err := versionRows(diff, nil, map[string]pgtype.UUID{"b": {Valid: true}})
+	assert.ErrorContains(t, err, "b-v2")
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/blueprinter/worker/internal/identity"
+)
+
+// identityStats counts the entities of a run that could not be told apart.
+type identityStats struct {
+	collisions int // dropped: an earlier entity of the same page had the same external ID
+	missing    int // dropped: no identity field had a value
+}
+
+// watchIdentity builds the identity spec of a watch from its fallbacks
+// (a JSON array of field name arrays) and normalizations.
+func watchIdentity(fields []string, fallbacks []byte, normalize []string) (identity.Spec, error) {
+	var sets [][]string
+	if len(fallbacks) > 0 {
+		if err := json.Unmarshal(fallbacks, &sets); err != nil {
+			return identity.Spec{}, fmt.Errorf("parsing identity fallbacks: %w", err)
+		}
+	}
+	return identity.NewSpec(fields, sets, normalize)
+}
+
+// keyEntities adds the entities of one page to extracted, keyed by external
+// ID, and returns the IDs it added. Entities without identity are dropped
+// rather than collapsed into one, and an ID is kept by the first entity to
+// claim it. Only a clash within the page counts as a collision: an entity
+// also listed on an earlier page is the same entity and is skipped.
+func keyEntities(
+	entities []map[string]any,
+	spec identity.Spec,
+	extracted map[string]map[string]any,
+	stats *identityStats,
+) []string {
+	added := make([]string, 0, len(entities))
+	onPage := make(map[string]bool, len(entities))
+	for _, entity := range entities {
+		eid, ok := spec.Key(entity)
+		if !ok {
+			stats.missing++
+			continue
+		}
+		if onPage[eid] {
+			stats.collisions++
+			continue
+		}
+		onPage[eid] = true
+		if _, listed := extracted[eid]; listed {
+			continue
+		}
+		extracted[eid] = entity
+		added = append(added, eid)
+	}
+	return added
+}
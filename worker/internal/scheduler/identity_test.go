@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyEntities(t *testing.T) {
+	spec, err := watchIdentity([]string{"name"}, []byte(`[["url"]]`), nil)
+	require.NoError(t, err)
+
+	extracted := map[string]map[string]any{}
+	var stats identityStats
+	added := keyEntities([]map[string]any{
+		{"name": "A", "price": 1},
+		{"name": "A", "price": 2},
+		{"url": "https://example.com/b"},
+		{"price": 3},
+		{"price": 4},
+	}, spec, extracted, &stats)
+
+	assert.Len(t, added, 2)
+	assert.Len(t, extracted, 2)
+	assert.Equal(t, identityStats{collisions: 1, missing: 2}, stats)
+	assert.Equal(t, 1, extracted[added[0]]["price"], "the first entity keeps the ID")
+
+	// The same entity on a later page is not a collision.
+	first := added[0]
+	added = keyEntities([]map[string]any{
+		{"name": "A", "price": 5},
+		{"name": "C", "price": 6},
+	}, spec, extracted, &stats)
+
+	assert.Len(t, added, 1)
+	assert.Len(t, extracted, 3)
+	assert.Equal(t, identityStats{collisions: 1, missing: 2}, stats)
+	assert.Equal(t, 1, extracted[first]["price"], "the first page keeps the entity")
+}
+
+func TestWatchIdentity_InvalidFallbacks(t *testing.T) {
+	_, err := watchIdentity([]string{"name"}, []byte(`["url"]`), nil)
+	assert.ErrorContains(t, err, "parsing identity fallbacks")
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+const (
+	// watchLease is how long a claimed watch stays hidden from other workers
+	// without a heartbeat. A crashed worker's watches are due again after it.
+	watchLease = 2 * time.Minute
+
+	// leaseHeartbeat is how often a running watch's lease is renewed.
+	leaseHeartbeat = 30 * time.Second
+)
+
+// ErrWatchRunning is returned when a manual run is requested for a watch that
+// another run currently holds.
+var ErrWatchRunning = errors.New("watch is already running")
+
+// claimDue leases up to max due watches to this worker. Watches claimed by
+// other workers are skipped, so every due watch runs exactly once.
+func (e *Executor) claimDue(ctx context.Context, max int) ([]dbgen.ClaimDueWatchesRow, error) {
+	return e.queries.ClaimDueWatches(ctx, dbgen.ClaimDueWatchesParams{
+		MaxWatches:   int32(max),
+		Owner:        e.workerID,
+		LeaseSeconds: int32(watchLease.Seconds()),
+	})
+}
+
+// holdLease renews this worker's lease on a watch every leaseHeartbeat until
+// ctx is done. The run is cancelled if the lease was lost, since another
+// worker may already be running the watch, or if its cancellation was
+// requested from another worker.
+func (e *Executor) holdLease(ctx context.Context, cancel context.CancelCauseFunc, watchID, runID pgtype.UUID, logger *slog.Logger) {
+	ticker := time.NewTicker(leaseHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := e.queries.ExtendWatchLease(ctx, dbgen.ExtendWatchLeaseParams{
+				LeaseSeconds: int32(watchLease.Seconds()),
+				ID:           watchID,
+				Owner:        e.workerID,
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("failed to extend watch lease", "error", err)
+				}
+				continue
+			}
+			if n == 0 {
+				logger.Error("watch lease lost, cancelling run")
+				cancel(errLeaseLost)
+				return
+			}
+
+			requested, err := e.queries.IsWatchRunCancelRequested(ctx, runID)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("failed to check run cancellation", "error", err)
+				}
+				continue
+			}
+			if requested {
+				logger.Info("run cancelled")
+				cancel(ErrRunCancelled)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/blueprinter/worker/internal/blueprint"
+	"github.com/blueprinter/worker/internal/db/dbgen"
+	"github.com/blueprinter/worker/internal/differ"
+)
+
+// ErrWatchNotFound is returned when a watch doesn't exist or belongs to another org.
+var ErrWatchNotFound = errors.New("watch not found")
+
+// MigrationReport describes how stored entities of a watch would be reconciled
+// with the current schema and blueprint versions.
+type MigrationReport struct {
+	WatchID          string                    `json:"watch_id"`
+	SchemaType       string                    `json:"schema_type"`
+	SchemaVersion    int                       `json:"schema_version"`
+	BlueprintVersion int32                     `json:"blueprint_version"`
+	Fields           []string                  `json:"fields"`
+	EntitiesTotal    int                       `json:"entities_total"`
+	EntitiesOutdated int                       `json:"entities_outdated"`
+	Entities         []differ.ContentMigration `json:"entities"`
+}
+
+// buildMigration derives the target field set from the blueprint's extraction
+// rules, plus the position fields when the watch tracks positions. Renames may
+// be declared on either the schema or the blueprint.
+func buildMigration(schema *blueprint.EntitySchema, rules *blueprint.ExtractionRules, trackPositions bool) differ.Migration {
+	m := differ.Migration{
+		Fields:  make([]string, 0, len(rules.Fields)+len(blueprint.PositionFields)),
+		Renames: make(map[string]string),
+	}
+	for name, mapping := range rules.Fields {
+		m.Fields = append(m.Fields, name)
+		if mapping != nil && mapping.RenamedFrom != "" {
+			m.Renames[name] = mapping.RenamedFrom
+		}
+	}
+	if trackPositions {
+		m.Fields = append(m.Fields, blueprint.PositionFields...)
+	}
+	sort.Strings(m.Fields)
+
+	for _, f := range schema.Fields {
+		if f.RenamedFrom == "" {
+			continue
+		}
+		if _, ok := m.Renames[f.Name]; !ok {
+			m.Renames[f.Name] = f.RenamedFrom
+		}
+	}
+	return m
+}
+
+func entityOutdated(entity *dbgen.Entity, schema *blueprint.EntitySchema, blueprintVersion int32) bool {
+	return entity.SchemaVersion != int32(schema.Version) || entity.BlueprintVersion != blueprintVersion
+}
+
+// migrateStoredEntities reconciles stored entities written under an older
+// schema or blueprint version with the current field set. The stored map is
+// updated in place so the differ only sees real changes. No events are emitted.
+func (e *Executor) migrateStoredEntities(
+	ctx context.Context,
+	q *dbgen.Queries,
+	watch *dbgen.ClaimDueWatchesRow,
+	schema *blueprint.EntitySchema,
+	rules *blueprint.ExtractionRules,
+	storedEntities []dbgen.Entity,
+	stored, extracted map[string]map[string]any,
+	logger *slog.Logger,
+) (int, error) {
+	migration := buildMigration(schema, rules, watch.TrackPositions)
+	migrated := 0
+
+	for i := range storedEntities {
+		entity := &storedEntities[i]
+		if !entityOutdated(entity, schema, watch.BlueprintVersion) {
+			continue
+		}
+		content, ok := stored[entity.ExternalID]
+		if !ok {
+			continue
+		}
+
+		newContent, report := migration.Apply(entity.ExternalID, content, extracted[entity.ExternalID])
+		contentBytes, err := json.Marshal(newContent)
+		if err != nil {
+			return migrated, fmt.Errorf("marshalling migrated content for %s: %w", entity.ExternalID, err)
+		}
+
+		if err := q.MigrateEntityContent(ctx, dbgen.MigrateEntityContentParams{
+			ID:               entity.ID,
+			Content:          contentBytes,
+			SchemaVersion:    int32(schema.Version),
+			BlueprintVersion: watch.BlueprintVersion,
+		}); err != nil {
+			return migrated, fmt.Errorf("migrating entity %s: %w", entity.ExternalID, err)
+		}
+
+		stored[entity.ExternalID] = newContent
+		migrated++
+		if !report.Empty() {
+			logger.Debug("entity content migrated",
+				"external_id", entity.ExternalID,
+				"renamed", report.Renamed,
+				"dropped", report.Dropped,
+				"adopted", report.Adopted,
+			)
+		}
+	}
+
+	return migrated, nil
+}
+
+// PlanMigration reports, without writing anything, how the stored entities of
+// a watch would be reconciled on its next run.
+func (e *Executor) PlanMigration(ctx context.Context, orgID, watchID string) (*MigrationReport, error) {
+	var id pgtype.UUID
+	if err := id.Scan(watchID); err != nil {
+		return nil, fmt.Errorf("invalid watch ID: %w", err)
+	}
+
+	watch, err := e.queries.GetWatchByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && watch.OrgID != orgID) {
+		return nil, ErrWatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting watch: %w", err)
+	}
+
+	var rules blueprint.ExtractionRules
+	if err := json.Unmarshal(watch.ExtractionRules, &rules); err != nil {
+		return nil, fmt.Errorf("parsing extraction rules: %w", err)
+	}
+
+	schema, err := e.schemas.GetSchema(ctx, watch.OrgID, watch.SchemaType)
+	if err != nil {
+		return nil, fmt.Errorf("resolving schema: %w", err)
+	}
+
+	storedEntities, err := e.queries.GetEntitiesByWatch(ctx, watch.ID)
+	if err != nil {
+		return nil, fmt.Errorf("loading stored entities: %w", err)
+	}
+
+	migration := buildMigration(schema, &rules, watch.TrackPositions)
+	report := &MigrationReport{
+		WatchID:          watchID,
+		SchemaType:       schema.Type,
+		SchemaVersion:    schema.Version,
+		BlueprintVersion: watch.BlueprintVersion,
+		Fields:           migration.Fields,
+		EntitiesTotal:    len(storedEntities),
+		Entities:         []differ.ContentMigration{},
+	}
+
+	for i := range storedEntities {
+		entity := &storedEntities[i]
+		if !entityOutdated(entity, schema, watch.BlueprintVersion) {
+			continue
+		}
+		report.EntitiesOutdated++
+
+		var content map[string]any
+		if err := json.Unmarshal(entity.Content, &content); err != nil {
+			return nil, fmt.Errorf("parsing content of entity %s: %w", entity.ExternalID, err)
+		}
+		if _, m := migration.Apply(entity.ExternalID, content, nil); !m.Empty() {
+			report.Entities = append(report.Entities, m)
+		}
+	}
+
+	return report, nil
+}
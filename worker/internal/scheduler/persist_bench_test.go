@@ -0,0 +1,218 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/blueprinter/worker/internal/blueprint"
+	"github.com/blueprinter/worker/internal/db/dbgen"
+	"github.com/blueprinter/worker/internal/differ"
+	"github.com/blueprinter/worker/internal/emitter"
+	"github.com/blueprinter/worker/internal/matcher"
+)
+
+// BenchmarkPersistRun measures the post-extraction phase of a run on a
+// 10k-entity watch. It needs a Postgres database with the schema applied:
+//
+//	BENCH_DATABASE_URL=postgres://... go test ./internal/scheduler -run '^$' -bench PersistRun
+func BenchmarkPersistRun(b *testing.B) {
+	const entityCount = 10_000
+
+	dsn := os.Getenv("BENCH_DATABASE_URL")
+	if dsn == "" {
+		b.Skip("BENCH_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		b.Fatalf("connecting: %v", err)
+	}
+	b.Cleanup(pool.Close)
+
+	orgID := fmt.Sprintf("bench_%d", time.Now().UnixNano())
+	b.Cleanup(func() { cleanupBenchOrg(ctx, pool, orgID) })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	queries := dbgen.New(pool)
+	em := emitter.New(queries, logger)
+	em.SetMatcher(matcher.New(logger))
+	e := NewExecutor(pool, queries, nil, em, nil, nil, "bench", RunTimeouts{}, logger)
+
+	blueprintID := benchExec(b, ctx, pool, `
+		INSERT INTO blueprints (org_id, name, url, schema_type, extraction_rules, status)
+		VALUES ($1, 'bench', 'https://example.com', 'ecommerce_product', '{}', 'active')
+		RETURNING id`, orgID)
+	schema := &blueprint.EntitySchema{Type: "ecommerce_product", Version: 1}
+
+	newWatch := func() *dbgen.ClaimDueWatchesRow {
+		watchID := benchExec(b, ctx, pool, `
+			INSERT INTO watches (org_id, blueprint_id, name, url, schedule)
+			VALUES ($1, $2, 'bench', 'https://example.com', '0 * * * *')
+			RETURNING id`, orgID, blueprintID)
+		benchExec(b, ctx, pool, `
+			INSERT INTO subscriptions (org_id, name, event_types, watch_id, channel_config)
+			VALUES ($1, 'bench', ARRAY['entity_appeared','entity_changed','entity_disappeared'], $2, '{}')
+			RETURNING id`, orgID, watchID)
+		return &dbgen.ClaimDueWatchesRow{
+			ID:                    watchID,
+			OrgID:                 orgID,
+			SchemaType:            schema.Type,
+			BlueprintVersion:      1,
+			MinEntityCount:        1,
+			MaxDisappearanceRatio: 0.5,
+		}
+	}
+
+	run := func(watch *dbgen.ClaimDueWatchesRow, extracted map[string]map[string]any) {
+		r, err := queries.CreateWatchRun(ctx, dbgen.CreateWatchRunParams{OrgID: watch.OrgID, WatchID: watch.ID})
+		if err != nil {
+			b.Fatalf("creating run: %v", err)
+		}
+		sources := make(map[string]fetchTarget, len(extracted))
+		for eid := range extracted {
+			sources[eid] = fetchTarget{URL: "https://example.com"}
+		}
+		var stats runStats
+		if err := e.persistRun(ctx, watch, schema, &blueprint.ExtractionRules{}, differ.Policy{}, r.ID, extracted, sources, &stats, logger); err != nil {
+			b.Fatalf("persisting run: %v", err)
+		}
+	}
+
+	b.Run("first_run", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			watch := newWatch()
+			extracted := benchEntities(entityCount, 0)
+			b.StartTimer()
+
+			run(watch, extracted)
+		}
+		b.ReportMetric(float64(entityCount*b.N)/b.Elapsed().Seconds(), "entities/s")
+	})
+
+	b.Run("ten_percent_changed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			watch := newWatch()
+			run(watch, benchEntities(entityCount, 0))
+			extracted := benchEntities(entityCount, entityCount/10)
+			b.StartTimer()
+
+			run(watch, extracted)
+		}
+		b.ReportMetric(float64(entityCount*b.N)/b.Elapsed().Seconds(), "entities/s")
+	})
+}
+
+// BenchmarkPersistWrites measures building the batched entity and version
+// writes of a run on a 10k-entity watch, without a database: statements go to
+// a stub that only counts them. It reports the statements a run sends, which
+// batching keeps at two per upsertBatchSize entities instead of two per
+// entity.
+func BenchmarkPersistWrites(b *testing.B) {
+	const entityCount = 10_000
+
+	ctx := context.Background()
+	watch := &dbgen.ClaimDueWatchesRow{OrgID: "bench", SchemaType: "ecommerce_product", BlueprintVersion: 1}
+	schema := &blueprint.EntitySchema{Type: "ecommerce_product", Version: 1}
+
+	extracted := benchEntities(entityCount, 0)
+	diff := &differ.DiffResult{Appeared: make([]differ.EntityDiff, 0, entityCount)}
+	sources := make(map[string]fetchTarget, entityCount)
+	entityIDs := make(map[string]pgtype.UUID, entityCount)
+	for eid, content := range extracted {
+		diff.Appeared = append(diff.Appeared, differ.EntityDiff{ExternalID: eid, Content: content})
+		sources[eid] = fetchTarget{URL: "https://example.com"}
+		entityIDs[eid] = pgtype.UUID{Valid: true}
+	}
+
+	var stub countingDB
+	q := dbgen.New(&stub)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := upsertEntities(ctx, q, watch, schema, diff.Appeared, sources, entityIDs); err != nil {
+			b.Fatalf("upserting: %v", err)
+		}
+		if err := recordVersions(ctx, q, watch, pgtype.UUID{Valid: true}, diff, nil, entityIDs); err != nil {
+			b.Fatalf("recording versions: %v", err)
+		}
+	}
+	b.ReportMetric(float64(stub.statements)/float64(b.N), "statements/op")
+	b.ReportMetric(float64(entityCount*b.N)/b.Elapsed().Seconds(), "entities/s")
+}
+
+// countingDB is a dbgen.DBTX that counts statements and returns no rows.
+type countingDB struct {
+	statements int
+}
+
+func (c *countingDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	c.statements++
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *countingDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	c.statements++
+	return emptyRows{}, nil
+}
+
+func (c *countingDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	c.statements++
+	return emptyRows{}
+}
+
+// emptyRows is a result set with no rows.
+type emptyRows struct{}
+
+func (emptyRows) Close()                                       {}
+func (emptyRows) Err() error                                   { return nil }
+func (emptyRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (emptyRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (emptyRows) Next() bool                                   { return false }
+func (emptyRows) Scan(...any) error                            { return pgx.ErrNoRows }
+func (emptyRows) Values() ([]any, error)                       { return nil, nil }
+func (emptyRows) RawValues() [][]byte                          { return nil }
+func (emptyRows) Conn() *pgx.Conn                              { return nil }
+
+// benchEntities builds count entities keyed by external ID; the first
+// changed of them get a different price.
+func benchEntities(count, changed int) map[string]map[string]any {
+	entities := make(map[string]map[string]any, count)
+	for i := 0; i < count; i++ {
+		price := 1000 + i
+		if i < changed {
+			price++
+		}
+		entities[fmt.Sprintf("eid-%05d", i)] = map[string]any{
+			"name":  fmt.Sprintf("Product %d", i),
+			"price": price,
+		}
+	}
+	return entities
+}
+
+func benchExec(b *testing.B, ctx context.Context, pool *pgxpool.Pool, sql string, args ...any) pgtype.UUID {
+	b.Helper()
+	var id pgtype.UUID
+	if err := pool.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		b.Fatalf("setup: %v", err)
+	}
+	return id
+}
+
+func cleanupBenchOrg(ctx context.Context, pool *pgxpool.Pool, orgID string) {
+	for _, table := range []string{"deliveries", "events", "entities", "watch_runs", "subscriptions", "watches", "blueprint_revisions", "blueprints"} {
+		_, _ = pool.Exec(ctx, "DELETE FROM "+table+" WHERE org_id = $1", orgID)
+	}
+}
@@ -0,0 +1,31 @@
+package scheduler
+
+import "github.com/blueprinter/worker/internal/blueprint"
+
+// alignPositionFields makes stored content agree with the extraction about
+// whether position fields are tracked, so that turning tracking on or off
+// does not register as a change of every entity. With tracking on, stored
+// entities without positions take the extracted ones; with it off, stored
+// positions are ignored.
+func alignPositionFields(stored, extracted map[string]map[string]any, track bool) {
+	for eid, content := range stored {
+		for _, field := range blueprint.PositionFields {
+			if !track {
+				delete(content, field)
+				continue
+			}
+			if _, ok := content[field]; ok {
+				continue
+			}
+			if v, ok := extracted[eid][field]; ok {
+				content[field] = v
+			}
+		}
+	}
+}
+
+// diffFieldOrder is the order a run lists field changes in: the schema's
+// fields, then the position fields.
+func diffFieldOrder(schema *blueprint.EntitySchema) []string {
+	return append(schema.FieldNames(), blueprint.PositionFields...)
+}
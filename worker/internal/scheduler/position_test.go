@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlignPositionFields_TrackingOn(t *testing.T) {
+	stored := map[string]map[string]any{
+		"a": {"name": "A"},
+		"b": {"name": "B", "_position": float64(1), "_page_position": float64(1)},
+	}
+	extracted := map[string]map[string]any{
+		"a": {"name": "A", "_position": 2, "_page_position": 2},
+		"b": {"name": "B", "_position": 1, "_page_position": 1},
+	}
+	alignPositionFields(stored, extracted, true)
+
+	// Entities stored before tracking took the extracted positions, so they
+	// don't change; positions stored earlier are kept and diffed as usual.
+	assert.Equal(t, map[string]any{"name": "A", "_position": 2, "_page_position": 2}, stored["a"])
+	assert.Equal(t, float64(1), stored["b"]["_position"])
+}
+
+func TestAlignPositionFields_TrackingOff(t *testing.T) {
+	stored := map[string]map[string]any{
+		"a": {"name": "A", "_position": float64(3), "_page_position": float64(3)},
+	}
+	alignPositionFields(stored, map[string]map[string]any{"a": {"name": "A"}}, false)
+	assert.Equal(t, map[string]any{"name": "A"}, stored["a"])
+}
@@ -0,0 +1,354 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/blueprinter/worker/internal/archive"
+	"github.com/blueprinter/worker/internal/blueprint"
+	"github.com/blueprinter/worker/internal/db"
+	"github.com/blueprinter/worker/internal/differ"
+	"github.com/blueprinter/worker/internal/emitter"
+	"github.com/blueprinter/worker/internal/identity"
+)
+
+const (
+	defaultReplayLimit = 20
+	maxReplayLimit     = 200
+)
+
+// ErrArchiveDisabled is returned when replay is requested but snapshot
+// archiving is not configured.
+var ErrArchiveDisabled = errors.New("snapshot archiving is not configured")
+
+// ReplayParams selects the snapshots to replay and, optionally, rules to
+// replay them with instead of the watch's current blueprint.
+type ReplayParams struct {
+	OrgID           string
+	WatchID         string
+	ExtractionRules *blueprint.ExtractionRules // nil: the watch's current rules
+	IdentityFields  []string                   // empty: the watch's, then the schema's
+	Since           time.Time                  // zero: no lower bound
+	Until           time.Time                  // zero: now
+	Limit           int                        // most recent runs to replay
+	EmitBaseline    bool                       // report events for the first replayed run
+}
+
+// ReplayStep is the outcome of replaying one archived run.
+type ReplayStep struct {
+	WatchRunID         string                 `json:"watch_run_id"`
+	SnapshotIDs        []string               `json:"snapshot_ids"`
+	CapturedAt         time.Time              `json:"captured_at"`
+	Baseline           bool                   `json:"baseline"`
+	EntitiesFound      int                    `json:"entities_found"`
+	Appeared           int                    `json:"appeared"`
+	Changed            int                    `json:"changed"`
+	Disappeared        int                    `json:"disappeared"`
+	Unchanged          int                    `json:"unchanged"`
+	Renamed            int                    `json:"renamed,omitempty"` // changed entities matched across an identity change
+	Events             []emitter.PreviewEvent `json:"events"`
+	Suspect            string                 `json:"suspect,omitempty"`             // safety guard that discarded the run
+	IdentityCollisions int                    `json:"identity_collisions,omitempty"` // entities dropped for a duplicate external ID
+	IdentityMissing    int                    `json:"identity_missing,omitempty"`    // entities dropped for having no identity
+	PageErrors         []PageError            `json:"page_errors,omitempty"`         // pages left out of the run
+	Error              string                 `json:"error,omitempty"`
+}
+
+// ReplayResult is the sequence of replayed runs of a watch.
+type ReplayResult struct {
+	WatchID     string       `json:"watch_id"`
+	Steps       []ReplayStep `json:"steps"`
+	TotalEvents int          `json:"total_events"`
+}
+
+// Replay runs the fetch-less pipeline (extract, identity, diff) over archived
+// snapshots of a watch, oldest first, against an in-memory entity set. Each
+// run is decided like a live one (grace window, failed pages, renames, safety
+// guards). It reports the events each run would have emitted and never writes
+// to entities, events or deliveries.
+func (e *Executor) Replay(ctx context.Context, p ReplayParams) (*ReplayResult, error) {
+	if e.archive == nil {
+		return nil, ErrArchiveDisabled
+	}
+
+	var id pgtype.UUID
+	if err := id.Scan(p.WatchID); err != nil {
+		return nil, fmt.Errorf("invalid watch ID: %w", err)
+	}
+
+	watch, err := e.queries.GetWatchByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && watch.OrgID != p.OrgID) {
+		return nil, ErrWatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting watch: %w", err)
+	}
+
+	rules := p.ExtractionRules
+	if rules == nil {
+		rules = &blueprint.ExtractionRules{}
+		if err := json.Unmarshal(watch.ExtractionRules, rules); err != nil {
+			return nil, fmt.Errorf("parsing extraction rules: %w", err)
+		}
+	}
+
+	schema, err := e.schemas.GetSchema(ctx, watch.OrgID, watch.SchemaType)
+	if err != nil {
+		return nil, fmt.Errorf("resolving schema: %w", err)
+	}
+
+	identityFields := p.IdentityFields
+	if len(identityFields) == 0 {
+		identityFields = watch.IdentityFields
+	}
+	if len(identityFields) == 0 {
+		identityFields = schema.IdentityFields
+	}
+	idSpec, err := watchIdentity(identityFields, watch.IdentityFallbacks, watch.IdentityNormalize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity configuration: %w", err)
+	}
+	policy, err := differ.ParsePolicy(watch.DiffPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid diff policy: %w", err)
+	}
+	policy.FieldOrder = diffFieldOrder(schema)
+
+	limit := p.Limit
+	if limit <= 0 {
+		limit = defaultReplayLimit
+	}
+	limit = min(limit, maxReplayLimit)
+	until := p.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	snapshots, err := e.archive.ListForWatch(ctx, p.OrgID, watch.ID, p.Since, until, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map each archived page back to its fetch target: positions count on
+	// across the pages of one listing, and a page missing from a run's
+	// snapshots failed in that run.
+	pages, _ := watchTargets(watch.Url, watch.Urls, watch.UrlParams)
+	targets := make(map[string]fetchTarget, len(pages))
+	for _, t := range pages {
+		targets[t.URL] = t
+	}
+	settings := runSettings{
+		trackPositions:        watch.TrackPositions,
+		renameThreshold:       watch.RenameThreshold,
+		minEntityCount:        watch.MinEntityCount,
+		maxDisappearanceRatio: watch.MaxDisappearanceRatio,
+		acceptNextRun:         watch.AcceptNextRun,
+	}
+
+	result := &ReplayResult{
+		WatchID: p.WatchID,
+		Steps:   []ReplayStep{},
+	}
+
+	// Snapshots are grouped by run; a run may have archived several pages.
+	// Alongside the active entity set, track what the grace window, failed
+	// pages and disappeared events need. Every run is extracted with the same
+	// rules, so unlike stored entities the set never needs migrating.
+	var state map[string]map[string]any
+	missedRuns := map[string]int32{}
+	firstSeen := map[string]time.Time{}
+	lastSeen := map[string]time.Time{}
+	sourceURLs := map[string]string{}
+	for start := 0; start < len(snapshots); {
+		end := start + 1
+		for end < len(snapshots) && snapshots[end].WatchRunID == snapshots[start].WatchRunID {
+			end++
+		}
+		group := snapshots[start:end]
+		start = end
+
+		step := ReplayStep{
+			WatchRunID: group[0].WatchRunID,
+			CapturedAt: group[0].CreatedAt,
+			Baseline:   state == nil,
+			Events:     []emitter.PreviewEvent{},
+		}
+
+		extracted, sources, err := e.replayExtract(ctx, p.OrgID, group, rules, idSpec, pages, targets, watch.TrackPositions, &step)
+		if err != nil {
+			// Like a failed run: the entity set is left as it was.
+			step.Error = err.Error()
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+
+		prev := state
+		if prev == nil {
+			prev = map[string]map[string]any{}
+		}
+		unchecked := uncheckedEntities(sourceURLs, step.PageErrors)
+		decision := decideRun(policy, settings, extracted, prev, unchecked, func(eid string) bool {
+			return withinGrace(watch.GraceMissedRuns, watch.GracePeriodSeconds, missedRuns[eid], lastSeen[eid], step.CapturedAt)
+		})
+		// Like accept_next_run on a live run, accepting applies to the first
+		// run compared with a previous entity set.
+		if len(prev) > 0 {
+			settings.acceptNextRun = false
+		}
+
+		// Like a suspect run: the guard keeps the entity set as it was.
+		if decision.trip != nil {
+			step.Suspect = decision.trip.Reason
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+		diffResult, missed := decision.diff, decision.missed
+		step.Renamed = decision.renamed
+		step.Appeared = len(diffResult.Appeared)
+		step.Changed = len(diffResult.Changed)
+		step.Disappeared = len(diffResult.Disappeared)
+		step.Unchanged = diffResult.Unchanged
+
+		if !step.Baseline || p.EmitBaseline {
+			src := emitter.DiffSource{
+				WatchID:   db.UUIDToString(watch.ID),
+				Name:      watch.Name,
+				URL:       watch.Url,
+				LastKnown: make(map[string]emitter.LastKnown, len(diffResult.Disappeared)),
+			}
+			for _, d := range diffResult.Disappeared {
+				src.LastKnown[d.ExternalID] = emitter.LastKnown{
+					Content:     prev[d.ExternalID],
+					SourceURL:   sourceURLs[d.ExternalID],
+					SourceParam: targets[sourceURLs[d.ExternalID]].Param,
+					FirstSeenAt: firstSeen[d.ExternalID],
+					LastSeenAt:  lastSeen[d.ExternalID],
+				}
+			}
+			step.Events = e.emitter.Preview(&diffResult, src)
+			result.TotalEvents += len(step.Events)
+		}
+
+		// Disappeared entities go stale and drop out of the active set;
+		// missed ones stay in it until their grace window runs out, and
+		// unchecked ones are left as they were. Like a stored entity, a
+		// renamed one keeps when it was first seen.
+		for _, d := range diffResult.Changed {
+			if d.PreviousExternalID != "" {
+				if _, ok := firstSeen[d.ExternalID]; !ok {
+					firstSeen[d.ExternalID] = firstSeen[d.PreviousExternalID]
+				}
+			}
+		}
+		next := make(map[string]map[string]any, len(extracted)+len(missed)+len(unchecked))
+		for eid, content := range extracted {
+			next[eid] = content
+			if _, ok := firstSeen[eid]; !ok {
+				firstSeen[eid] = step.CapturedAt
+			}
+			lastSeen[eid] = step.CapturedAt
+			sourceURLs[eid] = sources[eid]
+			delete(missedRuns, eid)
+		}
+		for _, eid := range missed {
+			next[eid] = prev[eid]
+			missedRuns[eid]++
+		}
+		for eid := range unchecked {
+			if _, ok := next[eid]; !ok {
+				next[eid] = prev[eid]
+			}
+		}
+		for _, d := range diffResult.Disappeared {
+			delete(missedRuns, d.ExternalID)
+			delete(lastSeen, d.ExternalID)
+			delete(sourceURLs, d.ExternalID)
+		}
+		for _, d := range diffResult.Changed {
+			if d.PreviousExternalID != "" {
+				delete(missedRuns, d.PreviousExternalID)
+				delete(lastSeen, d.PreviousExternalID)
+				delete(sourceURLs, d.PreviousExternalID)
+			}
+		}
+		state = next
+		result.Steps = append(result.Steps, step)
+	}
+
+	return result, nil
+}
+
+// replayExtract extracts entities from every snapshot of one run and keys
+// them by external ID, returning them with the URL of the page each was found
+// on. Like a live run, a page that fails to extract, or one of the watch's
+// pages the run archived no snapshot of, is recorded in the step's page
+// errors and left out; the run only fails when no page could be extracted.
+// targets maps page URLs to their fetch target.
+func (e *Executor) replayExtract(
+	ctx context.Context,
+	orgID string,
+	group []archive.Snapshot,
+	rules *blueprint.ExtractionRules,
+	idSpec identity.Spec,
+	pages []fetchTarget,
+	targets map[string]fetchTarget,
+	trackPositions bool,
+	step *ReplayStep,
+) (map[string]map[string]any, map[string]string, error) {
+	extracted := make(map[string]map[string]any)
+	sources := make(map[string]string)
+	listed := make(map[fetchTarget]int)
+	archived := make(map[string]bool, len(group))
+	var firstPageErr error
+	for i := range group {
+		step.SnapshotIDs = append(step.SnapshotIDs, group[i].ID)
+		archived[group[i].URL] = true
+		target, ok := targets[group[i].URL]
+		if !ok {
+			target = fetchTarget{URL: group[i].URL}
+		}
+
+		cleanedHTML, err := e.archive.CleanedHTML(ctx, orgID, group[i].ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading snapshot %s: %w", group[i].ID, err)
+		}
+
+		entities, err := blueprint.Extract(cleanedHTML, rules)
+		if err != nil {
+			err = fmt.Errorf("extracting snapshot %s: %w", group[i].ID, err)
+			if firstPageErr == nil {
+				firstPageErr = err
+			}
+			step.PageErrors = append(step.PageErrors, PageError{URL: target.URL, Param: target.Param, Phase: "extract", Error: err.Error()})
+			continue
+		}
+		step.EntitiesFound += len(entities)
+		if trackPositions {
+			blueprint.RecordPositions(entities, listed[target.listing()])
+			listed[target.listing()] += len(entities)
+		}
+
+		var ids identityStats
+		for _, eid := range keyEntities(entities, idSpec, extracted, &ids) {
+			sources[eid] = target.URL
+		}
+		step.IdentityCollisions += ids.collisions
+		step.IdentityMissing += ids.missing
+	}
+	if firstPageErr != nil && len(step.PageErrors) == len(group) {
+		return nil, nil, firstPageErr
+	}
+
+	for _, t := range pages {
+		if !archived[t.URL] {
+			step.PageErrors = append(step.PageErrors, PageError{URL: t.URL, Param: t.Param, Phase: "fetch", Error: "no snapshot archived"})
+		}
+	}
+	return extracted, sources, nil
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/blueprinter/worker/internal/db"
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+// runPhases are the phases of a run, in order.
+var runPhases = []string{"fetch", "extract", "persist"}
+
+// RunStatus is the progress and outcome of a watch run.
+type RunStatus struct {
+	ID              string      `json:"id"`
+	WatchID         string      `json:"watch_id"`
+	Status          string      `json:"status"`
+	Phase           string      `json:"phase,omitempty"` // last phase reached
+	Progress        RunProgress `json:"progress"`
+	StartedAt       time.Time   `json:"started_at"`
+	CompletedAt     *time.Time  `json:"completed_at,omitempty"`
+	CancelRequested bool        `json:"cancel_requested"`
+	Error           string      `json:"error,omitempty"`
+	PageErrors      []PageError `json:"page_errors,omitempty"` // pages the run went on without
+	Stats           *RunCounts  `json:"stats,omitempty"`       // set once the run finished
+}
+
+// RunProgress counts the phases a run has reached.
+type RunProgress struct {
+	Step  int `json:"step"`
+	Steps int `json:"steps"`
+}
+
+// RunCounts are the entity and event counts of a completed run.
+type RunCounts struct {
+	EntitiesFound   int32 `json:"entities_found"`
+	EntitiesNew     int32 `json:"entities_new"`
+	EntitiesChanged int32 `json:"entities_changed"`
+	EntitiesRemoved int32 `json:"entities_removed"`
+	EventsEmitted   int32 `json:"events_emitted"`
+
+	// Entities dropped because their external ID collided with another's,
+	// or because they had no identity values at all.
+	IdentityCollisions int32 `json:"identity_collisions"`
+	IdentityMissing    int32 `json:"identity_missing"`
+}
+
+// setPhase records the phase a run has reached. Failing to do so doesn't
+// stop the run.
+func (e *Executor) setPhase(ctx context.Context, runID pgtype.UUID, phase string, logger *slog.Logger) {
+	if err := e.queries.SetWatchRunPhase(ctx, dbgen.SetWatchRunPhaseParams{Phase: phase, ID: runID}); err != nil {
+		logger.Warn("failed to record run phase", "phase", phase, "error", err)
+	}
+}
+
+// GetRun reports the progress of a run, or its outcome once it finished.
+func (e *Executor) GetRun(ctx context.Context, orgID, runID string) (*RunStatus, error) {
+	var id pgtype.UUID
+	if err := id.Scan(runID); err != nil {
+		return nil, ErrRunNotFound
+	}
+
+	run, err := e.queries.GetWatchRun(ctx, dbgen.GetWatchRunParams{ID: id, OrgID: orgID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting run: %w", err)
+	}
+
+	return buildRunStatus(&run), nil
+}
+
+func buildRunStatus(run *dbgen.WatchRun) *RunStatus {
+	status := &RunStatus{
+		ID:              db.UUIDToString(run.ID),
+		WatchID:         db.UUIDToString(run.WatchID),
+		Status:          run.Status,
+		Phase:           run.Phase.String,
+		Progress:        RunProgress{Step: slices.Index(runPhases, run.Phase.String) + 1, Steps: len(runPhases)},
+		StartedAt:       run.StartedAt.Time,
+		CancelRequested: run.CancelRequestedAt.Valid,
+		Error:           run.ErrorMessage.String,
+	}
+	if run.CompletedAt.Valid {
+		status.CompletedAt = &run.CompletedAt.Time
+	}
+	if len(run.PageErrors) > 0 {
+		// Not fatal: the rest of the status is still worth reporting.
+		_ = json.Unmarshal(run.PageErrors, &status.PageErrors)
+	}
+	if run.Status == "completed" {
+		status.Progress.Step = status.Progress.Steps
+	}
+	if run.EntitiesFound.Valid {
+		status.Stats = &RunCounts{
+			EntitiesFound:   run.EntitiesFound.Int32,
+			EntitiesNew:     run.EntitiesNew.Int32,
+			EntitiesChanged: run.EntitiesChanged.Int32,
+			EntitiesRemoved: run.EntitiesRemoved.Int32,
+			EventsEmitted:   run.EventsEmitted.Int32,
+
+			IdentityCollisions: run.IdentityCollisions.Int32,
+			IdentityMissing:    run.IdentityMissing.Int32,
+		}
+	}
+	return status
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+func TestBuildRunStatus_Running(t *testing.T) {
+	started := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	status := buildRunStatus(&dbgen.WatchRun{
+		Status:    "running",
+		Phase:     pgtype.Text{String: "extract", Valid: true},
+		StartedAt: pgtype.Timestamptz{Time: started, Valid: true},
+	})
+
+	assert.Equal(t, "extract", status.Phase)
+	assert.Equal(t, RunProgress{Step: 2, Steps: 3}, status.Progress)
+	assert.Equal(t, started, status.StartedAt)
+	assert.Nil(t, status.CompletedAt)
+	assert.Nil(t, status.Stats)
+	assert.False(t, status.CancelRequested)
+}
+
+func TestBuildRunStatus_NotStarted(t *testing.T) {
+	status := buildRunStatus(&dbgen.WatchRun{Status: "running"})
+	assert.Equal(t, RunProgress{Step: 0, Steps: 3}, status.Progress)
+}
+
+func TestBuildRunStatus_Completed(t *testing.T) {
+	completed := time.Date(2025, 6, 1, 12, 1, 0, 0, time.UTC)
+	status := buildRunStatus(&dbgen.WatchRun{
+		Status:          "completed",
+		Phase:           pgtype.Text{String: "persist", Valid: true},
+		CompletedAt:     pgtype.Timestamptz{Time: completed, Valid: true},
+		EntitiesFound:   pgtype.Int4{Int32: 12, Valid: true},
+		EntitiesNew:     pgtype.Int4{Int32: 2, Valid: true},
+		EntitiesChanged: pgtype.Int4{Int32: 1, Valid: true},
+		EntitiesRemoved: pgtype.Int4{Int32: 0, Valid: true},
+		EventsEmitted:   pgtype.Int4{Int32: 3, Valid: true},
+	})
+
+	assert.Equal(t, RunProgress{Step: 3, Steps: 3}, status.Progress)
+	require.NotNil(t, status.CompletedAt)
+	assert.Equal(t, completed, *status.CompletedAt)
+	require.NotNil(t, status.Stats)
+	assert.Equal(t, RunCounts{EntitiesFound: 12, EntitiesNew: 2, EntitiesChanged: 1, EventsEmitted: 3}, *status.Stats)
+}
+
+func TestBuildRunStatus_TimedOut(t *testing.T) {
+	status := buildRunStatus(&dbgen.WatchRun{
+		Status:       "timed_out",
+		Phase:        pgtype.Text{String: "fetch", Valid: true},
+		ErrorMessage: pgtype.Text{String: "fetch timed out after 2m0s", Valid: true},
+	})
+
+	assert.Equal(t, RunProgress{Step: 1, Steps: 3}, status.Progress)
+	assert.Equal(t, "fetch timed out after 2m0s", status.Error)
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleParser accepts standard five-field cron expressions as well as
+// descriptors such as @hourly, @daily and @every 90m.
+var scheduleParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Schedule is a watch's parsed schedule: when it is due, in which timezone,
+// and how much random delay spreads its runs out.
+type Schedule struct {
+	spec   cron.Schedule
+	loc    *time.Location
+	jitter time.Duration
+}
+
+// ParseSchedule parses a cron expression or descriptor evaluated in the given
+// IANA timezone (UTC when empty). jitterSeconds adds up to that much random
+// delay to each run; it must be shorter than the schedule's interval.
+func ParseSchedule(expr, timezone string, jitterSeconds int32) (*Schedule, error) {
+	spec, err := scheduleParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+
+	if spec.Next(time.Now().In(loc)).IsZero() {
+		return nil, fmt.Errorf("schedule %q never fires", expr)
+	}
+
+	if jitterSeconds < 0 {
+		return nil, fmt.Errorf("jitter must not be negative")
+	}
+
+	s := &Schedule{spec: spec, loc: loc}
+	jitter := time.Duration(jitterSeconds) * time.Second
+	if gap := s.shortestInterval(time.Now()); jitter >= gap {
+		return nil, fmt.Errorf("jitter of %s must be shorter than the schedule's %s interval", jitter, gap)
+	}
+	s.jitter = jitter
+	return s, nil
+}
+
+// jitterCheckRuns is how many upcoming runs ParseSchedule looks at to find
+// the shortest interval a jitter must stay below.
+const jitterCheckRuns = 100
+
+// shortestInterval returns the shortest time between consecutive scheduled
+// times over the next jitterCheckRuns runs after from.
+func (s *Schedule) shortestInterval(from time.Time) time.Duration {
+	times := s.Upcoming(from, jitterCheckRuns)
+	gap := time.Duration(math.MaxInt64)
+	for i := 1; i < len(times); i++ {
+		gap = min(gap, times[i].Sub(times[i-1]))
+	}
+	return gap
+}
+
+// Next returns the first scheduled time after from, without jitter.
+func (s *Schedule) Next(from time.Time) time.Time {
+	return s.spec.Next(from.In(s.loc))
+}
+
+// NextRun returns when the watch should next run: the next scheduled time
+// plus a random delay of up to the schedule's jitter.
+func (s *Schedule) NextRun(from time.Time) time.Time {
+	return s.withJitter(s.Next(from))
+}
+
+// After returns when a watch that runs every interval should next run: from
+// plus interval plus the schedule's jitter.
+func (s *Schedule) After(from time.Time, interval time.Duration) time.Time {
+	return s.withJitter(from.Add(interval))
+}
+
+// Interval returns the time between the next two scheduled times after from.
+func (s *Schedule) Interval(from time.Time) time.Duration {
+	next := s.Next(from)
+	return s.Next(next).Sub(next)
+}
+
+func (s *Schedule) withJitter(t time.Time) time.Time {
+	if s.jitter > 0 {
+		t = t.Add(rand.N(s.jitter))
+	}
+	return t
+}
+
+// Upcoming returns the next n scheduled times after from, without jitter.
+func (s *Schedule) Upcoming(from time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for range n {
+		from = s.Next(from)
+		if from.IsZero() {
+			break // the expression never matches again
+		}
+		times = append(times, from)
+	}
+	return times
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseSchedule(t *testing.T, expr, timezone string) *Schedule {
+	t.Helper()
+	s, err := ParseSchedule(expr, timezone, 0)
+	require.NoError(t, err)
+	return s
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	_, err := ParseSchedule("every hour", "", 0)
+	assert.ErrorContains(t, err, `invalid schedule "every hour"`)
+
+	_, err = ParseSchedule("0 * * * *", "Mars/Olympus_Mons", 0)
+	assert.ErrorContains(t, err, `invalid timezone "Mars/Olympus_Mons"`)
+
+	_, err = ParseSchedule("0 0 30 2 *", "", 0)
+	assert.ErrorContains(t, err, "never fires")
+
+	_, err = ParseSchedule("0 * * * *", "", -1)
+	assert.Error(t, err)
+
+	_, err = ParseSchedule("*/15 * * * *", "", 15*60)
+	assert.ErrorContains(t, err, "must be shorter than the schedule's 15m0s interval")
+
+	// Weekdays at 09:00: the shortest gap is a day, not the weekend's three.
+	_, err = ParseSchedule("0 9 * * 1-5", "", 2*24*60*60)
+	assert.ErrorContains(t, err, "24h0m0s interval")
+
+	_, err = ParseSchedule("*/15 * * * *", "", 14*60)
+	assert.NoError(t, err)
+}
+
+func TestSchedule_Timezone(t *testing.T) {
+	from := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// 09:00 in Amsterdam is 07:00 UTC in summer.
+	next := mustParseSchedule(t, "0 9 * * *", "Europe/Amsterdam").Next(from)
+	assert.True(t, time.Date(2025, 6, 2, 7, 0, 0, 0, time.UTC).Equal(next), next)
+
+	next = mustParseSchedule(t, "0 9 * * *", "").Next(from)
+	assert.True(t, time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC).Equal(next), next)
+}
+
+func TestSchedule_Descriptors(t *testing.T) {
+	from := time.Date(2025, 6, 1, 12, 10, 0, 0, time.UTC)
+
+	assert.Equal(t, from.Add(90*time.Minute), mustParseSchedule(t, "@every 90m", "").Next(from))
+	assert.True(t, time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC).Equal(mustParseSchedule(t, "@hourly", "").Next(from)))
+}
+
+func TestSchedule_Jitter(t *testing.T) {
+	s, err := ParseSchedule("0 * * * *", "", 300)
+	require.NoError(t, err)
+
+	from := time.Date(2025, 6, 1, 12, 10, 0, 0, time.UTC)
+	base := s.Next(from)
+	for range 50 {
+		next := s.NextRun(from)
+		assert.False(t, next.Before(base))
+		assert.True(t, next.Before(base.Add(5*time.Minute)))
+	}
+}
+
+func TestSchedule_Upcoming(t *testing.T) {
+	from := time.Date(2025, 6, 1, 12, 10, 0, 0, time.UTC)
+	times := mustParseSchedule(t, "*/30 * * * *", "").Upcoming(from, 3)
+
+	require.Len(t, times, 3)
+	assert.True(t, time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC).Equal(times[0]))
+	assert.True(t, time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC).Equal(times[1]))
+	assert.True(t, time.Date(2025, 6, 1, 13, 30, 0, 0, time.UTC).Equal(times[2]))
+}
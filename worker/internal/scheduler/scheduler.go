@@ -4,82 +4,259 @@ import (
 	"context"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/blueprinter/worker/internal/db"
 	"github.com/blueprinter/worker/internal/db/dbgen"
 )
 
-const (
-	pollInterval  = 30 * time.Second
-	maxConcurrent = 5
-)
+const pollInterval = 30 * time.Second
+
+// DefaultConcurrency is the number of watches run at once when not configured.
+const DefaultConcurrency = 5
 
-// Scheduler polls the database for due watches and executes them concurrently.
+// Scheduler claims due watches and runs them on a fixed pool of workers. It
+// only claims as many watches as there are idle workers, and claims again as
+// soon as one frees up, so a slow run never holds up the others.
 type Scheduler struct {
-	executor *Executor
-	queries  *dbgen.Queries
-	logger   *slog.Logger
+	executor    *Executor
+	queries     *dbgen.Queries
+	concurrency int
+	logger      *slog.Logger
+
+	// claimDue and countDue reach the database for dispatch; tests replace them.
+	claimDue func(ctx context.Context, max int) ([]dbgen.ClaimDueWatchesRow, error)
+	countDue func(ctx context.Context) (int64, error)
+
+	running    atomic.Int64
+	queueDepth atomic.Int64
+	freed      chan struct{}
+
+	// Runs, scheduled or manual, execute under runCtx, which outlives Run's
+	// context so that a shutdown can let them finish.
+	runCtx    context.Context
+	interrupt context.CancelCauseFunc
+
+	mu      sync.Mutex
+	closed  bool           // no more manual runs are accepted
+	manuals sync.WaitGroup // manual runs in progress
+
+	stopping      chan struct{} // closed by Shutdown to stop claiming
+	interrupting  chan struct{} // closed by Shutdown when the grace period ends
+	stopped       chan struct{} // closed when Run returns
+	stopOnce      sync.Once
+	interruptOnce sync.Once
 }
 
-// NewScheduler creates a new Scheduler.
-func NewScheduler(executor *Executor, queries *dbgen.Queries, logger *slog.Logger) *Scheduler {
+// Stats is a point-in-time view of the scheduler's load.
+type Stats struct {
+	Concurrency int   `json:"concurrency"`
+	Running     int64 `json:"running"`
+	QueueDepth  int64 `json:"queue_depth"` // due watches not yet claimed by any worker
+}
+
+// NewScheduler creates a new Scheduler running up to concurrency watches at
+// once. A non-positive concurrency uses DefaultConcurrency.
+func NewScheduler(executor *Executor, queries *dbgen.Queries, concurrency int, logger *slog.Logger) *Scheduler {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	runCtx, interrupt := context.WithCancelCause(context.Background())
 	return &Scheduler{
-		executor: executor,
-		queries:  queries,
-		logger:   logger,
+		executor:     executor,
+		queries:      queries,
+		concurrency:  concurrency,
+		logger:       logger,
+		claimDue:     executor.claimDue,
+		countDue:     queries.CountDueWatches,
+		freed:        make(chan struct{}, 1),
+		runCtx:       runCtx,
+		interrupt:    interrupt,
+		stopping:     make(chan struct{}),
+		interrupting: make(chan struct{}),
+		stopped:      make(chan struct{}),
 	}
 }
 
-// Run starts the polling loop. It blocks until the context is cancelled.
+// Run starts the worker pool and the claim loop. It returns once Shutdown has
+// drained the pool, or when the context is cancelled, in which case runs in
+// progress are interrupted.
 func (s *Scheduler) Run(ctx context.Context) {
-	s.logger.Info("scheduler started", "poll_interval", pollInterval, "max_concurrent", maxConcurrent)
+	defer close(s.stopped)
+	s.logger.Info("scheduler started", "poll_interval", pollInterval, "concurrency", s.concurrency)
 	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
+	defer s.interrupt(nil)
+
+	work := make(chan *dbgen.ClaimDueWatchesRow)
+	var wg sync.WaitGroup
+	for range s.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.worker(s.runCtx, work)
+		}()
+	}
+
+	s.executor.sweepOrphanedRuns(ctx)
+
 	// Run once immediately on startup
-	s.poll(ctx)
+	s.dispatch(ctx, work)
 
+loop:
 	for {
 		select {
 		case <-ctx.Done():
-			s.logger.Info("scheduler stopping")
-			return
+			s.interrupt(ErrShutdown)
+			break loop
+		case <-s.stopping:
+			break loop
 		case <-ticker.C:
-			s.poll(ctx)
+			s.executor.sweepExpiredRuns(ctx)
+		case <-s.freed:
 		}
+		s.dispatch(ctx, work)
+	}
+
+	s.logger.Info("scheduler stopping", "running", s.running.Load())
+	close(work)
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		s.manuals.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-s.interrupting:
+		s.logger.Warn("shutdown grace period over, interrupting runs", "running", s.running.Load())
+		s.interrupt(ErrShutdown)
+		<-drained
 	}
+	s.logger.Info("scheduler stopped")
 }
 
-func (s *Scheduler) poll(ctx context.Context) {
-	watches, err := s.queries.GetDueWatches(ctx)
-	if err != nil {
-		s.logger.Error("failed to get due watches", "error", err)
+// Stats reports the scheduler's current load.
+func (s *Scheduler) Stats() Stats {
+	return Stats{
+		Concurrency: s.concurrency,
+		Running:     s.running.Load(),
+		QueueDepth:  s.queueDepth.Load(),
+	}
+}
+
+func (s *Scheduler) worker(ctx context.Context, work <-chan *dbgen.ClaimDueWatchesRow) {
+	for watch := range work {
+		s.executor.Execute(ctx, watch)
+		s.running.Add(-1)
+		select {
+		case s.freed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// dispatch claims as many due watches as there are idle workers and hands
+// them out.
+func (s *Scheduler) dispatch(ctx context.Context, work chan<- *dbgen.ClaimDueWatchesRow) {
+	idle := s.concurrency - int(s.running.Load())
+	if idle <= 0 {
+		s.updateQueueDepth(ctx)
 		return
 	}
 
-	if len(watches) == 0 {
+	watches, err := s.claimDue(ctx, idle)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("failed to claim due watches", "error", err)
+		}
 		return
 	}
 
-	s.logger.Info("found due watches", "count", len(watches))
+	if len(watches) > 0 {
+		s.logger.Info("claimed due watches", "count", len(watches))
+	}
+	for i := range watches {
+		s.running.Add(1)
+		work <- &watches[i]
+	}
 
-	sem := make(chan struct{}, maxConcurrent)
-	var wg sync.WaitGroup
+	s.updateQueueDepth(ctx)
+}
 
-	for i := range watches {
-		wg.Add(1)
-		sem <- struct{}{}
-		go func(watch *dbgen.GetDueWatchesRow) {
-			defer wg.Done()
-			defer func() { <-sem }()
-			s.executor.Execute(ctx, watch)
-		}(&watches[i])
+func (s *Scheduler) updateQueueDepth(ctx context.Context) {
+	depth, err := s.countDue(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("failed to count due watches", "error", err)
+		}
+		return
 	}
+	if depth > 0 && depth != s.queueDepth.Load() {
+		s.logger.Info("scheduler backlog", "queue_depth", depth, "running", s.running.Load())
+	}
+	s.queueDepth.Store(depth)
+}
+
+// RunSingle starts a manual run of an org's watch and returns its ID without
+// waiting for it to finish. It fails with ErrWatchRunning if the watch is
+// already running, and with ErrShutdown once the scheduler is stopping.
+func (s *Scheduler) RunSingle(ctx context.Context, orgID, watchID string) (string, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return "", ErrShutdown
+	}
+	s.manuals.Add(1)
+	s.mu.Unlock()
+
+	watch, runID, err := s.executor.claimByID(ctx, orgID, watchID)
+	if err != nil {
+		s.manuals.Done()
+		return "", err
+	}
+
+	logger := s.logger.With("watch_id", watchID, "watch_name", watch.Name, "trigger", "manual")
+	go func() {
+		defer s.manuals.Done()
+		_ = s.executor.runAndRecord(s.runCtx, watch, runID, logger)
+	}()
+	return db.UUIDToString(runID), nil
+}
+
+// GetRun reports the progress or outcome of a run.
+func (s *Scheduler) GetRun(ctx context.Context, orgID, runID string) (*RunStatus, error) {
+	return s.executor.GetRun(ctx, orgID, runID)
+}
+
+// CancelRun cancels an in-flight run.
+func (s *Scheduler) CancelRun(ctx context.Context, orgID, runID string) error {
+	return s.executor.CancelRun(ctx, orgID, runID)
+}
+
+// PlanMigration reports how a watch's stored entities would be migrated (dry run).
+func (s *Scheduler) PlanMigration(ctx context.Context, orgID, watchID string) (*MigrationReport, error) {
+	return s.executor.PlanMigration(ctx, orgID, watchID)
+}
+
+// Replay runs a watch over archived snapshots without side effects.
+func (s *Scheduler) Replay(ctx context.Context, p ReplayParams) (*ReplayResult, error) {
+	return s.executor.Replay(ctx, p)
+}
 
-	wg.Wait()
+// EntityHistory returns the recorded versions of an entity, or the timeline
+// of one of its fields.
+func (s *Scheduler) EntityHistory(ctx context.Context, orgID, entityID, field string) (*EntityHistory, error) {
+	return s.executor.EntityHistory(ctx, orgID, entityID, field)
 }
 
-// RunSingle executes a single watch by ID (for manual trigger endpoint).
-func (s *Scheduler) RunSingle(ctx context.Context, watchID string) (string, error) {
-	return s.executor.ExecuteByID(ctx, watchID)
+// WatchEntitiesAsOf returns the entities of a watch as they were at asOf.
+func (s *Scheduler) WatchEntitiesAsOf(ctx context.Context, orgID, watchID string, asOf time.Time) (*EntitySet, error) {
+	return s.executor.WatchEntitiesAsOf(ctx, orgID, watchID, asOf)
 }
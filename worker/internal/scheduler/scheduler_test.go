@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blueprinter/worker/internal/db/dbgen"
+)
+
+func TestDispatch_ClaimsIdleSlots(t *testing.T) {
+	s := NewScheduler(nil, nil, 3, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var asked []int
+	due := 10
+	s.claimDue = func(_ context.Context, max int) ([]dbgen.ClaimDueWatchesRow, error) {
+		asked = append(asked, max)
+		n := min(max, due)
+		due -= n
+		return make([]dbgen.ClaimDueWatchesRow, n), nil
+	}
+	s.countDue = func(context.Context) (int64, error) { return int64(due), nil }
+	work := make(chan *dbgen.ClaimDueWatchesRow, 10)
+	ctx := context.Background()
+
+	// All slots idle: claim one watch per worker.
+	s.dispatch(ctx, work)
+	assert.Equal(t, []int{3}, asked)
+	assert.Equal(t, Stats{Concurrency: 3, Running: 3, QueueDepth: 7}, s.Stats())
+
+	// Every worker busy: nothing is claimed.
+	s.dispatch(ctx, work)
+	assert.Equal(t, []int{3}, asked)
+
+	// One run finishes: only its slot is refilled.
+	s.running.Add(-1)
+	s.dispatch(ctx, work)
+	assert.Equal(t, []int{3, 1}, asked)
+	assert.Equal(t, int64(3), s.Stats().Running)
+	assert.Len(t, work, 4)
+
+	// Fewer due watches than idle slots: only those claimed count as running.
+	s.running.Add(-3)
+	due = 2
+	s.dispatch(ctx, work)
+	assert.Equal(t, []int{3, 1, 3}, asked)
+	assert.Equal(t, Stats{Concurrency: 3, Running: 2, QueueDepth: 0}, s.Stats())
+}
+
+// TestClaimDueWatches_OrgFairness checks that orgs take turns when due
+// watches are claimed. It needs an otherwise empty Postgres database with the
+// schema applied, since it claims whatever is due:
+//
+//	TEST_DATABASE_URL=postgres://... go test ./internal/scheduler -run OrgFairness
+func TestClaimDueWatches_OrgFairness(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	prefix := fmt.Sprintf("fair_%d", time.Now().UnixNano())
+	busy, quiet := prefix+"_busy", prefix+"_quiet"
+	t.Cleanup(func() {
+		for _, org := range []string{busy, quiet} {
+			for _, table := range []string{"watches", "blueprints"} {
+				_, _ = pool.Exec(ctx, "DELETE FROM "+table+" WHERE org_id = $1", org)
+			}
+		}
+	})
+
+	addWatches := func(org string, count int, overdue time.Duration) {
+		var blueprintID pgtype.UUID
+		require.NoError(t, pool.QueryRow(ctx, `
+			INSERT INTO blueprints (org_id, name, url, schema_type, extraction_rules, status)
+			VALUES ($1, 'fair', 'https://example.com', 'ecommerce_product', '{}', 'active')
+			RETURNING id`, org).Scan(&blueprintID))
+		for i := range count {
+			_, err := pool.Exec(ctx, `
+				INSERT INTO watches (org_id, blueprint_id, name, url, schedule, next_run_at)
+				VALUES ($1, $2, $3, 'https://example.com', '0 * * * *', now() - make_interval(secs => $4))`,
+				org, blueprintID, fmt.Sprintf("fair %d", i), overdue.Seconds()+float64(i))
+			require.NoError(t, err)
+		}
+	}
+	// The busy org's watches are all more overdue than the quiet org's one.
+	addWatches(busy, 5, time.Hour)
+	addWatches(quiet, 1, time.Minute)
+
+	queries := dbgen.New(pool)
+	claim := func(max int32) []string {
+		rows, err := queries.ClaimDueWatches(ctx, dbgen.ClaimDueWatchesParams{
+			MaxWatches:   max,
+			Owner:        prefix,
+			LeaseSeconds: 60,
+		})
+		require.NoError(t, err)
+		orgs := make([]string, len(rows))
+		for i, row := range rows {
+			orgs[i] = row.OrgID
+		}
+		return orgs
+	}
+
+	// Two slots: the quiet org gets one despite the busy org's older backlog.
+	assert.ElementsMatch(t, []string{busy, quiet}, claim(2))
+	// The rest of the backlog is the busy org's.
+	assert.ElementsMatch(t, []string{busy, busy, busy, busy}, claim(10))
+	assert.Empty(t, claim(10))
+}
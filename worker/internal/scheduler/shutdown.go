@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrShutdown is the cause of a run interrupted because its worker is shutting
+// down. Such runs complete as interrupted, don't count as watch failures, and
+// the watch is due again at once.
+var ErrShutdown = errors.New("worker shutting down")
+
+// Shutdown stops claiming watches and waits for the runs in progress to
+// finish. If ctx ends first, the remaining runs are interrupted and recorded
+// as such before Shutdown returns ctx's error. Run must have been started.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		s.interruptOnce.Do(func() { close(s.interrupting) })
+		<-s.stopped
+		return ctx.Err()
+	}
+}
+
+// sweepOrphanedRuns completes the runs that a stopped worker left running and
+// drops the leases a previous process held under this worker's ID.
+func (e *Executor) sweepOrphanedRuns(ctx context.Context) {
+	n, err := e.queries.InterruptOrphanedWatchRuns(ctx, e.workerID)
+	if err != nil {
+		e.logger.Error("failed to sweep orphaned runs", "error", err)
+		return
+	}
+	if n > 0 {
+		e.logger.Warn("marked orphaned runs as interrupted", "count", n)
+	}
+
+	released, err := e.queries.ReleaseWatchLeases(ctx, e.workerID)
+	if err != nil {
+		e.logger.Error("failed to release stale leases", "error", err)
+		return
+	}
+	if released > 0 {
+		e.logger.Info("released leases held by a previous process", "count", released)
+	}
+}
+
+// sweepExpiredRuns completes the runs of workers that stopped while running
+// them, once their watch's lease has lapsed, so they don't stay running until
+// some worker restarts.
+func (e *Executor) sweepExpiredRuns(ctx context.Context) {
+	n, err := e.queries.InterruptExpiredWatchRuns(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.logger.Error("failed to sweep expired runs", "error", err)
+		}
+		return
+	}
+	if n > 0 {
+		e.logger.Warn("marked runs with expired leases as interrupted", "count", n)
+	}
+}
@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// urlParamPlaceholder marks where a watch URL template takes its parameter.
+const urlParamPlaceholder = "{param}"
+
+// fetchTarget is one page fetched in a run: its URL and, for a URL template,
+// the parameter it was expanded with.
+type fetchTarget struct {
+	URL   string
+	Param string
+}
+
+// listing identifies the listing a page belongs to for position tracking:
+// the pages of a URL template share one per parameter, and any other URL is
+// a listing of its own.
+func (t fetchTarget) listing() fetchTarget {
+	if t.Param != "" {
+		return fetchTarget{Param: t.Param}
+	}
+	return fetchTarget{URL: t.URL}
+}
+
+// PageError is a page of a run that could not be fetched or extracted within
+// its deadline. The run goes on without it, and the entities last found on
+// it are left as they were.
+type PageError struct {
+	URL   string `json:"url"`
+	Param string `json:"param,omitempty"`
+	Phase string `json:"phase"` // fetch or extract
+	Error string `json:"error"`
+}
+
+// uncheckedEntities returns the external IDs of the stored entities last found
+// on a page that failed in this run. sources maps external IDs to the URL of
+// that page.
+func uncheckedEntities(sources map[string]string, failed []PageError) map[string]bool {
+	if len(failed) == 0 {
+		return nil
+	}
+	failedURLs := make(map[string]bool, len(failed))
+	for _, p := range failed {
+		failedURLs[p.URL] = true
+	}
+	unchecked := make(map[string]bool)
+	for eid, u := range sources {
+		if failedURLs[u] {
+			unchecked[eid] = true
+		}
+	}
+	return unchecked
+}
+
+// watchTargets expands a watch's URL, its additional URLs and its URL
+// parameters into the pages fetched in one run, in that order. With
+// parameters, every URL containing urlParamPlaceholder is fetched once per
+// parameter, which is escaped into it; URLs without the placeholder are
+// fetched once. Duplicate pages are fetched once.
+func watchTargets(primary string, urls, params []string) ([]fetchTarget, error) {
+	all := append([]string{primary}, urls...)
+
+	templated := false
+	for _, u := range all {
+		if strings.Contains(u, urlParamPlaceholder) {
+			templated = true
+			break
+		}
+	}
+	switch {
+	case templated && len(params) == 0:
+		return nil, errors.New("URL template has no parameters")
+	case !templated && len(params) > 0:
+		return nil, errors.New("URL parameters given but no URL contains " + urlParamPlaceholder)
+	}
+
+	targets := make([]fetchTarget, 0, len(all)*max(len(params), 1))
+	seen := make(map[string]bool, cap(targets))
+	add := func(t fetchTarget) {
+		if t.URL == "" || seen[t.URL] {
+			return
+		}
+		seen[t.URL] = true
+		targets = append(targets, t)
+	}
+	for _, u := range all {
+		if !strings.Contains(u, urlParamPlaceholder) {
+			add(fetchTarget{URL: u})
+			continue
+		}
+		for _, p := range params {
+			add(fetchTarget{
+				URL:   strings.ReplaceAll(u, urlParamPlaceholder, escapeParam(p)),
+				Param: p,
+			})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("watch has no URL")
+	}
+	return targets, nil
+}
+
+// escapeParam escapes a URL parameter so it is safe both as a query value and
+// as a path segment.
+func escapeParam(p string) string {
+	return strings.ReplaceAll(url.QueryEscape(p), "+", "%20")
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchTargets_SingleURL(t *testing.T) {
+	got, err := watchTargets("https://example.com/products", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []fetchTarget{{URL: "https://example.com/products"}}, got)
+}
+
+func TestWatchTargets_URLList(t *testing.T) {
+	got, err := watchTargets("https://example.com/de", []string{"https://example.com/fr", "https://example.com/de", ""}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []fetchTarget{
+		{URL: "https://example.com/de"},
+		{URL: "https://example.com/fr"},
+	}, got)
+}
+
+func TestWatchTargets_Template(t *testing.T) {
+	got, err := watchTargets("https://example.com/search?q={param}", nil, []string{"red shoes", "a&b"})
+	require.NoError(t, err)
+	assert.Equal(t, []fetchTarget{
+		{URL: "https://example.com/search?q=red%20shoes", Param: "red shoes"},
+		{URL: "https://example.com/search?q=a%26b", Param: "a&b"},
+	}, got)
+}
+
+func TestWatchTargets_TemplateWithPlainURL(t *testing.T) {
+	got, err := watchTargets("https://example.com/{param}/deals", []string{"https://example.com/deals"}, []string{"uk", "de"})
+	require.NoError(t, err)
+	assert.Equal(t, []fetchTarget{
+		{URL: "https://example.com/uk/deals", Param: "uk"},
+		{URL: "https://example.com/de/deals", Param: "de"},
+		{URL: "https://example.com/deals"},
+	}, got)
+}
+
+func TestFetchTarget_Listing(t *testing.T) {
+	uk1 := fetchTarget{URL: "https://example.com/uk/deals?page=1", Param: "uk"}
+	uk2 := fetchTarget{URL: "https://example.com/uk/deals?page=2", Param: "uk"}
+	de := fetchTarget{URL: "https://example.com/de/deals", Param: "de"}
+	shopA := fetchTarget{URL: "https://a.example.com/deals"}
+	shopB := fetchTarget{URL: "https://b.example.com/deals"}
+
+	assert.Equal(t, uk1.listing(), uk2.listing(), "pages of one parameter share a listing")
+	assert.NotEqual(t, uk1.listing(), de.listing())
+	assert.NotEqual(t, shopA.listing(), shopB.listing(), "plain URLs are listings of their own")
+}
+
+func TestWatchTargets_Invalid(t *testing.T) {
+	_, err := watchTargets("https://example.com/{param}", nil, nil)
+	assert.EqualError(t, err, "URL template has no parameters")
+
+	_, err = watchTargets("https://example.com", nil, []string{"uk"})
+	assert.EqualError(t, err, "URL parameters given but no URL contains {param}")
+
+	_, err = watchTargets("", nil, nil)
+	assert.EqualError(t, err, "watch has no URL")
+}
+
+func TestUncheckedEntities(t *testing.T) {
+	stored := map[string]string{
+		"a": "https://example.com/uk",
+		"b": "https://example.com/de",
+		"c": "https://example.com/uk",
+	}
+
+	assert.Nil(t, uncheckedEntities(stored, nil))
+	assert.Equal(t, map[string]bool{"a": true, "c": true}, uncheckedEntities(stored, []PageError{
+		{URL: "https://example.com/uk", Phase: "fetch", Error: "fetch timed out after 2m0s"},
+	}))
+}
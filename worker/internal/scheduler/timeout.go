@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blueprinter/worker/internal/blueprint"
+)
+
+// Default per-phase deadlines of a run.
+const (
+	DefaultFetchTimeout   = 2 * time.Minute
+	DefaultExtractTimeout = 30 * time.Second
+	DefaultPersistTimeout = 2 * time.Minute
+)
+
+// RunTimeouts bounds each phase of a watch run. Zero values use the defaults.
+type RunTimeouts struct {
+	Fetch   time.Duration // fetching, cleaning and archiving the page
+	Extract time.Duration // extracting entities
+	Persist time.Duration // the diff-and-write transaction
+}
+
+func (t RunTimeouts) withDefaults() RunTimeouts {
+	if t.Fetch <= 0 {
+		t.Fetch = DefaultFetchTimeout
+	}
+	if t.Extract <= 0 {
+		t.Extract = DefaultExtractTimeout
+	}
+	if t.Persist <= 0 {
+		t.Persist = DefaultPersistTimeout
+	}
+	return t
+}
+
+// PhaseTimeoutError reports a run phase that exceeded its deadline. Runs that
+// fail with it complete as timed_out.
+type PhaseTimeoutError struct {
+	Phase   string
+	Timeout time.Duration
+}
+
+func (e *PhaseTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Phase, e.Timeout)
+}
+
+// withPhaseTimeout runs fn under the phase's deadline. When fn fails because
+// its context ended, the reason (the phase timeout, or the run's cancellation
+// cause) is returned instead of the bare context error.
+func withPhaseTimeout(ctx context.Context, phase string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	pctx, cancel := context.WithTimeoutCause(ctx, timeout, &PhaseTimeoutError{Phase: phase, Timeout: timeout})
+	defer cancel()
+
+	err := fn(pctx)
+	if err != nil && pctx.Err() != nil {
+		return context.Cause(pctx)
+	}
+	return err
+}
+
+// extractEntities runs the extractor under ctx. Extraction doesn't take a
+// context, so it runs aside and is abandoned if ctx ends first.
+func extractEntities(ctx context.Context, cleanedHTML string, rules *blueprint.ExtractionRules) ([]map[string]any, error) {
+	type result struct {
+		entities []map[string]any
+		err      error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		entities, err := blueprint.Extract(cleanedHTML, rules)
+		done <- result{entities, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.entities, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
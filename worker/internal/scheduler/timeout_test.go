@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPhaseTimeout_Success(t *testing.T) {
+	err := withPhaseTimeout(context.Background(), "fetch", time.Second, func(ctx context.Context) error {
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestWithPhaseTimeout_PassesErrorsThrough(t *testing.T) {
+	boom := errors.New("boom")
+	err := withPhaseTimeout(context.Background(), "fetch", time.Second, func(ctx context.Context) error {
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+}
+
+func TestWithPhaseTimeout_Timeout(t *testing.T) {
+	err := withPhaseTimeout(context.Background(), "extract", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	var timeout *PhaseTimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, "extract", timeout.Phase)
+	assert.Equal(t, "extract timed out after 10ms", err.Error())
+}
+
+func TestWithPhaseTimeout_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(ErrRunCancelled)
+
+	err := withPhaseTimeout(ctx, "persist", time.Second, func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, ErrRunCancelled)
+}
+
+func TestRunTimeouts_WithDefaults(t *testing.T) {
+	got := RunTimeouts{Extract: time.Minute}.withDefaults()
+	assert.Equal(t, DefaultFetchTimeout, got.Fetch)
+	assert.Equal(t, time.Minute, got.Extract)
+	assert.Equal(t, DefaultPersistTimeout, got.Persist)
+}